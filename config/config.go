@@ -1,16 +1,23 @@
 package config
 
 import (
+	"context"
 	"log"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"p9e.in/ugcl/storage"
 )
 
 var DB *gorm.DB
 
+// Storage holds uploaded files; see ConnectStorage
+var Storage storage.Backend
+
 func Connect() {
 	// Load .env file
 	err := godotenv.Load()
@@ -24,3 +31,71 @@ func Connect() {
 		log.Fatal("Failed to connect to database:", err)
 	}
 }
+
+// defaultTrashRetentionDays applies when TRASH_RETENTION_DAYS is not set
+const defaultTrashRetentionDays = 90
+
+// defaultAttachmentRetentionDays applies when ATTACHMENT_RETENTION_DAYS is
+// not set
+const defaultAttachmentRetentionDays = 7
+
+// TrashRetention is how long deleted records stay restorable before they
+// are purged for good, from TRASH_RETENTION_DAYS. Zero turns purging off.
+func TrashRetention() time.Duration {
+	return envDays("TRASH_RETENTION_DAYS", defaultTrashRetentionDays)
+}
+
+// AttachmentRetention is how long an upload may stay unlinked to any
+// record before it is deleted, from ATTACHMENT_RETENTION_DAYS. Zero turns
+// deleting off.
+func AttachmentRetention() time.Duration {
+	return envDays("ATTACHMENT_RETENTION_DAYS", defaultAttachmentRetentionDays)
+}
+
+// envDays reads a whole number of days from the environment variable name,
+// falling back to def when it is unset or invalid
+func envDays(name string, def int) time.Duration {
+	days := def
+	if raw := os.Getenv(name); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			log.Printf("invalid %s %q, using %d", name, raw, def)
+		} else {
+			days = n
+		}
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// ConnectStorage sets up Storage from STORAGE_BACKEND: local (the
+// directory LOCAL_STORAGE_DIR, served under LOCAL_STORAGE_URL with URLs
+// signed by STORAGE_SIGNING_KEY, or JWT_SECRET without one), gcs (the
+// bucket GCS_BUCKET) or s3 (S3_ENDPOINT, S3_REGION, S3_BUCKET,
+// S3_ACCESS_KEY_ID and S3_SECRET_ACCESS_KEY). Without it uploads go to GCS
+// as they always have.
+func ConnectStorage() {
+	cfg := storage.Config{
+		Backend:         envOr("STORAGE_BACKEND", storage.BackendGCS),
+		LocalDir:        envOr("LOCAL_STORAGE_DIR", "./uploads"),
+		LocalURL:        envOr("LOCAL_STORAGE_URL", "/uploads/"),
+		LocalSigningKey: envOr("STORAGE_SIGNING_KEY", JWTSecret),
+		GCSBucket:       envOr("GCS_BUCKET", "sreeugcl"),
+		S3Endpoint:      os.Getenv("S3_ENDPOINT"),
+		S3Region:        os.Getenv("S3_REGION"),
+		S3Bucket:        os.Getenv("S3_BUCKET"),
+		S3AccessKey:     os.Getenv("S3_ACCESS_KEY_ID"),
+		S3SecretKey:     os.Getenv("S3_SECRET_ACCESS_KEY"),
+	}
+	var err error
+	Storage, err = storage.New(context.Background(), cfg)
+	if err != nil {
+		log.Fatal("Failed to set up file storage:", err)
+	}
+}
+
+func envOr(name, fallback string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return fallback
+}
@@ -1,8 +1,16 @@
 package config
 
 import (
+	"os"
+	"slices"
+	"strings"
+	"time"
+
 	"github.com/go-gormigrate/gormigrate/v2"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"p9e.in/ugcl/models"
 )
 
@@ -19,7 +27,553 @@ func Migrations(db *gorm.DB) error {
 			// 	return tx.Migrator().DropTable("dairy_sites")
 			// },
 		},
+		{
+			ID: "18102026_typed_numeric_columns",
+			Migrate: func(tx *gorm.DB) error {
+				if err := tx.AutoMigrate(&models.NumericQuarantine{}); err != nil {
+					return err
+				}
+				for _, c := range models.NumericColumns {
+					if err := convertNumericColumn(tx, c); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+			Rollback: func(tx *gorm.DB) error {
+				for _, c := range models.NumericColumns {
+					legacy := c.Column + "_legacy"
+					if !tx.Migrator().HasColumn(c.Table, legacy) {
+						continue
+					}
+					if err := tx.Exec("ALTER TABLE ? DROP COLUMN ?", clause.Table{Name: c.Table}, clause.Column{Name: c.Column}).Error; err != nil {
+						return err
+					}
+					if err := tx.Exec("ALTER TABLE ? RENAME COLUMN ? TO ?", clause.Table{Name: c.Table}, clause.Column{Name: legacy}, clause.Column{Name: c.Column}).Error; err != nil {
+						return err
+					}
+				}
+				return tx.Migrator().DropTable(&models.NumericQuarantine{})
+			},
+		},
+		{
+			ID: "18102026_user_sessions",
+			Migrate: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&models.Session{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable(&models.Session{})
+			},
+		},
+		{
+			ID: "18102026_api_clients",
+			Migrate: func(tx *gorm.DB) error {
+				if err := tx.AutoMigrate(&models.APIClient{}); err != nil {
+					return err
+				}
+				return seedAPIClients(tx)
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable(&models.APIClient{})
+			},
+		},
+		{
+			ID: "18102026_role_permissions",
+			Migrate: func(tx *gorm.DB) error {
+				if err := tx.AutoMigrate(&models.RolePermission{}); err != nil {
+					return err
+				}
+				return seedRolePermissions(tx)
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable(&models.RolePermission{})
+			},
+		},
+		{
+			ID: "18102026_site_access",
+			Migrate: func(tx *gorm.DB) error {
+				if err := tx.AutoMigrate(&models.Site{}, &models.UserSite{}, &models.UserCluster{}, &models.APIClient{}); err != nil {
+					return err
+				}
+				return seedSiteAccess(tx)
+			},
+			Rollback: func(tx *gorm.DB) error {
+				if err := tx.Where("permission = ?", sitesAll).Delete(&models.RolePermission{}).Error; err != nil {
+					return err
+				}
+				if err := tx.Migrator().DropColumn(&models.APIClient{}, "Sites"); err != nil {
+					return err
+				}
+				return tx.Migrator().DropTable(&models.UserCluster{}, &models.UserSite{}, &models.Site{})
+			},
+		},
+		{
+			ID: "18102026_master_data",
+			Migrate: func(tx *gorm.DB) error {
+				if err := tx.AutoMigrate(&models.Site{}, &models.MasterContractor{}, &models.Vehicle{}, &models.FuelCard{}, &models.Yard{}); err != nil {
+					return err
+				}
+				for _, link := range models.MasterLinks {
+					if err := addMasterLink(tx, link); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+			Rollback: func(tx *gorm.DB) error {
+				for _, link := range models.MasterLinks {
+					if tx.Migrator().HasColumn(link.Model, link.IDField) {
+						if err := tx.Migrator().DropColumn(link.Model, link.IDField); err != nil {
+							return err
+						}
+					}
+				}
+				for _, field := range []string{"Aliases", "ChainageFrom", "ChainageTo", "Latitude", "Longitude", "RadiusMeters"} {
+					if err := tx.Migrator().DropColumn(&models.Site{}, field); err != nil {
+						return err
+					}
+				}
+				return tx.Migrator().DropTable(&models.Yard{}, &models.FuelCard{}, &models.Vehicle{}, &models.MasterContractor{})
+			},
+		},
+		{
+			ID: "18102026_audit_logs",
+			Migrate: func(tx *gorm.DB) error {
+				if err := tx.AutoMigrate(&models.AuditLog{}); err != nil {
+					return err
+				}
+				// Entries can be added but never changed or removed
+				for _, stmt := range []string{
+					`CREATE OR REPLACE FUNCTION audit_logs_append_only() RETURNS trigger AS $$
+					BEGIN
+						RAISE EXCEPTION 'audit_logs is append-only';
+					END;
+					$$ LANGUAGE plpgsql`,
+					`DROP TRIGGER IF EXISTS audit_logs_append_only ON audit_logs`,
+					`CREATE TRIGGER audit_logs_append_only BEFORE UPDATE OR DELETE ON audit_logs
+						FOR EACH ROW EXECUTE FUNCTION audit_logs_append_only()`,
+				} {
+					if err := tx.Exec(stmt).Error; err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+			Rollback: func(tx *gorm.DB) error {
+				if err := tx.Migrator().DropTable(&models.AuditLog{}); err != nil {
+					return err
+				}
+				return tx.Exec("DROP FUNCTION IF EXISTS audit_logs_append_only()").Error
+			},
+		},
+		{
+			ID: "18102026_approval_workflow",
+			Migrate: func(tx *gorm.DB) error {
+				if err := tx.AutoMigrate(&models.ApprovalStage{}, &models.ApprovalRequest{}, &models.ApprovalAction{}); err != nil {
+					return err
+				}
+				return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&defaultApprovalStages).Error
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable(&models.ApprovalAction{}, &models.ApprovalRequest{}, &models.ApprovalStage{})
+			},
+		},
+		{
+			ID: "18102026_geofence",
+			Migrate: func(tx *gorm.DB) error {
+				if err := tx.AutoMigrate(&models.Site{}, &models.GeofencePolicy{}); err != nil {
+					return err
+				}
+				m := tx.Migrator()
+				for _, sm := range models.SyncModules {
+					if !models.GeofencedModule(sm.Model) {
+						continue
+					}
+					for _, field := range []string{"GeofenceStatus", "GeofenceDistanceMeters"} {
+						if !m.HasColumn(sm.Model, field) {
+							if err := m.AddColumn(sm.Model, field); err != nil {
+								return err
+							}
+						}
+					}
+					if !m.HasIndex(sm.Model, "GeofenceStatus") {
+						if err := m.CreateIndex(sm.Model, "GeofenceStatus"); err != nil {
+							return err
+						}
+					}
+				}
+				return nil
+			},
+			Rollback: func(tx *gorm.DB) error {
+				m := tx.Migrator()
+				for _, sm := range models.SyncModules {
+					if !models.GeofencedModule(sm.Model) {
+						continue
+					}
+					for _, field := range []string{"GeofenceStatus", "GeofenceDistanceMeters"} {
+						if m.HasColumn(sm.Model, field) {
+							if err := m.DropColumn(sm.Model, field); err != nil {
+								return err
+							}
+						}
+					}
+				}
+				if err := m.DropColumn(&models.Site{}, "Boundary"); err != nil {
+					return err
+				}
+				return m.DropTable(&models.GeofencePolicy{})
+			},
+		},
+		{
+			ID: "18102026_attachments",
+			Migrate: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&attachmentsTable{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable(&attachmentsTable{})
+			},
+		},
+		{
+			ID: "18102026_attachment_links",
+			Migrate: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&attachmentLinkColumns{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				m := tx.Migrator()
+				if err := m.DropIndex(&attachmentLinkColumns{}, "CreatedAt"); err != nil {
+					return err
+				}
+				for _, field := range []string{"SHA256", "Label", "CapturedAt", "Module", "RecordID", "LinkedAt"} {
+					if err := m.DropColumn(&attachmentLinkColumns{}, field); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+		{
+			ID: "18102026_attachment_images",
+			Migrate: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&attachmentImageColumns{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				m := tx.Migrator()
+				for _, field := range []string{"Width", "Height", "Latitude", "Longitude", "ThumbnailKey", "WebKey", "DistanceMeters", "CaptureLagMinutes"} {
+					if err := m.DropColumn(&attachmentImageColumns{}, field); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+		{
+			ID: "18102026_photo_matches",
+			Migrate: func(tx *gorm.DB) error {
+				return tx.AutoMigrate(&attachmentPHashColumn{}, &models.PhotoMatch{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				m := tx.Migrator()
+				if err := m.DropTable(&models.PhotoMatch{}); err != nil {
+					return err
+				}
+				return m.DropColumn(&attachmentPHashColumn{}, "PHash")
+			},
+		},
+		{
+			ID: "18102026_default_roles",
+			Migrate: func(tx *gorm.DB) error {
+				return seedDefaultRoles(tx)
+			},
+			// The grants are indistinguishable from ones made by hand
+			Rollback: func(tx *gorm.DB) error { return nil },
+		},
 	})
 
 	return m.Migrate()
 }
+
+// The attachments table grew over several migrations. Each migrates only
+// the columns it added, as they were then, so that later changes to
+// models.Attachment leave the earlier steps and their rollbacks alone.
+
+// attachmentsTable is the table as 18102026_attachments created it
+type attachmentsTable struct {
+	ID             uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Key            string    `gorm:"size:300;not null;uniqueIndex"`
+	Name           string    `gorm:"size:255;not null"`
+	ContentType    string    `gorm:"size:100;not null"`
+	Size           int64     `gorm:"not null"`
+	UploadedBy     string    `gorm:"size:64;index"`
+	UploadedByName string    `gorm:"size:100"`
+	CreatedAt      time.Time
+}
+
+func (attachmentsTable) TableName() string { return "attachments" }
+
+// attachmentLinkColumns are added by 18102026_attachment_links, which also
+// indexes created_at for the reaper
+type attachmentLinkColumns struct {
+	SHA256     string `gorm:"column:sha256;size:64;index"`
+	Label      string `gorm:"size:50"`
+	CapturedAt *time.Time
+	Module     string     `gorm:"size:30;index:idx_attachments_record"`
+	RecordID   *uuid.UUID `gorm:"type:uuid;index:idx_attachments_record"`
+	LinkedAt   *time.Time
+	CreatedAt  time.Time `gorm:"index"`
+}
+
+func (attachmentLinkColumns) TableName() string { return "attachments" }
+
+// attachmentImageColumns are added by 18102026_attachment_images
+type attachmentImageColumns struct {
+	Width             int
+	Height            int
+	Latitude          *float64
+	Longitude         *float64
+	ThumbnailKey      string `gorm:"size:300"`
+	WebKey            string `gorm:"size:300"`
+	DistanceMeters    *float64
+	CaptureLagMinutes *int
+}
+
+func (attachmentImageColumns) TableName() string { return "attachments" }
+
+// attachmentPHashColumn is added by 18102026_photo_matches
+type attachmentPHashColumn struct {
+	PHash *int64 `gorm:"column:phash"`
+}
+
+func (attachmentPHashColumn) TableName() string { return "attachments" }
+
+// legacyAPIClients are the clients that used to be configured in code,
+// keyed by the environment variable holding their key
+var legacyAPIClients = []struct {
+	env    string
+	client models.APIClient
+}{
+	{"MOBILE_APP_KEY", models.APIClient{
+		AppName:        "MobileApp",
+		AllowedPaths:   pq.StringArray{"/api/v1"},
+		AllowedMethods: pq.StringArray{"POST"},
+		ReadPaths:      pq.StringArray{"/api/v1/sync"},
+	}},
+	{"PARTNER_PORTAL_KEY", models.APIClient{
+		AppName:        "PartnerPortal",
+		AllowedPaths:   pq.StringArray{"/api/v1"},
+		AllowedMethods: pq.StringArray{"GET"},
+		AllowedCIDRs:   pq.StringArray{"20.204.19.129/32", "127.0.0.1/32", "::1/128"},
+	}},
+	{"INTERNAL_OPS_KEY", models.APIClient{
+		AppName:        "InternalOps",
+		AllowedPaths:   pq.StringArray{"/api/v1/*"},
+		AllowedMethods: pq.StringArray{"GET", "POST", "PUT", "DELETE"},
+	}},
+}
+
+// seedAPIClients carries the keys from the environment over to api_clients
+// so existing apps keep working. Afterwards the variables can be removed.
+func seedAPIClients(tx *gorm.DB) error {
+	for _, legacy := range legacyAPIClients {
+		key := os.Getenv(legacy.env)
+		if key == "" {
+			continue
+		}
+		client := legacy.client
+		client.KeyHash = models.HashAPIKey(key)
+		client.KeyPrefix = key[:min(4, len(key))]
+		if err := tx.Create(&client).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// adminRoles had every admin route before permissions existed
+var adminRoles = []string{"admin", "super_admin", "project_coordinator"}
+
+// submitterRoles may submit records whether or not anyone holds them yet,
+// as everyone who registers gets one
+var submitterRoles = []string{models.DefaultRole}
+
+// seedRolePermissions reproduces the access roles had before the permission
+// matrix: admin roles get everything, every other role in use, and the
+// submitterRoles, may submit records and read KPIs.
+func seedRolePermissions(tx *gorm.DB) error {
+	var grants []models.RolePermission
+	for _, role := range adminRoles {
+		grants = append(grants, defaultGrants(role)...)
+	}
+
+	var roles []string
+	if err := tx.Model(&models.User{}).Distinct().Where("role NOT IN ?", adminRoles).Pluck("role", &roles).Error; err != nil {
+		return err
+	}
+	for _, role := range submitterRoles {
+		if !slices.Contains(roles, role) {
+			roles = append(roles, role)
+		}
+	}
+	for _, role := range roles {
+		grants = append(grants, defaultGrants(role)...)
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&grants).Error
+}
+
+// defaultGrants are the permissions a role starts with: everything for
+// adminRoles, submitting records and reading KPIs for the rest
+func defaultGrants(role string) []models.RolePermission {
+	if slices.Contains(adminRoles, role) {
+		return []models.RolePermission{{Role: role, Permission: "*"}}
+	}
+	var grants []models.RolePermission
+	for _, module := range models.RecordModules {
+		grants = append(grants, models.RolePermission{Role: role, Permission: models.PermissionName(module, models.ActionCreate)})
+	}
+	return append(grants, models.RolePermission{Role: role, Permission: models.PermissionName("kpi", models.ActionRead)})
+}
+
+// seedDefaultRoles gives the adminRoles and submitterRoles that have no
+// grants at all their defaults, with every site. Databases set up before
+// seedRolePermissions covered roles nobody held yet lack them, which left
+// a fresh install without anyone able to use the API.
+func seedDefaultRoles(tx *gorm.DB) error {
+	var seeded []string
+	if err := tx.Model(&models.RolePermission{}).Distinct().Pluck("role", &seeded).Error; err != nil {
+		return err
+	}
+	var grants []models.RolePermission
+	for _, role := range append(slices.Clone(adminRoles), submitterRoles...) {
+		if slices.Contains(seeded, role) {
+			continue
+		}
+		grants = append(grants, defaultGrants(role)...)
+		grants = append(grants, models.RolePermission{Role: role, Permission: sitesAll})
+	}
+	if len(grants) == 0 {
+		return nil
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&grants).Error
+}
+
+var sitesAll = models.PermissionName("sites", models.ActionAll)
+
+// seedSiteAccess keeps every role that already has grants unrestricted, so
+// nobody loses access on deploy. Site restriction starts for a role once
+// sites:all is taken away from it and its users are assigned sites.
+func seedSiteAccess(tx *gorm.DB) error {
+	var roles []string
+	if err := tx.Model(&models.RolePermission{}).Distinct().Pluck("role", &roles).Error; err != nil {
+		return err
+	}
+	if len(roles) == 0 {
+		return nil
+	}
+	grants := make([]models.RolePermission, len(roles))
+	for i, role := range roles {
+		grants[i] = models.RolePermission{Role: role, Permission: sitesAll}
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&grants).Error
+}
+
+// defaultApprovalStages start every request with the project coordinator;
+// payments of a lakh or more also need an admin. Existing records have no
+// request until someone submits them.
+var defaultApprovalStages = []models.ApprovalStage{
+	{Module: "payment", Position: 1, Name: "Coordinator approval", Role: "project_coordinator"},
+	{Module: "payment", Position: 2, Name: "Admin approval", Role: "admin", MinAmount: 100000},
+	{Module: "material", Position: 1, Name: "Coordinator approval", Role: "project_coordinator"},
+}
+
+// addMasterLink adds the optional foreign key column of a MasterLink, with
+// its index and a constraint that unlinks rows when the master record is
+// deleted. Only the new column is touched; the record tables are not
+// auto-migrated.
+func addMasterLink(tx *gorm.DB, link models.MasterLink) error {
+	m := tx.Migrator()
+	if !m.HasColumn(link.Model, link.IDField) {
+		if err := m.AddColumn(link.Model, link.IDField); err != nil {
+			return err
+		}
+	}
+
+	stmt := &gorm.Statement{DB: tx}
+	if err := stmt.Parse(link.Model); err != nil {
+		return err
+	}
+	table := stmt.Schema.Table
+	column := stmt.Schema.LookUpField(link.IDField).DBName
+	if err := tx.Exec("CREATE INDEX IF NOT EXISTS ? ON ? (?)",
+		clause.Column{Name: "idx_" + table + "_" + column}, clause.Table{Name: table}, clause.Column{Name: column}).Error; err != nil {
+		return err
+	}
+
+	master, _ := models.FindMasterTable(link.Kind)
+	masterStmt := &gorm.Statement{DB: tx}
+	if err := masterStmt.Parse(master.Model); err != nil {
+		return err
+	}
+	constraint := "fk_" + table + "_" + column
+	var exists int64
+	if err := tx.Raw("SELECT COUNT(*) FROM information_schema.table_constraints WHERE constraint_name = ? AND table_name = ?",
+		constraint, table).Scan(&exists).Error; err != nil {
+		return err
+	}
+	if exists > 0 {
+		return nil
+	}
+	return tx.Exec("ALTER TABLE ? ADD CONSTRAINT ? FOREIGN KEY (?) REFERENCES ? (id) ON DELETE SET NULL",
+		clause.Table{Name: table}, clause.Column{Name: constraint}, clause.Column{Name: column},
+		clause.Table{Name: masterStmt.Schema.Table}).Error
+}
+
+// numericPattern matches the legacy strings that convert cleanly once
+// whitespace and thousands separators are removed
+const numericPattern = `^[-+]?([0-9]+\.?[0-9]*|\.[0-9]+)$`
+
+// convertNumericColumn keeps the legacy text column as <column>_legacy,
+// adds a numeric column under the original name and backfills it. Values
+// that do not parse stay NULL and are copied to numeric_quarantines.
+// Databases created after the model change already have the numeric
+// column and are left alone.
+func convertNumericColumn(tx *gorm.DB, c models.NumericColumn) error {
+	columnTypes, err := tx.Migrator().ColumnTypes(c.Table)
+	if err != nil {
+		return err
+	}
+	isText := false
+	for _, ct := range columnTypes {
+		if ct.Name() == c.Column {
+			name := strings.ToLower(ct.DatabaseTypeName())
+			isText = name == "text" || strings.Contains(name, "char")
+		}
+	}
+	if !isText {
+		return nil
+	}
+
+	table := clause.Table{Name: c.Table}
+	column := clause.Column{Name: c.Column}
+	legacy := clause.Column{Name: c.Column + "_legacy"}
+	cleaned := clause.Expr{SQL: "REPLACE(TRIM(?), ',', '')", Vars: []interface{}{legacy}}
+
+	steps := []struct {
+		sql  string
+		vars []interface{}
+	}{
+		{"ALTER TABLE ? RENAME COLUMN ? TO ?", []interface{}{table, column, legacy}},
+		{"ALTER TABLE ? ALTER COLUMN ? DROP NOT NULL", []interface{}{table, legacy}},
+		{"ALTER TABLE ? ADD COLUMN ? numeric", []interface{}{table, column}},
+		// Empty strings have always meant zero to the KPI handlers
+		{"UPDATE ? SET ? = 0 WHERE ? = ''", []interface{}{table, column, cleaned}},
+		{"UPDATE ? SET ? = (?)::numeric WHERE ? ~ ?", []interface{}{table, column, cleaned, cleaned, numericPattern}},
+		{`INSERT INTO numeric_quarantines (source_table, record_id, column_name, raw_value, resolved, created_at)
+			SELECT ?, id::text, ?, COALESCE(?, ''), false, NOW() FROM ? WHERE ? IS NULL`,
+			[]interface{}{c.Table, c.Column, legacy, table, column}},
+	}
+	for _, step := range steps {
+		if err := tx.Exec(step.sql, step.vars...).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,5 +1,4 @@
-// Package docs GENERATED BY THE COMMAND ABOVE; DO NOT EDIT
-// This file was generated by swaggo/swag
+// Package docs Code generated by swaggo/swag. DO NOT EDIT
 package docs
 
 import "github.com/swaggo/swag"
@@ -16,25 +15,46 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/api/v1/admin/contractor": {
+        "/api/v1/admin/{module}": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Retrieves paginated list of contractor reports",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Lists the records of a module, paginated and filtered as any report",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "contractor"
+                    "records"
                 ],
-                "summary": "Get all contractor reports",
+                "summary": "List records",
                 "parameters": [
+                    {
+                        "enum": [
+                            "dprsite",
+                            "wrapping",
+                            "eway",
+                            "water",
+                            "stock",
+                            "dairysite",
+                            "payment",
+                            "material",
+                            "mnr",
+                            "nmr_vehicle",
+                            "contractor",
+                            "painting",
+                            "diesel",
+                            "tasks",
+                            "vehiclelog"
+                        ],
+                        "type": "string",
+                        "description": "Record module",
+                        "name": "module",
+                        "in": "path",
+                        "required": true
+                    },
                     {
                         "type": "integer",
                         "description": "Page number",
@@ -46,6 +66,66 @@ const docTemplate = `{
                         "description": "Items per page",
                         "name": "limit",
                         "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated columns to return",
+                        "name": "fields",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Columns to sort by, each - prefixed for descending",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Earliest date, YYYY-MM-DD",
+                        "name": "fromDate",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Latest date, YYYY-MM-DD",
+                        "name": "toDate",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Column the date range applies to",
+                        "name": "dateColumn",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "json, csv, xlsx or geojson",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Keyset cursor; empty for the first page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Bounding box, minLng,minLat,maxLng,maxLat",
+                        "name": "bbox",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Centre of a radius filter, lat,lng",
+                        "name": "near",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Radius around near, in km",
+                        "name": "radiusKm",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -56,40 +136,201 @@ const docTemplate = `{
                             "additionalProperties": true
                         }
                     },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "type": "string"
                         }
                     }
                 }
             }
         },
-        "/api/v1/admin/contractor/{id}": {
+        "/api/v1/admin/{module}/trash": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Retrieves a contractor report by ID",
-                "consumes": [
+                "description": "Lists the deleted records of a module, taking the list parameters",
+                "produces": [
                     "application/json"
                 ],
+                "tags": [
+                    "records"
+                ],
+                "summary": "List deleted records",
+                "parameters": [
+                    {
+                        "enum": [
+                            "dprsite",
+                            "wrapping",
+                            "eway",
+                            "water",
+                            "stock",
+                            "dairysite",
+                            "payment",
+                            "material",
+                            "mnr",
+                            "nmr_vehicle",
+                            "contractor",
+                            "painting",
+                            "diesel",
+                            "tasks",
+                            "vehiclelog"
+                        ],
+                        "type": "string",
+                        "description": "Record module",
+                        "name": "module",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Items per page",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/admin/{module}/trash/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Removes a deleted record for good. Super admins only.",
+                "tags": [
+                    "records"
+                ],
+                "summary": "Purge a record",
+                "parameters": [
+                    {
+                        "enum": [
+                            "dprsite",
+                            "wrapping",
+                            "eway",
+                            "water",
+                            "stock",
+                            "dairysite",
+                            "payment",
+                            "material",
+                            "mnr",
+                            "nmr_vehicle",
+                            "contractor",
+                            "painting",
+                            "diesel",
+                            "tasks",
+                            "vehiclelog"
+                        ],
+                        "type": "string",
+                        "description": "Record module",
+                        "name": "module",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Record ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/admin/{module}/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Gets a record by ID, with its ETag",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "contractor"
+                    "records"
                 ],
-                "summary": "Get contractor report by ID",
+                "summary": "Get a record",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Contractor Report ID",
+                        "enum": [
+                            "dprsite",
+                            "wrapping",
+                            "eway",
+                            "water",
+                            "stock",
+                            "dairysite",
+                            "payment",
+                            "material",
+                            "mnr",
+                            "nmr_vehicle",
+                            "contractor",
+                            "painting",
+                            "diesel",
+                            "tasks",
+                            "vehiclelog"
+                        ],
+                        "type": "string",
+                        "description": "Record module",
+                        "name": "module",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Record ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -99,16 +340,20 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Contractor"
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
                         }
                     },
                     "404": {
                         "description": "Not Found",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "type": "string"
                         }
                     }
                 }
@@ -119,7 +364,7 @@ const docTemplate = `{
                         "BearerAuth": []
                     }
                 ],
-                "description": "Updates a contractor report by ID",
+                "description": "Replaces a record with PUT or applies a JSON merge patch with PATCH. With If-Match the record must still carry that ETag.",
                 "consumes": [
                     "application/json"
                 ],
@@ -127,24 +372,48 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "contractor"
+                    "records"
                 ],
-                "summary": "Update contractor report",
+                "summary": "Update a record",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Contractor Report ID",
+                        "enum": [
+                            "dprsite",
+                            "wrapping",
+                            "eway",
+                            "water",
+                            "stock",
+                            "dairysite",
+                            "payment",
+                            "material",
+                            "mnr",
+                            "nmr_vehicle",
+                            "contractor",
+                            "painting",
+                            "diesel",
+                            "tasks",
+                            "vehiclelog"
+                        ],
+                        "type": "string",
+                        "description": "Record module",
+                        "name": "module",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Record ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Updated contractor report data",
-                        "name": "contractor",
+                        "description": "Record or merge patch",
+                        "name": "record",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.Contractor"
+                            "type": "object"
                         }
                     }
                 ],
@@ -152,15 +421,40 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Contractor"
+                            "type": "object",
+                            "additionalProperties": true
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.FieldError"
                             }
                         }
                     }
@@ -172,7 +466,78 @@ const docTemplate = `{
                         "BearerAuth": []
                     }
                 ],
-                "description": "Deletes a contractor report by ID",
+                "description": "Soft deletes a record. With If-Match the record must still carry that ETag.",
+                "tags": [
+                    "records"
+                ],
+                "summary": "Delete a record",
+                "parameters": [
+                    {
+                        "enum": [
+                            "dprsite",
+                            "wrapping",
+                            "eway",
+                            "water",
+                            "stock",
+                            "dairysite",
+                            "payment",
+                            "material",
+                            "mnr",
+                            "nmr_vehicle",
+                            "contractor",
+                            "painting",
+                            "diesel",
+                            "tasks",
+                            "vehiclelog"
+                        ],
+                        "type": "string",
+                        "description": "Record module",
+                        "name": "module",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Record ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Replaces a record with PUT or applies a JSON merge patch with PATCH. With If-Match the record must still carry that ETag.",
                 "consumes": [
                     "application/json"
                 ],
@@ -180,45 +545,174 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "contractor"
+                    "records"
                 ],
-                "summary": "Delete contractor report",
+                "summary": "Update a record",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "Contractor Report ID",
+                        "enum": [
+                            "dprsite",
+                            "wrapping",
+                            "eway",
+                            "water",
+                            "stock",
+                            "dairysite",
+                            "payment",
+                            "material",
+                            "mnr",
+                            "nmr_vehicle",
+                            "contractor",
+                            "painting",
+                            "diesel",
+                            "tasks",
+                            "vehiclelog"
+                        ],
+                        "type": "string",
+                        "description": "Record module",
+                        "name": "module",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Record ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Record or merge patch",
+                        "name": "record",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "No Content",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "type": "object",
+                            "additionalProperties": true
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.FieldError"
                             }
                         }
                     }
                 }
             }
         },
-        "/api/v1/contractor": {
+        "/api/v1/admin/{module}/{id}/restore": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Undoes the delete of a record",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "records"
+                ],
+                "summary": "Restore a record",
+                "parameters": [
+                    {
+                        "enum": [
+                            "dprsite",
+                            "wrapping",
+                            "eway",
+                            "water",
+                            "stock",
+                            "dairysite",
+                            "payment",
+                            "material",
+                            "mnr",
+                            "nmr_vehicle",
+                            "contractor",
+                            "painting",
+                            "diesel",
+                            "tasks",
+                            "vehiclelog"
+                        ],
+                        "type": "string",
+                        "description": "Record module",
+                        "name": "module",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Record ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/{module}": {
             "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Creates a new contractor report entry",
+                "description": "Creates a record. Uploads listed in attachmentIds are linked to it.",
                 "consumes": [
                     "application/json"
                 ],
@@ -226,17 +720,41 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "contractor"
+                    "records"
                 ],
-                "summary": "Create contractor report",
+                "summary": "Create a record",
                 "parameters": [
                     {
-                        "description": "Contractor Report Data",
-                        "name": "contractor",
+                        "enum": [
+                            "dprsite",
+                            "wrapping",
+                            "eway",
+                            "water",
+                            "stock",
+                            "dairysite",
+                            "payment",
+                            "material",
+                            "mnr",
+                            "nmr_vehicle",
+                            "contractor",
+                            "painting",
+                            "diesel",
+                            "tasks",
+                            "vehiclelog"
+                        ],
+                        "type": "string",
+                        "description": "Record module",
+                        "name": "module",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Record",
+                        "name": "record",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.Contractor"
+                            "type": "object"
                         }
                     }
                 ],
@@ -244,29 +762,42 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Contractor"
+                            "type": "object",
+                            "additionalProperties": true
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.FieldError"
                             }
                         }
                     }
                 }
             }
         },
-        "/api/v1/contractor/batch": {
+        "/api/v1/{module}/batch": {
             "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Bulk creates contractor reports. Existing IDs are ignored.",
+                "description": "Creates the records of an offline batch, reporting the outcome of each",
                 "consumes": [
                     "application/json"
                 ],
@@ -274,19 +805,43 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "contractor"
+                    "records"
                 ],
-                "summary": "Batch create contractor reports",
+                "summary": "Batch create records",
                 "parameters": [
                     {
-                        "description": "Array of contractor reports",
+                        "enum": [
+                            "dprsite",
+                            "wrapping",
+                            "eway",
+                            "water",
+                            "stock",
+                            "dairysite",
+                            "payment",
+                            "material",
+                            "mnr",
+                            "nmr_vehicle",
+                            "contractor",
+                            "painting",
+                            "diesel",
+                            "tasks",
+                            "vehiclelog"
+                        ],
+                        "type": "string",
+                        "description": "Record module",
+                        "name": "module",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Records",
                         "name": "batch",
                         "in": "body",
                         "required": true,
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/models.Contractor"
+                                "type": "object"
                             }
                         }
                     }
@@ -295,25 +850,20 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "type": "object",
+                            "additionalProperties": true
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "type": "string"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "type": "string"
                         }
                     }
                 }
@@ -321,73 +871,16 @@ const docTemplate = `{
         }
     },
     "definitions": {
-        "models.Contractor": {
+        "models.FieldError": {
             "type": "object",
             "properties": {
-                "actualMeters": {
-                    "type": "string"
-                },
-                "areaPhotos": {
-                    "type": "array",
-                    "items": {
-                        "type": "string"
-                    }
-                },
-                "cardNumber": {
-                    "type": "string"
-                },
-                "chainageFrom": {
-                    "type": "string"
-                },
-                "chainageTo": {
-                    "type": "string"
-                },
-                "contractorName": {
-                    "type": "string"
-                },
-                "contractorPhone": {
-                    "type": "string"
-                },
-                "createdAt": {
-                    "type": "string"
-                },
-                "dieselTaken": {
-                    "type": "string"
-                },
-                "id": {
-                    "type": "string"
-                },
-                "latitude": {
-                    "type": "number"
-                },
-                "longitude": {
-                    "type": "number"
-                },
-                "meterPhotos": {
-                    "type": "array",
-                    "items": {
-                        "type": "string"
-                    }
-                },
-                "siteEngineerName": {
-                    "type": "string"
-                },
-                "siteEngineerPhone": {
-                    "type": "string"
-                },
-                "siteName": {
-                    "type": "string"
-                },
-                "submittedAt": {
-                    "type": "string"
-                },
-                "updatedAt": {
+                "code": {
                     "type": "string"
                 },
-                "vehicleType": {
+                "field": {
                     "type": "string"
                 },
-                "woringHours": {
+                "message": {
                     "type": "string"
                 }
             }
@@ -405,6 +898,8 @@ var SwaggerInfo = &swag.Spec{
 	Description:      "",
 	InfoInstanceName: "swagger",
 	SwaggerTemplate:  docTemplate,
+	LeftDelim:        "{{",
+	RightDelim:       "}}",
 }
 
 func init() {
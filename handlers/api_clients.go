@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+	"p9e.in/ugcl/config"
+	"p9e.in/ugcl/middleware"
+	"p9e.in/ugcl/models"
+)
+
+type apiClientReq struct {
+	AppName        string     `json:"appName" validate:"required,max=100"`
+	AllowedPaths   []string   `json:"allowedPaths" validate:"required,min=1,dive,startswith=/"`
+	AllowedMethods []string   `json:"allowedMethods" validate:"required,min=1,dive,oneof=GET POST PUT PATCH DELETE"`
+	ReadPaths      []string   `json:"readPaths" validate:"dive,startswith=/"`
+	AllowedCIDRs   []string   `json:"allowedCidrs" validate:"dive,cidr"`
+	Sites          []string   `json:"sites" validate:"dive,required,max=200"`
+	ExpiresAt      *time.Time `json:"expiresAt"`
+}
+
+type rotateAPIKeyReq struct {
+	// GraceMinutes keeps the old key working for a while after rotation
+	GraceMinutes int `json:"graceMinutes" validate:"gte=0,lte=10080"`
+}
+
+// apiClientKeyResp is only ever sent right after a key is generated
+type apiClientKeyResp struct {
+	Client models.APIClient `json:"client"`
+	Key    string           `json:"key"`
+}
+
+// ListAPIClients handles GET /api/v1/admin/api-clients
+func ListAPIClients(w http.ResponseWriter, r *http.Request) {
+	var clients []models.APIClient
+	if err := config.DB.Order("app_name, created_at").Find(&clients).Error; err != nil {
+		http.Error(w, "DB error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(clients)
+}
+
+// CreateAPIClient handles POST /api/v1/admin/api-clients. The key is in the
+// response and cannot be retrieved again.
+func CreateAPIClient(w http.ResponseWriter, r *http.Request) {
+	var req apiClientReq
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	normalizeAPIClientReq(&req)
+	if !validateBody(w, &req) {
+		return
+	}
+
+	key, hash, err := middleware.NewAPIKey()
+	if err != nil {
+		http.Error(w, "couldn't generate key", http.StatusInternalServerError)
+		return
+	}
+	client := models.APIClient{
+		AppName:        req.AppName,
+		KeyHash:        hash,
+		KeyPrefix:      key[:8],
+		AllowedPaths:   req.AllowedPaths,
+		AllowedMethods: req.AllowedMethods,
+		ReadPaths:      req.ReadPaths,
+		AllowedCIDRs:   req.AllowedCIDRs,
+		Sites:          req.Sites,
+		ExpiresAt:      req.ExpiresAt,
+	}
+	if err := config.DB.Create(&client).Error; err != nil {
+		http.Error(w, "DB error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	middleware.InvalidateAPIKeys()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(apiClientKeyResp{Client: client, Key: key})
+}
+
+// RotateAPIClientKey handles POST /api/v1/admin/api-clients/{id}/rotate
+func RotateAPIClientKey(w http.ResponseWriter, r *http.Request) {
+	var req rotateAPIKeyReq
+	if r.ContentLength != 0 && !decodeBody(w, r, &req) {
+		return
+	}
+	if !validateBody(w, &req) {
+		return
+	}
+	client, ok := findAPIClient(w, r)
+	if !ok {
+		return
+	}
+	if client.RevokedAt != nil {
+		http.Error(w, "api client has been revoked", http.StatusConflict)
+		return
+	}
+
+	key, hash, err := middleware.NewAPIKey()
+	if err != nil {
+		http.Error(w, "couldn't generate key", http.StatusInternalServerError)
+		return
+	}
+	updates := map[string]interface{}{
+		"key_hash":                hash,
+		"key_prefix":              key[:8],
+		"previous_key_hash":       "",
+		"previous_key_expires_at": nil,
+	}
+	if req.GraceMinutes > 0 {
+		updates["previous_key_hash"] = client.KeyHash
+		updates["previous_key_expires_at"] = time.Now().Add(time.Duration(req.GraceMinutes) * time.Minute)
+	}
+	if err := config.DB.Model(&client).Updates(updates).Error; err != nil {
+		http.Error(w, "DB error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	middleware.InvalidateAPIKeys()
+
+	config.DB.First(&client, "id = ?", client.ID)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(apiClientKeyResp{Client: client, Key: key})
+}
+
+// RevokeAPIClient handles POST /api/v1/admin/api-clients/{id}/revoke. Both
+// the current and any rotated out key stop working immediately.
+func RevokeAPIClient(w http.ResponseWriter, r *http.Request) {
+	client, ok := findAPIClient(w, r)
+	if !ok {
+		return
+	}
+	if client.RevokedAt == nil {
+		now := time.Now()
+		if err := config.DB.Model(&client).Update("revoked_at", now).Error; err != nil {
+			http.Error(w, "DB error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		client.RevokedAt = &now
+		middleware.InvalidateAPIKeys()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(client)
+}
+
+func findAPIClient(w http.ResponseWriter, r *http.Request) (models.APIClient, bool) {
+	var client models.APIClient
+	err := config.DB.First(&client, "id = ?", mux.Vars(r)["id"]).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "api client not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "DB error: "+err.Error(), http.StatusInternalServerError)
+		}
+		return client, false
+	}
+	return client, true
+}
+
+// normalizeAPIClientReq upper-cases methods and turns bare IPs into
+// single-address CIDRs before validation
+func normalizeAPIClientReq(req *apiClientReq) {
+	req.AppName = strings.TrimSpace(req.AppName)
+	for i, m := range req.AllowedMethods {
+		req.AllowedMethods[i] = strings.ToUpper(strings.TrimSpace(m))
+	}
+	for i, c := range req.AllowedCIDRs {
+		c = strings.TrimSpace(c)
+		if ip := net.ParseIP(c); ip != nil {
+			if ip.To4() != nil {
+				c += "/32"
+			} else {
+				c += "/128"
+			}
+		}
+		req.AllowedCIDRs[i] = c
+	}
+}
+
+type apiClientSitesReq struct {
+	Sites []string `json:"sites" validate:"dive,required,max=200"`
+}
+
+// SetAPIClientSites handles PUT /api/v1/admin/api-clients/{id}/sites. An
+// empty list lets the client see every site again.
+func SetAPIClientSites(w http.ResponseWriter, r *http.Request) {
+	var req apiClientSitesReq
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	if !validateBody(w, &req) {
+		return
+	}
+	client, ok := findAPIClient(w, r)
+	if !ok {
+		return
+	}
+	if err := config.DB.Model(&client).Update("sites", pq.StringArray(req.Sites)).Error; err != nil {
+		http.Error(w, "DB error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	client.Sites = req.Sites
+	middleware.InvalidateAPIKeys()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(client)
+}
@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+	"p9e.in/ugcl/config"
+	"p9e.in/ugcl/middleware"
+	"p9e.in/ugcl/models"
+)
+
+type approvalActionReq struct {
+	Comment string `json:"comment"`
+}
+
+// ListPendingApprovals handles GET /api/v1/approvals/pending. It lists the
+// requests waiting on the caller's role, within the caller's sites, each
+// with its record.
+func ListPendingApprovals(w http.ResponseWriter, r *http.Request) {
+	role := middleware.GetRole(r)
+	scope, err := middleware.GetSiteScope(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	can := func(permission string) bool { return middleware.HasPermission(role, permission) }
+	pending, err := models.PendingApprovals(config.DB, role, can, scope)
+	if err != nil {
+		http.Error(w, "DB error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pending)
+}
+
+// GetApproval handles GET /api/v1/approvals/{module}/{id}, the approval
+// state of a record with every action taken on it. The requester and
+// users with <module>:read may see it.
+func GetApproval(w http.ResponseWriter, r *http.Request) {
+	flow, id, record, ok := findApprovalRecord(w, r)
+	if !ok {
+		return
+	}
+	req, err := models.FindApproval(config.DB, flow.Module, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, "no approval request for this record", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "DB error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	permission := models.PermissionName(flow.Module, models.ActionRead)
+	if req.RequestedBy != middleware.GetUserID(r) && !middleware.HasPermission(middleware.GetRole(r), permission) {
+		http.Error(w, "forbidden: requires "+permission, http.StatusForbidden)
+		return
+	}
+	req.Record = record
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(req)
+}
+
+// ActOnApproval handles POST /api/v1/approvals/{module}/{id}/{action} with
+// an optional {"comment": "..."}; rejecting needs a comment. The requester
+// or users with <module>:update may submit, <module>:approve holders with
+// the current stage's role approve or reject, and the workflow's done
+// permission (payment:pay, material:fulfil) completes an approved request.
+func ActOnApproval(w http.ResponseWriter, r *http.Request) {
+	flow, id, _, ok := findApprovalRecord(w, r)
+	if !ok {
+		return
+	}
+	var body approvalActionReq
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	action := mux.Vars(r)["action"]
+	permission := models.PermissionName(flow.Module, action)
+	switch action {
+	case models.ApprovalSubmit:
+		permission = models.PermissionName(flow.Module, models.ActionUpdate)
+		existing, err := models.FindApproval(config.DB, flow.Module, id)
+		if err == nil && existing.RequestedBy != "" && existing.RequestedBy == middleware.GetUserID(r) {
+			permission = ""
+		}
+	case models.ApprovalApprove, models.ApprovalReject:
+		permission = models.PermissionName(flow.Module, models.ActionApprove)
+	}
+	if permission != "" && !middleware.HasPermission(middleware.GetRole(r), permission) {
+		http.Error(w, "forbidden: requires "+permission, http.StatusForbidden)
+		return
+	}
+
+	var req *models.ApprovalRequest
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		req, err = models.ActOnApproval(tx, flow.Module, id, action, middleware.GetAuditActor(r), body.Comment)
+		return err
+	})
+	switch {
+	case err == nil:
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	case errors.Is(err, models.ErrApprovalState):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case errors.Is(err, models.ErrApprovalRole):
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	default:
+		writeReportError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(req)
+}
+
+// ListApprovalStages handles GET /api/v1/admin/approvals/stages
+func ListApprovalStages(w http.ResponseWriter, r *http.Request) {
+	stages := []models.ApprovalStage{}
+	if err := config.DB.Order("module, position").Find(&stages).Error; err != nil {
+		http.Error(w, "DB error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stages)
+}
+
+// SetApprovalStages handles PUT /api/v1/admin/approvals/stages/{module}
+// with the module's stages in order, replacing the current ones. Requests
+// already at a stage keep it; the new stages apply from their next step.
+func SetApprovalStages(w http.ResponseWriter, r *http.Request) {
+	module := mux.Vars(r)["module"]
+	if _, ok := models.FindApprovalWorkflow(module); !ok {
+		http.Error(w, "unknown approval module "+module, http.StatusNotFound)
+		return
+	}
+	var stages []models.ApprovalStage
+	if !decodeBody(w, r, &stages) {
+		return
+	}
+	for i := range stages {
+		if !validateBody(w, &stages[i]) {
+			return
+		}
+	}
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		return models.ReplaceApprovalStages(tx, module, stages)
+	})
+	if err != nil {
+		http.Error(w, "DB error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stages)
+}
+
+// findApprovalRecord resolves {module} and {id} to a record the caller's
+// sites allow, answering 404 or 403 when there is none
+func findApprovalRecord(w http.ResponseWriter, r *http.Request) (models.ApprovalWorkflow, uuid.UUID, interface{}, bool) {
+	vars := mux.Vars(r)
+	flow, ok := models.FindApprovalWorkflow(vars["module"])
+	if !ok {
+		http.Error(w, "unknown approval module "+vars["module"], http.StatusNotFound)
+		return flow, uuid.Nil, nil, false
+	}
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return flow, id, nil, false
+	}
+	record := newRecord(flow.Model)
+	if err := config.DB.First(record, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "DB error: "+err.Error(), http.StatusInternalServerError)
+		}
+		return flow, id, nil, false
+	}
+	if !siteAllowed(w, r, record) {
+		return flow, id, nil, false
+	}
+	return flow, id, record, true
+}
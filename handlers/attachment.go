@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+	"p9e.in/ugcl/config"
+	"p9e.in/ugcl/middleware"
+	"p9e.in/ugcl/models"
+)
+
+// decodeRecord decodes a create body into record and the attachment IDs
+// sent along with it into links, answering 400 for bad JSON
+func decodeRecord(w http.ResponseWriter, r *http.Request, record interface{}, links *models.AttachmentLinks) bool {
+	var raw json.RawMessage
+	if !decodeBody(w, r, &raw) {
+		return false
+	}
+	for _, v := range []interface{}{record, links} {
+		if err := json.Unmarshal(raw, v); err != nil {
+			http.Error(w, "invalid JSON: "+err.Error(), http.StatusBadRequest)
+			return false
+		}
+	}
+	return true
+}
+
+// checkAttachments runs models.CheckAttachments for the caller, answering
+// 422 when an attachment cannot be linked to record
+func checkAttachments(w http.ResponseWriter, r *http.Request, record interface{}, ids []uuid.UUID) bool {
+	err := models.CheckAttachments(config.DB, ids, middleware.GetUserID(r), record)
+	if err == nil {
+		return true
+	}
+	var verr models.ValidationError
+	if errors.As(err, &verr) {
+		writeValidationError(w, verr)
+		return false
+	}
+	http.Error(w, "DB error: "+err.Error(), http.StatusInternalServerError)
+	return false
+}
+
+// ListRecordAttachments handles GET /api/v1/admin/{module}/{id}/attachments.
+// It lists the uploads linked to the record and needs read access to the
+// module and the record's site.
+func ListRecordAttachments(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	module, permission := vars["module"], vars["module"]+":read"
+	model, ok := models.AuditModel(module)
+	if !ok {
+		http.Error(w, "unknown module "+module, http.StatusNotFound)
+		return
+	}
+	if !middleware.HasPermission(middleware.GetRole(r), permission) {
+		http.Error(w, "forbidden: requires "+permission, http.StatusForbidden)
+		return
+	}
+	id := strings.TrimSpace(vars["id"])
+	record, err := findUnscoped(model, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "DB error: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	if !siteAllowed(w, r, record) {
+		return
+	}
+
+	list, err := models.RecordAttachments(config.DB, module, id)
+	if err != nil {
+		http.Error(w, "DB error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// canReadAttachment reports whether the caller may download att: its
+// uploader, holders of files:read and, for a linked attachment, anyone
+// who can read the record it belongs to
+func canReadAttachment(r *http.Request, att models.Attachment) bool {
+	userID, role := middleware.GetUserID(r), middleware.GetRole(r)
+	if userID != "" && userID == att.UploadedBy {
+		return true
+	}
+	if middleware.HasPermission(role, "files:read") {
+		return true
+	}
+	if att.RecordID == nil || !middleware.HasPermission(role, att.Module+":read") {
+		return false
+	}
+	model, ok := models.AuditModel(att.Module)
+	if !ok {
+		return false
+	}
+	record, err := findUnscoped(model, att.RecordID.String())
+	if err != nil {
+		return false
+	}
+	scope, err := middleware.GetSiteScope(r)
+	if err != nil {
+		return false
+	}
+	return models.CheckRecordSite(record, scope) == nil
+}
+
+// GetPhotoDuplicateReport handles GET /api/v1/admin/reports/photo-duplicates.
+// It lists the photos of diesel, contractor, dprsite and vehiclelog records
+// that match photos of earlier records, newest first and within the
+// caller's sites, with links to both records and photos. fromDate and
+// toDate (YYYY-MM-DD, inclusive) bound when they were flagged and module
+// limits it to one module.
+func GetPhotoDuplicateReport(w http.ResponseWriter, r *http.Request) {
+	from, to, ok := parseDateRange(w, r)
+	if !ok {
+		return
+	}
+	scope, err := middleware.GetSiteScope(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	report, err := models.PhotoDuplicateReport(config.DB, scope, r.URL.Query().Get("module"), from, to)
+	if err != nil {
+		writeReportError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+	"p9e.in/ugcl/config"
+	"p9e.in/ugcl/middleware"
+	"p9e.in/ugcl/models"
+)
+
+// createAudited inserts record and its audit entry in one transaction,
+// linking the given attachments and opening its approval request when the
+// module has a workflow
+func createAudited(r *http.Request, record interface{}, attachments ...uuid.UUID) error {
+	actor := middleware.GetAuditActor(r)
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(record).Error; err != nil {
+			return err
+		}
+		if err := models.LinkAttachments(tx, attachments, record); err != nil {
+			return err
+		}
+		if err := models.WriteAudit(tx, actor, models.AuditCreate, record, nil); err != nil {
+			return err
+		}
+		return models.StartApproval(tx, actor, record)
+	})
+}
+
+// saveAudited saves record and the diff against before, its AuditSnapshot
+// from ahead of the update, in one transaction
+func saveAudited(r *http.Request, before models.AuditState, record interface{}) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(record).Error; err != nil {
+			return err
+		}
+		return models.WriteAudit(tx, middleware.GetAuditActor(r), models.AuditUpdate, record, before)
+	})
+}
+
+// deleteAudited soft-deletes record and records its last state in one
+// transaction
+func deleteAudited(r *http.Request, record interface{}) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(record).Error; err != nil {
+			return err
+		}
+		return models.WriteAudit(tx, middleware.GetAuditActor(r), models.AuditDelete, record, nil)
+	})
+}
+
+// restoreAudited undoes the soft delete of record, reloads it and records
+// the restore in one transaction
+func restoreAudited(r *http.Request, record interface{}) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(record).Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.First(record).Error; err != nil {
+			return err
+		}
+		return models.WriteAudit(tx, middleware.GetAuditActor(r), models.AuditRestore, record, nil)
+	})
+}
+
+// GetRecordHistory handles GET /api/v1/admin/{module}/{id}/history and
+// /api/v1/admin/master/{kind}/{id}/history. It lists every create, update,
+// delete and restore of the record, oldest first, and needs read access to
+// the module.
+func GetRecordHistory(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	module, permission := vars["module"], vars["module"]+":read"
+	if kind, ok := vars["kind"]; ok {
+		module, permission = "master/"+kind, "master:read"
+		if kind == models.MasterSites {
+			permission = "sites:read"
+		}
+	}
+	model, ok := models.AuditModel(module)
+	if !ok {
+		http.Error(w, "unknown module "+module, http.StatusNotFound)
+		return
+	}
+	if !middleware.HasPermission(middleware.GetRole(r), permission) {
+		http.Error(w, "forbidden: requires "+permission, http.StatusForbidden)
+		return
+	}
+
+	// Users limited to some sites only see the history of their records.
+	// Deleted records are included so their deletion can be traced.
+	id := strings.TrimSpace(vars["id"])
+	if _, scoped := model.(models.SiteScoped); scoped {
+		record, err := findUnscoped(model, id)
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			scope, err := middleware.GetSiteScope(r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if !scope.All {
+				http.Error(w, "not found", http.StatusNotFound)
+				return
+			}
+		case err != nil:
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		default:
+			if !siteAllowed(w, r, record) {
+				return
+			}
+		}
+	}
+
+	entries, err := models.AuditHistory(config.DB, module, id)
+	if err != nil {
+		http.Error(w, "DB error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
+
+// findUnscoped loads a record of model by id, including soft-deleted rows
+func findUnscoped(model interface{}, id string) (interface{}, error) {
+	record := newRecord(model)
+	err := config.DB.Unscoped().First(record, "id = ?", id).Error
+	return record, err
+}
+
+// newRecord returns a pointer to a new zero value of model's type
+func newRecord(model interface{}) interface{} {
+	return reflect.New(reflect.TypeOf(model)).Interface()
+}
@@ -5,12 +5,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"os"
 	"strconv"
 	"strings"
 
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+	"github.com/gorilla/mux"
 	"golang.org/x/crypto/bcrypt"
 	"p9e.in/ugcl/config"
 	"p9e.in/ugcl/middleware"
@@ -27,9 +26,10 @@ type registerReq struct {
 	Email    string `json:"email"`
 	Phone    string `json:"phone"`
 	Password string `json:"password"`
-	Role     string `json:"role"`
 }
 
+// Register handles the public POST /api/v1/register. Whatever the client
+// asks for, the new user gets models.DefaultRole.
 func Register(w http.ResponseWriter, r *http.Request) {
 	var req registerReq
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -47,7 +47,7 @@ func Register(w http.ResponseWriter, r *http.Request) {
 		Email:        req.Email,
 		Phone:        req.Phone,
 		PasswordHash: string(hash),
-		Role:         req.Role,
+		Role:         models.DefaultRole,
 	}
 	if err := config.DB.Create(&u).Error; err != nil {
 		if strings.Contains(err.Error(), "duplicate key") {
@@ -66,8 +66,8 @@ type loginReq struct {
 }
 
 type loginResp struct {
-	Token string      `json:"token"`
-	User  userPayload `json:"user"`
+	middleware.TokenPair
+	User userPayload `json:"user"`
 }
 type userPayload struct {
 	ID    uuid.UUID `json:"id"`
@@ -93,14 +93,18 @@ func Login(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "invalid credentials", http.StatusUnauthorized)
 		return
 	}
-	token, err := middleware.GenerateToken(u.ID.String(), u.Role, u.Name, u.Phone)
+	if !u.IsActive {
+		http.Error(w, "account is deactivated", http.StatusForbidden)
+		return
+	}
+	tokens, err := middleware.StartSession(config.DB, u, r)
 	if err != nil {
 		http.Error(w, "couldn't create token", http.StatusInternalServerError)
 		return
 	}
 	u.PasswordHash = "" // don't leak password hash
 	out := loginResp{
-		Token: token,
+		TokenPair: tokens,
 		User: userPayload{
 			ID:    u.ID,
 			Name:  u.Name,
@@ -121,16 +125,12 @@ func GetCurrentUser(w http.ResponseWriter, r *http.Request) {
 	}
 	tokenString := strings.TrimPrefix(auth, "Bearer ")
 
-	// 2) Parse & validate
-	secret := []byte(os.Getenv("JWT_SECRET"))
-	token, err := jwt.ParseWithClaims(tokenString, &models.JWTClaims{}, func(t *jwt.Token) (interface{}, error) {
-		return secret, nil
-	})
-	if err != nil || !token.Valid {
+	// 2) Parse & validate, including the session
+	claims, err := middleware.ParseToken(tokenString)
+	if err != nil {
 		http.Error(w, "Invalid token", http.StatusUnauthorized)
 		return
 	}
-	claims := token.Claims.(*models.JWTClaims)
 
 	// 3) Fetch user record
 	var user models.User
@@ -150,6 +150,67 @@ func GetCurrentUser(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(resp)
 }
 
+type refreshReq struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+// RefreshToken handles POST /api/v1/token/refresh. The refresh token in the
+// body is single use; the response carries its replacement.
+func RefreshToken(w http.ResponseWriter, r *http.Request) {
+	var req refreshReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		http.Error(w, "refreshToken is required", http.StatusBadRequest)
+		return
+	}
+	tokens, err := middleware.RefreshSession(config.DB, req.RefreshToken)
+	if err != nil {
+		if middleware.IsAuthError(err) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+		} else {
+			http.Error(w, "couldn't refresh token", http.StatusInternalServerError)
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// Logout handles POST /api/v1/logout and ends the caller's session
+func Logout(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetClaims(r)
+	if claims == nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if err := middleware.RevokeSession(config.DB, claims.SessionID, models.RevokedLogout); err != nil {
+		http.Error(w, "DB error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RevokeUserSessions handles POST /api/v1/admin/users/{id}/sessions/revoke.
+// Every token of the user stops working on its next request.
+func RevokeUserSessions(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if _, err := uuid.Parse(id); err != nil {
+		http.Error(w, "invalid user id", http.StatusBadRequest)
+		return
+	}
+	var u models.User
+	if err := config.DB.First(&u, "id = ?", id).Error; err != nil {
+		http.Error(w, "user not found", http.StatusNotFound)
+		return
+	}
+	revoked, err := middleware.RevokeUserSessions(config.DB, id, models.RevokedAdmin)
+	if err != nil {
+		http.Error(w, "DB error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"userId": id, "revoked": revoked})
+}
+
 func GetAllUsers(w http.ResponseWriter, r *http.Request) {
 	pageStr := r.URL.Query().Get("page")
 	limitStr := r.URL.Query().Get("limit")
@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"p9e.in/ugcl/config"
+	"p9e.in/ugcl/models"
+)
+
+func TestRegisterIgnoresRequestedRole(t *testing.T) {
+	setupDB(t)
+	body := `{"name":"Field Engineer","email":"fe@example.com","phone":"9845012345","password":"secret123","role":"super_admin"}`
+	w := serve(Register, httptest.NewRequest(http.MethodPost, "/api/v1/register", strings.NewReader(body)), caller{})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Register = %d %s, want 201", w.Code, w.Body)
+	}
+	var u models.User
+	if err := config.DB.First(&u, "phone = ?", "9845012345").Error; err != nil {
+		t.Fatal(err)
+	}
+	if u.Role != models.DefaultRole {
+		t.Errorf("Role = %q, want %q", u.Role, models.DefaultRole)
+	}
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"p9e.in/ugcl/config"
+	"p9e.in/ugcl/middleware"
+	"p9e.in/ugcl/models"
+)
+
+// serveBatch answers the POST /batch endpoints backed by models.BatchService.
+// The body is a JSON array of items; ?mode=atomic rejects the whole batch
+// when any item is invalid, the default ?mode=partial stores what it can.
+// The reply always lists each item's status so the app can clear its queue.
+// stamp fills in each item before validation and check, when set, vets it
+// after.
+func serveBatch[T any](w http.ResponseWriter, r *http.Request, model T, stamp func(*T), check func(*T) error) {
+	var raw []json.RawMessage
+	if !decodeBody(w, r, &raw) {
+		return
+	}
+
+	scope, err := middleware.GetSiteScope(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	service := models.NewBatchService(config.DB, model).
+		WithSiteScope(scope).
+		WithAudit(middleware.GetAuditActor(r)).
+		WithCheck(check)
+	response, err := service.Ingest(raw, r.URL.Query().Get("mode"), middleware.GetUserID(r), stamp)
+	if err != nil {
+		writeReportError(w, err)
+		return
+	}
+
+	status := http.StatusOK
+	if !response.Committed {
+		status = http.StatusInternalServerError
+		if response.Invalid > 0 {
+			status = http.StatusUnprocessableEntity
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
@@ -1,95 +0,0 @@
-package handlers
-
-import (
-	"encoding/json"
-	"net/http"
-	"strconv"
-
-	"github.com/gorilla/mux"
-	"gorm.io/gorm/clause"
-	"p9e.in/ugcl/config"
-	"p9e.in/ugcl/middleware"
-	"p9e.in/ugcl/models"
-)
-
-func GetAllDieselReports(w http.ResponseWriter, r *http.Request) {
-	params, err := models.ParseReportParams(r)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	}
-
-	if err := params.Validate(); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	}
-
-	service := models.NewReportService(config.DB, models.Diesel{})
-	response, err := service.GetReport(params)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
-}
-
-func CreateDieselReport(w http.ResponseWriter, r *http.Request) {
-	var item models.Diesel
-	json.NewDecoder(r.Body).Decode(&item)
-	user := middleware.GetUser(r)
-	item.PersonFilled = user.Name
-	item.PersonPhone = user.Phone
-	config.DB.Create(&item)
-	json.NewEncoder(w).Encode(item)
-}
-
-func GetDieselReport(w http.ResponseWriter, r *http.Request) {
-	params := mux.Vars(r)
-	id, _ := strconv.Atoi(params["id"])
-	var item models.Diesel
-	config.DB.First(&item, id)
-	json.NewEncoder(w).Encode(item)
-}
-
-func UpdateDieselReport(w http.ResponseWriter, r *http.Request) {
-	params := mux.Vars(r)
-	id, _ := strconv.Atoi(params["id"])
-	var item models.Diesel
-	config.DB.First(&item, id)
-	json.NewDecoder(r.Body).Decode(&item)
-	config.DB.Save(&item)
-	json.NewEncoder(w).Encode(item)
-}
-
-func DeleteDieselReport(w http.ResponseWriter, r *http.Request) {
-	params := mux.Vars(r)
-	id, _ := strconv.Atoi(params["id"])
-	config.DB.Delete(&models.Diesel{}, id)
-	w.WriteHeader(http.StatusNoContent)
-}
-
-// BatchDieselReports handles POST /api/v1/diesel/batch
-func BatchDiesels(w http.ResponseWriter, r *http.Request) {
-	var batch []models.Diesel
-	if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
-		http.Error(w, "invalid JSON: "+err.Error(), http.StatusBadRequest)
-		return
-	}
-	user := middleware.GetUser(r)
-	for i := range batch {
-		batch[i].PersonFilled = user.Name
-		batch[i].PersonPhone = user.Phone
-	}
-	if err := config.DB.
-		Clauses(clause.OnConflict{
-			Columns:   []clause.Column{{Name: "id"}},
-			DoNothing: true,
-		}).
-		Create(&batch).Error; err != nil {
-		http.Error(w, "db error: "+err.Error(), http.StatusInternalServerError)
-		return
-	}
-	w.WriteHeader(http.StatusOK)
-}
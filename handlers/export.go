@@ -0,0 +1,298 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"p9e.in/ugcl/models"
+)
+
+// newExportWriter returns the models.RowWriter for a csv, xlsx or geojson
+// download.
+// HTTP headers are only sent once the first row is written, so parameter
+// errors can still be answered with a 400.
+func newExportWriter(w http.ResponseWriter, format, name string) models.RowWriter {
+	filename := fmt.Sprintf("%s-%s.%s", name, time.Now().Format("20060102-150405"), format)
+	switch format {
+	case models.FormatXLSX:
+		return &xlsxWriter{w: w, filename: filename}
+	case models.FormatGeoJSON:
+		return &geojsonWriter{w: w, filename: filename}
+	}
+	return &csvWriter{w: w, filename: filename}
+}
+
+func setDownloadHeaders(w http.ResponseWriter, contentType, filename string) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+}
+
+func formatCell(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case time.Time:
+		return t.Format(time.RFC3339)
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
+
+// csvCell is formatCell for CSV. Text that a spreadsheet would take for a
+// formula, anything starting with =, +, -, @, a tab or a carriage return,
+// is prefixed with ' so it opens as the text the user typed. Numbers, which
+// numeric columns are scanned as text, are left alone.
+func csvCell(v interface{}) string {
+	s := formatCell(v)
+	text, ok := v.(string)
+	if !ok || text == "" || !strings.ContainsRune("=+-@\t\r", rune(text[0])) {
+		return s
+	}
+	if _, err := strconv.ParseFloat(text, 64); err == nil {
+		return s
+	}
+	return "'" + s
+}
+
+// csvWriter streams rows as RFC 4180 CSV
+type csvWriter struct {
+	w        http.ResponseWriter
+	filename string
+	csv      *csv.Writer
+	record   []string
+}
+
+func (c *csvWriter) WriteHeader(columns []string) error {
+	setDownloadHeaders(c.w, "text/csv; charset=utf-8", c.filename)
+	c.csv = csv.NewWriter(c.w)
+	c.record = make([]string, len(columns))
+	return c.csv.Write(columns)
+}
+
+func (c *csvWriter) WriteRow(values []interface{}) error {
+	for i, v := range values {
+		c.record[i] = csvCell(v)
+	}
+	return c.csv.Write(c.record)
+}
+
+func (c *csvWriter) Close() error {
+	c.csv.Flush()
+	return c.csv.Error()
+}
+
+// xlsxWriter streams a single-sheet workbook. The sheet XML is written
+// directly into the zip entry using inline strings, so no shared string
+// table or temporary file is needed however many rows there are.
+type xlsxWriter struct {
+	w        http.ResponseWriter
+	filename string
+	zip      *zip.Writer
+	sheet    *bufio.Writer
+	row      int
+}
+
+const (
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`
+	xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`
+	xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Report" sheetId="1" r:id="rId1"/></sheets></workbook>`
+	xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`
+	xlsxSheetStart = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+	xlsxSheetEnd = `</sheetData></worksheet>`
+)
+
+func (x *xlsxWriter) WriteHeader(columns []string) error {
+	setDownloadHeaders(x.w, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", x.filename)
+	x.zip = zip.NewWriter(x.w)
+	for _, part := range []struct{ name, body string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", xlsxWorkbook},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+	} {
+		f, err := x.zip.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, part.body); err != nil {
+			return err
+		}
+	}
+	f, err := x.zip.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	x.sheet = bufio.NewWriter(f)
+	if _, err := x.sheet.WriteString(xlsxSheetStart); err != nil {
+		return err
+	}
+	values := make([]interface{}, len(columns))
+	for i, c := range columns {
+		values[i] = c
+	}
+	return x.WriteRow(values)
+}
+
+func (x *xlsxWriter) WriteRow(values []interface{}) error {
+	x.row++
+	fmt.Fprintf(x.sheet, `<row r="%d">`, x.row)
+	for i, v := range values {
+		ref := xlsxColumnName(i) + strconv.Itoa(x.row)
+		switch t := v.(type) {
+		case nil:
+			continue
+		case int64:
+			fmt.Fprintf(x.sheet, `<c r="%s"><v>%d</v></c>`, ref, t)
+		case float64:
+			fmt.Fprintf(x.sheet, `<c r="%s"><v>%s</v></c>`, ref, strconv.FormatFloat(t, 'f', -1, 64))
+		case bool:
+			b := 0
+			if t {
+				b = 1
+			}
+			fmt.Fprintf(x.sheet, `<c r="%s" t="b"><v>%d</v></c>`, ref, b)
+		default:
+			// Inline strings are never evaluated, so text that looks like
+			// a formula needs no escaping here
+			fmt.Fprintf(x.sheet, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">`, ref)
+			if err := xml.EscapeText(x.sheet, []byte(formatCell(v))); err != nil {
+				return err
+			}
+			x.sheet.WriteString(`</t></is></c>`)
+		}
+	}
+	_, err := x.sheet.WriteString(`</row>`)
+	return err
+}
+
+func (x *xlsxWriter) Close() error {
+	if _, err := x.sheet.WriteString(xlsxSheetEnd); err != nil {
+		return err
+	}
+	if err := x.sheet.Flush(); err != nil {
+		return err
+	}
+	return x.zip.Close()
+}
+
+// xlsxColumnName converts a zero based index to A, B, ... Z, AA, AB ...
+func xlsxColumnName(i int) string {
+	name := ""
+	for i >= 0 {
+		name = string(rune('A'+i%26)) + name
+		i = i/26 - 1
+	}
+	return name
+}
+
+// geojsonWriter streams a GeoJSON FeatureCollection with one Point feature
+// per row. The latitude and longitude columns become the geometry and the
+// other columns its properties, in column order. Rows without a position
+// (0,0 or null) get a null geometry.
+type geojsonWriter struct {
+	w        http.ResponseWriter
+	filename string
+	buf      *bufio.Writer
+	columns  []string
+	lat, lng int
+	rows     int
+}
+
+func (g *geojsonWriter) WriteHeader(columns []string) error {
+	setDownloadHeaders(g.w, "application/geo+json", g.filename)
+	g.buf = bufio.NewWriter(g.w)
+	g.columns = columns
+	g.lat, g.lng = -1, -1
+	for i, c := range columns {
+		switch c {
+		case models.LatitudeField:
+			g.lat = i
+		case models.LongitudeField:
+			g.lng = i
+		}
+	}
+	_, err := g.buf.WriteString(`{"type":"FeatureCollection","features":[`)
+	return err
+}
+
+func (g *geojsonWriter) WriteRow(values []interface{}) error {
+	if g.rows > 0 {
+		g.buf.WriteByte(',')
+	}
+	g.rows++
+	g.buf.WriteString(`{"type":"Feature","geometry":`)
+	lat, okLat := geojsonCoordinate(values, g.lat)
+	lng, okLng := geojsonCoordinate(values, g.lng)
+	if okLat && okLng && (lat != 0 || lng != 0) {
+		fmt.Fprintf(g.buf, `{"type":"Point","coordinates":[%s,%s]}`,
+			strconv.FormatFloat(lng, 'f', -1, 64), strconv.FormatFloat(lat, 'f', -1, 64))
+	} else {
+		g.buf.WriteString("null")
+	}
+	g.buf.WriteString(`,"properties":{`)
+	first := true
+	for i, v := range values {
+		if i == g.lat || i == g.lng {
+			continue
+		}
+		if !first {
+			g.buf.WriteByte(',')
+		}
+		first = false
+		if t, ok := v.(time.Time); ok {
+			v = t.Format(time.RFC3339)
+		}
+		key, _ := json.Marshal(g.columns[i])
+		val, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		g.buf.Write(key)
+		g.buf.WriteByte(':')
+		g.buf.Write(val)
+	}
+	_, err := g.buf.WriteString("}}")
+	return err
+}
+
+func (g *geojsonWriter) Close() error {
+	if _, err := g.buf.WriteString("]}"); err != nil {
+		return err
+	}
+	return g.buf.Flush()
+}
+
+// geojsonCoordinate reads the number in column i of values, if there is one
+func geojsonCoordinate(values []interface{}, i int) (float64, bool) {
+	if i < 0 {
+		return 0, false
+	}
+	switch t := values[i].(type) {
+	case float64:
+		return t, true
+	case int64:
+		return float64(t), true
+	case string:
+		f, err := strconv.ParseFloat(t, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
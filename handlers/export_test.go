@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"p9e.in/ugcl/models"
+)
+
+func TestCSVEscapesFormulas(t *testing.T) {
+	tests := []struct {
+		in   interface{}
+		want string
+	}{
+		{"=HYPERLINK(\"http://x\")", "'=HYPERLINK(\"http://x\")"},
+		{"+91 98450 12345", "'+91 98450 12345"},
+		{"-cmd", "'-cmd"},
+		{"@SUM(A1)", "'@SUM(A1)"},
+		{"\tTab", "'\tTab"},
+		{"\rReturn", "'\rReturn"},
+		{"Yard A", "Yard A"},
+		{"", ""},
+		{"-12.5", "-12.5"},
+		{float64(-3), "-3"},
+		{nil, ""},
+	}
+	w := httptest.NewRecorder()
+	out := newExportWriter(w, models.FormatCSV, "test")
+	if err := out.WriteHeader([]string{"value", "n"}); err != nil {
+		t.Fatal(err)
+	}
+	for i, tt := range tests {
+		if err := out.WriteRow([]interface{}{tt.in, int64(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := out.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, tt := range tests {
+		if got := rows[i+1][0]; got != tt.want {
+			t.Errorf("cell for %q = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestXLSXWritesTextAsInlineStrings(t *testing.T) {
+	w := httptest.NewRecorder()
+	out := newExportWriter(w, models.FormatXLSX, "test")
+	if err := out.WriteHeader([]string{"remarks", "meters"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := out.WriteRow([]interface{}{"=1+1", float64(2)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := out.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	body := w.Body.Bytes()
+	zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := zr.Open("xl/worksheets/sheet1.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	sheet, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`<c r="A2" t="inlineStr"><is><t xml:space="preserve">=1+1</t></is></c>`,
+		`<c r="B2"><v>2</v></c>`,
+	} {
+		if !strings.Contains(string(sheet), want) {
+			t.Errorf("sheet lacks %s:\n%s", want, sheet)
+		}
+	}
+	if strings.Contains(string(sheet), "<f>") {
+		t.Errorf("sheet has a formula:\n%s", sheet)
+	}
+}
@@ -1,23 +1,38 @@
 package handlers
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"io"
+	"log"
 	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
 
-	"cloud.google.com/go/storage"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+	"p9e.in/ugcl/config"
+	"p9e.in/ugcl/imaging"
+	"p9e.in/ugcl/middleware"
+	"p9e.in/ugcl/models"
 )
 
-const (
-	projectID  = "ugcl-461407" // Your GCP project ID
-	bucketName = "sreeugcl"    // Replace with your GCS bucket
-)
+// fileURLTTL is how long the signed URL behind /files/{id} stays valid
+const fileURLTTL = 5 * time.Minute
 
+// UploadFile handles POST /api/v1/files/upload with a multipart "file"
+// field and optionally a "label" and a "capturedAt" time (RFC 3339). The
+// file is stored privately under a fresh ID and its unlinked attachment
+// is returned; its url is the /files/{id} link records keep, its id what
+// the create endpoints take in attachmentIds. JPEG, PNG and GIF images
+// also get a thumbnail and a web copy, see storeImageVariants.
 func UploadFile(w http.ResponseWriter, r *http.Request) {
-	ctx := context.Background()
-
 	// Parse the multipart form
 	if err := r.ParseMultipartForm(50 << 20); err != nil {
 		http.Error(w, "bad multipart form: "+err.Error(), http.StatusBadRequest)
@@ -32,33 +47,169 @@ func UploadFile(w http.ResponseWriter, r *http.Request) {
 	}
 	defer file.Close()
 
-	// Initialize GCS client
-	client, err := storage.NewClient(ctx)
+	var capturedAt *time.Time
+	if raw := r.FormValue("capturedAt"); raw != "" {
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			http.Error(w, "capturedAt must be an RFC 3339 time", http.StatusBadRequest)
+			return
+		}
+		capturedAt = &t
+	}
+
+	// Trust the content over the client's label
+	sniff := make([]byte, 512)
+	n, err := io.ReadFull(file, sniff)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		http.Error(w, "failed to read file: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	hash := sha256.New()
+	if _, err = file.Seek(0, io.SeekStart); err == nil {
+		_, err = io.Copy(hash, file)
+	}
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
 	if err != nil {
-		http.Error(w, "failed to create GCS client: "+err.Error(), http.StatusInternalServerError)
+		http.Error(w, "failed to read file: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
-	defer client.Close()
-
-	// Create object in bucket
-	object := client.Bucket(bucketName).Object(header.Filename)
-	writer := object.NewWriter(ctx)
 
-	// Optional: Make the file publicly accessible
-	object.ACL().Set(ctx, storage.AllUsers, storage.RoleReader)
+	actor := middleware.GetAuditActor(r)
+	now := time.Now()
+	att := models.Attachment{
+		ID:             uuid.New(),
+		Name:           filepath.Base(filepath.Clean("/" + header.Filename)),
+		ContentType:    http.DetectContentType(sniff[:n]),
+		Size:           header.Size,
+		SHA256:         hex.EncodeToString(hash.Sum(nil)),
+		Label:          strings.TrimSpace(r.FormValue("label")),
+		CapturedAt:     capturedAt,
+		UploadedBy:     actor.UserID,
+		UploadedByName: actor.Name,
+	}
+	att.Key = models.AttachmentKey(att.ID, header.Filename, now)
+	if !validateBody(w, &att) {
+		return
+	}
 
-	// Upload file content
-	if _, err := io.Copy(writer, file); err != nil {
-		http.Error(w, "failed to upload to GCS: "+err.Error(), http.StatusInternalServerError)
+	if err := storeImageVariants(r.Context(), &att, file); err != nil {
+		deleteObjects(r.Context(), att.Keys()[1:])
+		http.Error(w, "failed to store file: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
-	if err := writer.Close(); err != nil {
-		http.Error(w, "failed to finalize upload: "+err.Error(), http.StatusInternalServerError)
+	if err := config.Storage.Put(r.Context(), att.Key, file, att.ContentType); err != nil {
+		deleteObjects(r.Context(), att.Keys()[1:])
+		http.Error(w, "failed to store file: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := config.DB.Create(&att).Error; err != nil {
+		deleteObjects(r.Context(), att.Keys())
+		http.Error(w, "DB error: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	// Return public GCS URL
-	url := fmt.Sprintf("https://storage.googleapis.com/%s/%s", bucketName, header.Filename)
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"url": url})
+	json.NewEncoder(w).Encode(att)
+}
+
+// storeImageVariants stores the thumbnail and web copy of an image upload
+// and fills in what its EXIF data tells; the EXIF capture time wins over
+// the client's. Anything imaging cannot decode is left as it is. file is
+// rewound for storing the original.
+func storeImageVariants(ctx context.Context, att *models.Attachment, file io.ReadSeeker) error {
+	switch att.ContentType {
+	case "image/jpeg", "image/png", "image/gif":
+	default:
+		return nil
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	img, err := imaging.Process(data)
+	if errors.Is(err, imaging.ErrUnsupported) || errors.Is(err, imaging.ErrTooLarge) {
+		log.Printf("upload %s: no image variants: %v", att.ID, err)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	att.Width, att.Height = img.Width, img.Height
+	att.Latitude, att.Longitude = img.Latitude, img.Longitude
+	if img.PerceptualHash != 0 {
+		phash := int64(img.PerceptualHash)
+		att.PHash = &phash
+	}
+	if img.CapturedAt != nil {
+		att.CapturedAt = img.CapturedAt
+	}
+	for _, v := range []struct {
+		variant string
+		data    []byte
+		key     *string
+	}{
+		{models.VariantThumbnail, img.Thumbnail, &att.ThumbnailKey},
+		{models.VariantWeb, img.Web, &att.WebKey},
+	} {
+		key := models.AttachmentVariantKey(att.Key, v.variant)
+		if err := config.Storage.Put(ctx, key, bytes.NewReader(v.data), "image/jpeg"); err != nil {
+			return err
+		}
+		*v.key = key
+	}
+	return nil
+}
+
+// deleteObjects removes objects stored for an upload that failed
+func deleteObjects(ctx context.Context, keys []string) {
+	for _, key := range keys {
+		if err := config.Storage.Delete(ctx, key); err != nil {
+			log.Printf("failed to delete %s: %v", key, err)
+		}
+	}
+}
+
+// GetFile handles GET /api/v1/files/{id}. Callers that may read the
+// attachment, see canReadAttachment, are redirected to a signed URL valid
+// for fileURLTTL. ?variant=thumbnail or ?variant=web asks for a copy of
+// an image instead of the original; those carry no EXIF data.
+func GetFile(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	var att models.Attachment
+	if err := config.DB.First(&att, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "DB error: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if !canReadAttachment(r, att) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	key, ok := att.VariantKey(r.URL.Query().Get("variant"))
+	if !ok {
+		http.Error(w, "no such variant", http.StatusNotFound)
+		return
+	}
+	url, err := config.Storage.SignedURL(r.Context(), key, fileURLTTL)
+	if err != nil {
+		http.Error(w, "failed to sign URL: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, r, url, http.StatusFound)
 }
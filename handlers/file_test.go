@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"p9e.in/ugcl/config"
+	"p9e.in/ugcl/models"
+	"p9e.in/ugcl/storage"
+)
+
+// setupFiles sets up the database with attachments and points
+// config.Storage at a local store under t.TempDir(), returning the store.
+// Admins are granted files:read.
+func setupFiles(t *testing.T) *storage.Local {
+	t.Helper()
+	db := setupDB(t, &models.Attachment{})
+	if err := db.Create(&models.RolePermission{Role: "admin", Permission: "files:read"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	local, err := storage.NewLocal(filepath.Join(t.TempDir(), "uploads"), "/uploads/", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := config.Storage
+	config.Storage = local
+	t.Cleanup(func() { config.Storage = old })
+	return local
+}
+
+// uploadRequest builds a multipart upload of content as filename with
+// the given extra form fields
+func uploadRequest(t *testing.T, filename string, content []byte, fields map[string]string) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for k, v := range fields {
+		if err := mw.WriteField(k, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if filename != "" {
+		fw, err := mw.CreateFormFile("file", filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write(content)
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/files/upload", &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	return r
+}
+
+// upload stores content through UploadFile as c and returns the
+// attachment as saved
+func upload(t *testing.T, c caller, filename string, content []byte) models.Attachment {
+	t.Helper()
+	w := serve(UploadFile, uploadRequest(t, filename, content, map[string]string{"label": "bill"}), c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("UploadFile = %d %s, want 200", w.Code, w.Body)
+	}
+	var got models.Attachment
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	var att models.Attachment
+	if err := config.DB.First(&att, "id = ?", got.ID).Error; err != nil {
+		t.Fatalf("attachment %s not saved: %v", got.ID, err)
+	}
+	return att
+}
+
+func readObject(t *testing.T, key string) string {
+	t.Helper()
+	rc, err := config.Storage.Open(t.Context(), key)
+	if err != nil {
+		t.Fatalf("Open(%s): %v", key, err)
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestUploadFile(t *testing.T) {
+	setupFiles(t)
+	user := signIn(t, "user")
+
+	att := upload(t, user, "../../receipt.txt", []byte("diesel receipt"))
+	if att.Name != "receipt.txt" {
+		t.Errorf("Name = %q, want the base name receipt.txt", att.Name)
+	}
+	if !strings.HasPrefix(att.ContentType, "text/plain") {
+		t.Errorf("ContentType = %q, want the sniffed text/plain", att.ContentType)
+	}
+	if att.Size != int64(len("diesel receipt")) || att.SHA256 == "" {
+		t.Errorf("Size, SHA256 = %d, %q", att.Size, att.SHA256)
+	}
+	if att.Label != "bill" || att.UploadedBy != user.id || att.RecordID != nil {
+		t.Errorf("Label, UploadedBy, RecordID = %q, %q, %v; want an unlinked upload by %s", att.Label, att.UploadedBy, att.RecordID, user.id)
+	}
+	if att.URL != models.AttachmentURL(att.ID) {
+		t.Errorf("URL = %q, want %q", att.URL, models.AttachmentURL(att.ID))
+	}
+	if got := readObject(t, att.Key); got != "diesel receipt" {
+		t.Errorf("stored object = %q", got)
+	}
+	if att.ThumbnailKey != "" || att.WebKey != "" {
+		t.Errorf("text upload got image variants %q, %q", att.ThumbnailKey, att.WebKey)
+	}
+}
+
+func TestUploadFileImageVariants(t *testing.T) {
+	setupFiles(t)
+	var img bytes.Buffer
+	if err := png.Encode(&img, image.NewRGBA(image.Rect(0, 0, 40, 30))); err != nil {
+		t.Fatal(err)
+	}
+
+	att := upload(t, signIn(t, "user"), "meter.png", img.Bytes())
+	if att.ContentType != "image/png" || att.Width != 40 || att.Height != 30 {
+		t.Errorf("ContentType, Width, Height = %q, %d, %d", att.ContentType, att.Width, att.Height)
+	}
+	for _, key := range []string{att.Key, att.ThumbnailKey, att.WebKey} {
+		if key == "" {
+			t.Fatalf("missing object key in %+v", att)
+		}
+		readObject(t, key)
+	}
+}
+
+func TestUploadFileRejectsBadRequests(t *testing.T) {
+	setupFiles(t)
+	user := signIn(t, "user")
+	tests := []struct {
+		name     string
+		filename string
+		fields   map[string]string
+	}{
+		{"no file", "", nil},
+		{"bad capturedAt", "a.txt", map[string]string{"capturedAt": "yesterday"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(UploadFile, uploadRequest(t, tt.filename, []byte("x"), tt.fields), user)
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("UploadFile = %d %s, want 400", w.Code, w.Body)
+			}
+		})
+	}
+
+	w := serve(UploadFile, uploadRequest(t, "a.txt", []byte("x"), map[string]string{"capturedAt": "2999-01-01T00:00:00Z"}), user)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("UploadFile with a future capturedAt = %d %s, want 422", w.Code, w.Body)
+	}
+
+	var n int64
+	config.DB.Model(&models.Attachment{}).Count(&n)
+	if n != 0 {
+		t.Errorf("%d attachments saved by rejected uploads", n)
+	}
+}
+
+// getFile requests /files/{id} as c
+func getFile(id, query string, c caller) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/files/"+id+query, nil)
+	return serve(GetFile, mux.SetURLVars(r, map[string]string{"id": id}), c)
+}
+
+func TestGetFile(t *testing.T) {
+	local := setupFiles(t)
+	owner, other, admin := signIn(t, "user"), signIn(t, "user"), signIn(t, "admin")
+	att := upload(t, owner, "receipt.txt", []byte("diesel receipt"))
+	id := att.ID.String()
+
+	for _, c := range []caller{owner, admin} {
+		w := getFile(id, "", c)
+		if w.Code != http.StatusFound {
+			t.Fatalf("GetFile as %s = %d %s, want 302", c.id, w.Code, w.Body)
+		}
+		if cc := w.Header().Get("Cache-Control"); cc != "no-store" {
+			t.Errorf("Cache-Control = %q, want no-store", cc)
+		}
+
+		// The redirect is served by the store mounted as routes mounts it
+		download := httptest.NewRecorder()
+		http.StripPrefix("/uploads/", local).ServeHTTP(download, httptest.NewRequest(http.MethodGet, w.Header().Get("Location"), nil))
+		if download.Code != http.StatusOK || download.Body.String() != "diesel receipt" {
+			t.Errorf("GET %s = %d %q, want the stored file", w.Header().Get("Location"), download.Code, download.Body)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		id, query string
+		caller    caller
+		want      int
+	}{
+		{"other user", id, "", other, http.StatusForbidden},
+		{"anonymous", id, "", caller{}, http.StatusForbidden},
+		{"unknown id", uuid.NewString(), "", owner, http.StatusNotFound},
+		{"malformed id", "receipt.txt", "", owner, http.StatusNotFound},
+		{"missing variant", id, "?variant=thumbnail", owner, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := getFile(tt.id, tt.query, tt.caller); w.Code != tt.want {
+				t.Errorf("GetFile = %d %s, want %d", w.Code, w.Body, tt.want)
+			}
+		})
+	}
+}
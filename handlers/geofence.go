@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm/clause"
+	"p9e.in/ugcl/config"
+	"p9e.in/ugcl/middleware"
+	"p9e.in/ugcl/models"
+)
+
+// enforceGeofence stores the geofence outcome of a new record, answering
+// 422 when its module rejects outside submissions. It reports whether the
+// handler should carry on.
+func enforceGeofence(w http.ResponseWriter, record interface{}) bool {
+	err := models.EnforceGeofence(config.DB, record)
+	if err == nil {
+		return true
+	}
+	var verr models.ValidationError
+	if errors.As(err, &verr) {
+		writeValidationError(w, verr)
+		return false
+	}
+	http.Error(w, "DB error: "+err.Error(), http.StatusInternalServerError)
+	return false
+}
+
+// GetOutsideFenceReport handles GET /api/v1/admin/reports/geofence. It
+// lists the submissions made outside their site's geofence by engineer,
+// within the caller's sites. fromDate and toDate (YYYY-MM-DD, inclusive)
+// bound the creation date and module limits it to one module.
+func GetOutsideFenceReport(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	from, to, ok := parseDateRange(w, r)
+	if !ok {
+		return
+	}
+
+	scope, err := middleware.GetSiteScope(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	report, err := models.OutsideFenceReport(config.DB, scope, query.Get("module"), from, to)
+	if err != nil {
+		writeReportError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// ListGeofencePolicies handles GET /api/v1/admin/geofence/policies
+func ListGeofencePolicies(w http.ResponseWriter, r *http.Request) {
+	policies, err := models.GeofencePolicies(config.DB)
+	if err != nil {
+		http.Error(w, "DB error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(policies)
+}
+
+// SetGeofencePolicy handles PUT /api/v1/admin/geofence/policies/{module}
+// with {"mode": "flag" | "reject"}. It applies to submissions from then on.
+func SetGeofencePolicy(w http.ResponseWriter, r *http.Request) {
+	module := mux.Vars(r)["module"]
+	if _, ok := models.FindGeofencedModule(module); !ok {
+		http.Error(w, "unknown geofenced module "+module, http.StatusNotFound)
+		return
+	}
+	var policy models.GeofencePolicy
+	if !decodeBody(w, r, &policy) {
+		return
+	}
+	policy.Module = module
+	if !validateBody(w, &policy) {
+		return
+	}
+	err := config.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "module"}},
+		DoUpdates: clause.AssignmentColumns([]string{"mode", "updated_at"}),
+	}).Create(&policy).Error
+	if err != nil {
+		http.Error(w, "DB error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(policy)
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"p9e.in/ugcl/config"
+	"p9e.in/ugcl/middleware"
+	"p9e.in/ugcl/models"
+)
+
+// setupDB points config.DB at a fresh SQLite database under t.TempDir()
+// holding users, sessions and role permissions besides tables
+func setupDB(t *testing.T, tables ...interface{}) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tables = append(tables, &models.User{}, &models.Session{}, &models.RolePermission{})
+	if err := db.AutoMigrate(tables...); err != nil {
+		t.Fatal(err)
+	}
+	old := config.DB
+	config.DB = db
+	t.Cleanup(func() {
+		config.DB = old
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// caller is a signed in user
+type caller struct {
+	id, token string
+}
+
+// signIn creates a user with role and starts a session for them
+func signIn(t *testing.T, role string) caller {
+	t.Helper()
+	u := models.User{Name: role, Email: uuid.NewString() + "@example.com", Phone: uuid.NewString()[:15], Role: role, IsActive: true}
+	if err := config.DB.Create(&u).Error; err != nil {
+		t.Fatal(err)
+	}
+	pair, err := middleware.StartSession(config.DB, u, httptest.NewRequest(http.MethodPost, "/api/v1/login", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return caller{id: u.ID.String(), token: pair.Token}
+}
+
+// serve answers r with handler behind JWTMiddleware, as c when c has
+// signed in
+func serve(handler http.HandlerFunc, r *http.Request, c caller) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	if c.token == "" {
+		handler(w, r)
+		return w
+	}
+	r.Header.Set("Authorization", "Bearer "+c.token)
+	middleware.JWTMiddleware(handler).ServeHTTP(w, r)
+	return w
+}
@@ -23,6 +23,12 @@ func GetContractorKPIs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	labels, err := models.LoadMasterLabels(db)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
 	var (
 		totalMeters, totalDiesel, totalWorkingHours float64
 		totalReports, reportsWithPhotos             int
@@ -35,7 +41,7 @@ func GetContractorKPIs(w http.ResponseWriter, r *http.Request) {
 	)
 
 	for i, con := range contractors {
-		meters := helper.ToFloat(con.ActualMeters)
+		meters := con.ActualMeters.Float()
 		diesel := helper.ToFloat(con.DieselTaken)
 		workingHours := helper.ToFloat(con.WoringHours)
 		date := time.Time(con.SubmittedAt)
@@ -56,13 +62,13 @@ func GetContractorKPIs(w http.ResponseWriter, r *http.Request) {
 		vehicleMap[con.VehicleType]++
 
 		// CardNumber diesel
-		cardMap[con.CardNumber] += diesel
+		cardMap[labels.Of(con.FuelCardID, con.CardNumber)] += diesel
 
 		// Geo heatmap
 		geoLocations = append(geoLocations, [2]float64{con.Latitude, con.Longitude})
 
 		// Reports by date/site
-		dateKey := fmt.Sprintf("%s|%s", date.Format("2006-01-02"), con.SiteName)
+		dateKey := fmt.Sprintf("%s|%s", date.Format("2006-01-02"), labels.Of(con.SiteID, con.SiteName))
 		reportsByDateSite[dateKey]++
 
 		// For average meters/day
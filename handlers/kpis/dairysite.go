@@ -18,6 +18,12 @@ func GetDairyKPIs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	labels, err := models.LoadMasterLabels(db)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
 	// Reports submitted per site/day/engineer
 	reportsPerSite := make(map[string]int)
 	reportsPerDay := make(map[string]int)
@@ -28,11 +34,12 @@ func GetDairyKPIs(w http.ResponseWriter, r *http.Request) {
 	geoPoints := make([]kpis.GeoPoint, 0, len(sites))
 
 	for _, s := range sites {
-		reportsPerSite[s.NameOfSite]++
+		site := labels.Of(s.SiteID, s.NameOfSite)
+		reportsPerSite[site]++
 		day := time.Time(s.SubmittedAt).Format("2006-01-02")
 		reportsPerDay[day]++
 		reportsPerEngineer[s.SiteEngineerName]++
-		siteSet[s.NameOfSite] = true
+		siteSet[site] = true
 		engineerSet[s.SiteEngineerName] = true
 		geoPoints = append(geoPoints, kpis.GeoPoint{
 			Latitude:  s.Latitude,
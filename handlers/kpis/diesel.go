@@ -20,6 +20,12 @@ func GetDieselKPIs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	labels, err := models.LoadMasterLabels(db)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
 	var (
 		totalLiters, totalAmount float64
 		withPhotos, withRemarks  int
@@ -30,18 +36,18 @@ func GetDieselKPIs(w http.ResponseWriter, r *http.Request) {
 	)
 
 	for _, d := range diesels {
-		liters := helper.ToFloat(d.QuantityInLiters)
+		liters := d.QuantityInLiters.Float()
 		amount := helper.ToFloat(d.AmountPaid)
 
 		totalLiters += liters
 		totalAmount += amount
 
 		// Groupings
-		byContractor[d.ContractorName] += liters
-		byVehicle[d.VehicleNumber] += liters
-		cardDiesel[d.CardNumber] += liters
-		cardAmount[d.CardNumber] += amount
-		perSite[d.NameOfSite]++
+		byContractor[labels.Of(d.ContractorID, d.ContractorName)] += liters
+		byVehicle[labels.Of(d.VehicleID, d.VehicleNumber)] += liters
+		cardDiesel[labels.Of(d.FuelCardID, d.CardNumber)] += liters
+		cardAmount[labels.Of(d.FuelCardID, d.CardNumber)] += amount
+		perSite[labels.Of(d.SiteID, d.NameOfSite)]++
 		dateStr := time.Time(d.SubmittedAt).Format("2006-01-02")
 		perDate[dateStr]++
 
@@ -21,6 +21,12 @@ func GetStockKPIs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	labels, err := models.LoadMasterLabels(db)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
 	var (
 		totalIn, totalOut, specials, withChallan, defective int
 		delaySum, agingSum                                  float64
@@ -29,7 +35,7 @@ func GetStockKPIs(w http.ResponseWriter, r *http.Request) {
 	)
 
 	for _, s := range stocks {
-		quantity := helper.ToInt(s.ItemQuantity)
+		quantity := int(s.ItemQuantity.Float())
 		length := helper.ToInt(s.TotalLength)
 		isIn := s.InOut == "IN"
 		isOut := s.InOut == "OUT"
@@ -62,7 +68,7 @@ func GetStockKPIs(w http.ResponseWriter, r *http.Request) {
 		delaySum += delay
 		agingSum += delay
 		// Top Contractors
-		contractorMap[s.ContractorName] += quantity + length
+		contractorMap[labels.Of(s.ContractorID, s.ContractorName)] += quantity + length
 		// Top Items/PipeDia
 		itemKey := s.ItemDescription + " | " + s.PipeDia
 		itemPipeMap[itemKey] += quantity + length
@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+	"p9e.in/ugcl/config"
+	"p9e.in/ugcl/models"
+)
+
+// ListMasterRecords handles GET /api/v1/admin/master/{kind}[?q=...]. q
+// searches the name or number; sites also filter on ?cluster=.
+func ListMasterRecords(kind string) http.HandlerFunc {
+	table := mustMasterTable(kind)
+	return func(w http.ResponseWriter, r *http.Request) {
+		key, err := masterKeyColumn(table)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		query := config.DB.Model(table.Model).Order(key)
+		if q := strings.TrimSpace(r.URL.Query().Get("q")); q != "" {
+			query = query.Where(key+" ILIKE ? OR array_to_string(aliases, ' ') ILIKE ?", "%"+q+"%", "%"+q+"%")
+		}
+		if cluster := r.URL.Query().Get("cluster"); cluster != "" && kind == models.MasterSites {
+			query = query.Where("cluster = ?", cluster)
+		}
+		records := reflect.New(reflect.SliceOf(reflect.TypeOf(table.Model)))
+		if err := query.Find(records.Interface()).Error; err != nil {
+			http.Error(w, "DB error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(records.Interface())
+	}
+}
+
+// CreateMasterRecord handles POST /api/v1/admin/master/{kind}
+func CreateMasterRecord(kind string) http.HandlerFunc {
+	table := mustMasterTable(kind)
+	return func(w http.ResponseWriter, r *http.Request) {
+		record := reflect.New(reflect.TypeOf(table.Model))
+		if !decodeBody(w, r, record.Interface()) {
+			return
+		}
+		record.Elem().FieldByName("ID").Set(reflect.ValueOf(uuid.Nil))
+		store := func(rec interface{}) error { return createAudited(r, rec) }
+		if !saveMasterRecord(w, table, record, store) {
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(record.Interface())
+	}
+}
+
+// UpdateMasterRecord handles PUT /api/v1/admin/master/{kind}/{id}. Renaming
+// does not touch the free text of linked records, only their label.
+func UpdateMasterRecord(kind string) http.HandlerFunc {
+	table := mustMasterTable(kind)
+	return func(w http.ResponseWriter, r *http.Request) {
+		record, ok := findMasterRecord(w, r, table)
+		if !ok {
+			return
+		}
+		id := record.Elem().FieldByName("ID").Interface()
+		before := models.AuditSnapshot(record.Interface())
+		if !decodeBody(w, r, record.Interface()) {
+			return
+		}
+		record.Elem().FieldByName("ID").Set(reflect.ValueOf(id))
+		store := func(rec interface{}) error { return saveAudited(r, before, rec) }
+		if !saveMasterRecord(w, table, record, store) {
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(record.Interface())
+	}
+}
+
+// DeleteMasterRecord handles DELETE /api/v1/admin/master/{kind}/{id}.
+// Records linked to it keep their free text and lose the link.
+func DeleteMasterRecord(kind string) http.HandlerFunc {
+	table := mustMasterTable(kind)
+	return func(w http.ResponseWriter, r *http.Request) {
+		record, ok := findMasterRecord(w, r, table)
+		if !ok {
+			return
+		}
+		if err := deleteAudited(r, record.Interface()); err != nil {
+			http.Error(w, "DB error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// LinkMasterRecords handles POST /api/v1/admin/master/{kind}/link. It is a
+// dry run that lists proposed links unless ?apply=true is given.
+// ?threshold= sets the similarity needed to link, 0.85 by default.
+func LinkMasterRecords(kind string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		threshold := models.DefaultMasterThreshold
+		if raw := r.URL.Query().Get("threshold"); raw != "" {
+			t, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				http.Error(w, "invalid parameter threshold: must be a number", http.StatusBadRequest)
+				return
+			}
+			threshold = t
+		}
+		apply := r.URL.Query().Get("apply") == "true"
+
+		var report *models.MasterLinkReport
+		err := config.DB.Transaction(func(tx *gorm.DB) error {
+			var err error
+			report, err = models.LinkMasterData(tx, kind, threshold, apply)
+			return err
+		})
+		if err != nil {
+			writeReportError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(report)
+	}
+}
+
+// saveMasterRecord normalizes the key, validates and stores the record
+// with store, answering 422 or 409 when that is not possible
+func saveMasterRecord(w http.ResponseWriter, table models.MasterTable, record reflect.Value, store func(interface{}) error) bool {
+	keyField := record.Elem().FieldByName(table.KeyField)
+	key := strings.TrimSpace(keyField.String())
+	if table.Numbered {
+		key = models.NormalizeMasterNumber(key)
+	}
+	keyField.SetString(key)
+	if !validateBody(w, record.Interface()) {
+		return false
+	}
+
+	column, err := masterKeyColumn(table)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	var taken int64
+	err = config.DB.Model(table.Model).
+		Where("LOWER(TRIM("+column+")) = LOWER(?) AND id <> ?", key, record.Elem().FieldByName("ID").Interface()).
+		Count(&taken).Error
+	if err != nil {
+		http.Error(w, "DB error: "+err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	if taken > 0 {
+		http.Error(w, table.Kind+" "+key+" already exists", http.StatusConflict)
+		return false
+	}
+
+	if err := store(record.Interface()); err != nil {
+		http.Error(w, "DB error: "+err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
+func findMasterRecord(w http.ResponseWriter, r *http.Request, table models.MasterTable) (reflect.Value, bool) {
+	record := reflect.New(reflect.TypeOf(table.Model))
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return record, false
+	}
+	if err := config.DB.First(record.Interface(), "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, table.Kind+" not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "DB error: "+err.Error(), http.StatusInternalServerError)
+		}
+		return record, false
+	}
+	return record, true
+}
+
+func masterKeyColumn(table models.MasterTable) (string, error) {
+	stmt := &gorm.Statement{DB: config.DB}
+	if err := stmt.Parse(table.Model); err != nil {
+		return "", err
+	}
+	return stmt.Schema.LookUpField(table.KeyField).DBName, nil
+}
+
+// mustMasterTable is for route registration, where an unknown kind is a
+// programming error
+func mustMasterTable(kind string) models.MasterTable {
+	table, ok := models.FindMasterTable(kind)
+	if !ok {
+		panic("unknown master data kind " + kind)
+	}
+	return table
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+	"p9e.in/ugcl/config"
+	"p9e.in/ugcl/middleware"
+	"p9e.in/ugcl/models"
+)
+
+type rolePermissions struct {
+	Role        string   `json:"role"`
+	Permissions []string `json:"permissions"`
+}
+
+type setRolePermissionsReq struct {
+	Permissions []string `json:"permissions"`
+}
+
+// ListPermissions handles GET /api/v1/admin/permissions
+func ListPermissions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.Permissions())
+}
+
+// ListRolePermissions handles GET /api/v1/admin/roles. Roles held by users
+// but without any grant are listed with an empty set.
+func ListRolePermissions(w http.ResponseWriter, r *http.Request) {
+	var grants []models.RolePermission
+	if err := config.DB.Order("role, permission").Find(&grants).Error; err != nil {
+		http.Error(w, "DB error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var userRoles []string
+	if err := config.DB.Model(&models.User{}).Distinct().Pluck("role", &userRoles).Error; err != nil {
+		http.Error(w, "DB error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	byRole := map[string][]string{}
+	for _, role := range userRoles {
+		byRole[role] = []string{}
+	}
+	for _, g := range grants {
+		byRole[g.Role] = append(byRole[g.Role], g.Permission)
+	}
+	out := make([]rolePermissions, 0, len(byRole))
+	for role, perms := range byRole {
+		out = append(out, rolePermissions{Role: role, Permissions: perms})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Role < out[j].Role })
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(out)
+}
+
+// SetRolePermissions handles PUT /api/v1/admin/roles/{role}/permissions and
+// replaces every grant of the role
+func SetRolePermissions(w http.ResponseWriter, r *http.Request) {
+	role := strings.TrimSpace(mux.Vars(r)["role"])
+	var req setRolePermissionsReq
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	var invalid models.ValidationError
+	seen := map[string]bool{}
+	perms := []string{}
+	for i, p := range req.Permissions {
+		p = strings.TrimSpace(p)
+		if !models.IsGrantable(p) {
+			invalid = append(invalid, models.FieldError{
+				Field:   "permissions[" + strconv.Itoa(i) + "]",
+				Code:    "unknown",
+				Message: "unknown permission " + p,
+			})
+			continue
+		}
+		if !seen[p] {
+			seen[p] = true
+			perms = append(perms, p)
+		}
+	}
+	if len(invalid) > 0 {
+		writeValidationError(w, invalid)
+		return
+	}
+
+	// Refuse to let admins take permission management away from themselves
+	if role == middleware.GetRole(r) && !grantsCover(perms, "permissions:manage") {
+		http.Error(w, "this change would remove permissions:manage from your own role", http.StatusConflict)
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("role = ?", role).Delete(&models.RolePermission{}).Error; err != nil {
+			return err
+		}
+		if len(perms) == 0 {
+			return nil
+		}
+		grants := make([]models.RolePermission, len(perms))
+		for i, p := range perms {
+			grants[i] = models.RolePermission{Role: role, Permission: p}
+		}
+		return tx.Create(&grants).Error
+	})
+	if err != nil {
+		http.Error(w, "DB error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	middleware.InvalidatePermissions()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rolePermissions{Role: role, Permissions: perms})
+}
+
+func grantsCover(grants []string, permission string) bool {
+	for _, g := range grants {
+		if models.GrantCovers(g, permission) {
+			return true
+		}
+	}
+	return false
+}
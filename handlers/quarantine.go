@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"p9e.in/ugcl/config"
+	"p9e.in/ugcl/middleware"
+	"p9e.in/ugcl/models"
+)
+
+// GET /api/v1/admin/quarantine?resolved=false
+func GetNumericQuarantine(w http.ResponseWriter, r *http.Request) {
+	serveReport(w, r, models.NumericQuarantine{})
+}
+
+type resolveQuarantineReq struct {
+	Value models.Quantity `json:"value"`
+}
+
+// POST /api/v1/admin/quarantine/{id}/resolve
+// Writes the corrected number to the source record and marks the entry
+// resolved. The record is saved like any other update: audited, with a new
+// updated_at so sync pulls and ETags pick the fix up.
+func ResolveNumericQuarantine(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	var req resolveQuarantineReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var item models.NumericQuarantine
+	if err := config.DB.First(&item, "id = ?", id).Error; err != nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	if item.Resolved {
+		http.Error(w, "already resolved", http.StatusConflict)
+		return
+	}
+	var model interface{}
+	for _, c := range models.NumericColumns {
+		if c.Table == item.SourceTable && c.Column == item.ColumnName {
+			model = c.Model
+		}
+	}
+	if model == nil {
+		http.Error(w, "unknown source column", http.StatusUnprocessableEntity)
+		return
+	}
+	record, err := findUnscoped(model, item.RecordID)
+	if err != nil {
+		http.Error(w, "source record not found", http.StatusNotFound)
+		return
+	}
+	if !siteAllowed(w, r, record) {
+		return
+	}
+
+	value := float64(req.Value)
+	user := middleware.GetUser(r).Name
+	now := time.Now()
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		// Reload under a lock so the audit diff starts from what is stored
+		err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(record, "id = ?", item.RecordID).Error
+		if err != nil {
+			return err
+		}
+		before := models.AuditSnapshot(record)
+		if err := tx.Unscoped().Model(record).Update(item.ColumnName, value).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().First(record, "id = ?", item.RecordID).Error; err != nil {
+			return err
+		}
+		if err := models.WriteAudit(tx, middleware.GetAuditActor(r), models.AuditUpdate, record, before); err != nil {
+			return err
+		}
+		item.Resolved = true
+		item.ResolvedValue = &value
+		item.ResolvedBy = &user
+		item.ResolvedAt = &now
+		return tx.Save(&item).Error
+	})
+	if err != nil {
+		http.Error(w, "db error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(item)
+}
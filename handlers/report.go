@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"p9e.in/ugcl/config"
+	"p9e.in/ugcl/middleware"
+	"p9e.in/ugcl/models"
+)
+
+// serveReport answers the GET list endpoints backed by models.ReportService.
+// Mistakes in the query string (unknown fields, bad operators) return 400.
+// format=csv, xlsx or geojson streams the whole filtered result as a
+// download; bbox and near=lat,lng&radiusKm= limit it to an area.
+func serveReport[T any](w http.ResponseWriter, r *http.Request, model T) {
+	serveReportFrom(w, r, models.NewReportService(config.DB, model))
+}
+
+// serveReportFrom is serveReport for a service set up by the caller. The
+// caller's site scope is added to it.
+func serveReportFrom[T any](w http.ResponseWriter, r *http.Request, service *models.ReportService[T]) {
+	params, err := models.ParseReportParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := params.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	scope, err := middleware.GetSiteScope(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	service.WithSiteScope(scope)
+	if params.Format != models.FormatJSON {
+		out := newExportWriter(w, params.Format, service.TableName())
+		if err := service.ExportReport(params, out); err != nil {
+			if w.Header().Get("Content-Disposition") != "" {
+				// The download has started; all we can do is log and stop
+				log.Printf("export %s failed: %v", service.TableName(), err)
+				return
+			}
+			writeReportError(w, err)
+		}
+		return
+	}
+
+	response, err := service.GetReport(params)
+	if err != nil {
+		writeReportError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// writeReportError maps ReportService errors to HTTP status codes
+func writeReportError(w http.ResponseWriter, err error) {
+	var paramErr *models.ParamError
+	if errors.As(err, &paramErr) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
+
+// parseDateRange reads the fromDate and toDate parameters (YYYY-MM-DD,
+// inclusive) as the range [from, to), answering 400 when either is
+// malformed. A missing parameter leaves its end zero, that is open.
+func parseDateRange(w http.ResponseWriter, r *http.Request) (from, to time.Time, ok bool) {
+	for _, p := range []struct {
+		name string
+		dst  *time.Time
+	}{{"fromDate", &from}, {"toDate", &to}} {
+		v := r.URL.Query().Get(p.name)
+		if v == "" {
+			continue
+		}
+		d, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			http.Error(w, "invalid "+p.name+" parameter: must be YYYY-MM-DD", http.StatusBadRequest)
+			return time.Time{}, time.Time{}, false
+		}
+		*p.dst = d
+	}
+	if !to.IsZero() {
+		to = to.AddDate(0, 0, 1)
+	}
+	return from, to, true
+}
@@ -0,0 +1,360 @@
+package report_handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+	"p9e.in/ugcl/config"
+	"p9e.in/ugcl/helper"
+	"p9e.in/ugcl/middleware"
+	"p9e.in/ugcl/models"
+	"p9e.in/ugcl/models/reports"
+)
+
+// GetDailyProgressReport renders the Daily Progress Report for one site.
+// GET /api/v1/admin/reports/daily/{site}?date=YYYY-MM-DD[&format=json]
+func GetDailyProgressReport(w http.ResponseWriter, r *http.Request) {
+	site := strings.TrimSpace(mux.Vars(r)["site"])
+	if site == "" {
+		http.Error(w, "site is required", http.StatusBadRequest)
+		return
+	}
+	scope, err := middleware.GetSiteScope(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !scope.Allows(site) {
+		http.Error(w, "forbidden: not assigned to site "+site, http.StatusForbidden)
+		return
+	}
+	day := time.Now()
+	if d := r.URL.Query().Get("date"); d != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", d, time.Local)
+		if err != nil {
+			http.Error(w, "invalid date parameter: must be YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		day = parsed
+	}
+
+	report, err := buildDailyProgress(config.DB, site, day)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if r.URL.Query().Get("format") == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(report)
+		return
+	}
+
+	pdf := renderDailyProgressPDF(report)
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", fmt.Sprintf("dpr-%s-%s.pdf", safeFileName(site), report.Date)))
+	if err := pdf.Output(w); err != nil {
+		http.Error(w, "failed to render pdf: "+err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// siteScope restricts a query to one site (free text, so compared loosely)
+// and to submissions made on the given day.
+func siteScope(db *gorm.DB, siteColumn, site string, day time.Time) *gorm.DB {
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	return db.
+		Where("LOWER(TRIM("+siteColumn+")) = LOWER(TRIM(?))", site).
+		Where("submitted_at >= ? AND submitted_at < ?", start, start.AddDate(0, 0, 1)).
+		Order("submitted_at")
+}
+
+func buildDailyProgress(db *gorm.DB, site string, day time.Time) (*reports.DailyProgress, error) {
+	out := &reports.DailyProgress{Site: site, Date: day.Format("2006-01-02")}
+
+	var dprs []models.DprSite
+	if err := siteScope(db, "name_of_site", site, day).Find(&dprs).Error; err != nil {
+		return nil, err
+	}
+	for _, d := range dprs {
+		meters := d.ActualMetersLaidOnDay.Float()
+		out.TotalMetersLaid += meters
+		out.Progress = append(out.Progress, reports.ProgressEntry{
+			ChainageFrom: d.ChainageFrom,
+			ChainageTo:   d.ChainageTo,
+			MetersLaid:   meters,
+			TypeOfWorks:  d.TypeOfWorks,
+			PipeDia:      d.PipeDia,
+			Contractor:   d.NameOfContractor,
+			SiteEngineer: d.NameOfSiteEngineer,
+		})
+		out.Photos = appendPhotos(out.Photos, "DPR", "Working site", d.UploadWorkingSitePhoto)
+		out.Photos = appendPhotos(out.Photos, "DPR", "Diesel bill", d.UploadTheDieselBillPhoto)
+	}
+
+	var mnrs []models.Mnr
+	if err := siteScope(db, "name_of_site", site, day).Find(&mnrs).Error; err != nil {
+		return nil, err
+	}
+	for _, m := range mnrs {
+		entry := reports.LabourEntry{
+			Contractor:      m.ContractorName,
+			WorkDescription: m.WorkDescription,
+			Skilled:         helper.ToInt(m.SkilledLabourCount),
+			Unskilled:       helper.ToInt(m.UnskilledLabourCount),
+			Women:           helper.ToInt(m.WomenCount),
+		}
+		out.TotalSkilledLabour += entry.Skilled
+		out.TotalUnskilledLabour += entry.Unskilled
+		out.TotalWomen += entry.Women
+		out.Labour = append(out.Labour, entry)
+		out.Photos = appendPhotos(out.Photos, "MNR", "Work", helper.AsStringArray(m.WorkPhotos)...)
+	}
+
+	var nmrs []models.Nmr_Vehicle
+	if err := siteScope(db, "name_of_site", site, day).Find(&nmrs).Error; err != nil {
+		return nil, err
+	}
+	for _, n := range nmrs {
+		hours := helper.ToFloat(n.WorkedHoursPerDay)
+		vehicleType := ""
+		if n.VehicleType != nil {
+			vehicleType = *n.VehicleType
+		}
+		out.TotalEquipmentHours += hours
+		out.Equipment = append(out.Equipment, reports.EquipmentEntry{
+			Source:      "nmr_vehicle",
+			VehicleType: vehicleType,
+			Vehicle:     n.ContractorName,
+			Hours:       hours,
+			Description: n.WorkDescription,
+		})
+		out.Photos = appendPhotos(out.Photos, "NMR vehicle", "Work", helper.AsStringArray(n.WorkPhotos)...)
+	}
+
+	var logs []models.VehicleLog
+	if err := siteScope(db, "site_location", site, day).Find(&logs).Error; err != nil {
+		return nil, err
+	}
+	for _, l := range logs {
+		hours := helper.ToFloat(l.TotalWorkingHours)
+		out.TotalEquipmentHours += hours
+		out.Equipment = append(out.Equipment, reports.EquipmentEntry{
+			Source:      "vehicle_log",
+			VehicleType: l.VehicleType,
+			Vehicle:     l.RegistrationNumber,
+			Hours:       hours,
+			Description: l.WorkDescription,
+		})
+		out.Photos = appendPhotos(out.Photos, "Vehicle log", "Work", l.WorkImages...)
+	}
+
+	var diesels []models.Diesel
+	if err := siteScope(db, "name_of_site", site, day).Find(&diesels).Error; err != nil {
+		return nil, err
+	}
+	for _, d := range diesels {
+		entry := reports.DieselEntry{
+			Vehicle:    d.VehicleNumber,
+			ToWhom:     d.ToWhom,
+			CardNumber: d.CardNumber,
+			Liters:     d.QuantityInLiters.Float(),
+			Amount:     helper.ToFloat(d.AmountPaid),
+		}
+		out.TotalDieselLiters += entry.Liters
+		out.TotalDieselAmount += entry.Amount
+		out.Diesel = append(out.Diesel, entry)
+		out.Photos = appendPhotos(out.Photos, "Diesel", "Meter reading", d.MeterReadingPhotos...)
+		out.Photos = appendPhotos(out.Photos, "Diesel", "Bill", d.BillPhotos...)
+	}
+
+	var stocks []models.Stock
+	if err := siteScope(db, "yard_name", site, day).Find(&stocks).Error; err != nil {
+		return nil, err
+	}
+	for _, s := range stocks {
+		out.Stock = append(out.Stock, reports.StockEntry{
+			InOut:       s.InOut,
+			Item:        s.ItemDescription,
+			PipeDia:     s.PipeDia,
+			Quantity:    s.ItemQuantity.Float(),
+			TotalLength: s.TotalLength,
+			Vehicle:     s.VehicleNumber,
+		})
+		out.Photos = appendPhotos(out.Photos, "Stock", "Challan", helper.AsStringArray(s.ChallanFiles)...)
+	}
+
+	var waters []models.Water
+	if err := siteScope(db, "site_name", site, day).Find(&waters).Error; err != nil {
+		return nil, err
+	}
+	for _, wt := range waters {
+		liters := wt.CapacityInLiters.Float()
+		out.TotalWaterLiters += liters
+		out.Water = append(out.Water, reports.WaterEntry{
+			Tanker:   wt.TankerVehicleNumber,
+			Supplier: wt.SupplierName,
+			Purpose:  wt.Purpose,
+			Liters:   liters,
+		})
+		out.Photos = appendPhotos(out.Photos, "Water", "Tanker", helper.AsStringArray(wt.Photos)...)
+	}
+
+	return out, nil
+}
+
+func appendPhotos(photos []reports.PhotoRef, module, label string, urls ...string) []reports.PhotoRef {
+	for _, u := range urls {
+		if u = strings.TrimSpace(u); u != "" {
+			photos = append(photos, reports.PhotoRef{Module: module, Label: label, URL: u})
+		}
+	}
+	return photos
+}
+
+func safeFileName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+// renderDailyProgressPDF lays the report out as one A4 document with a
+// table per module and the photo links at the end.
+func renderDailyProgressPDF(rep *reports.DailyProgress) *fpdf.Fpdf {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle(fmt.Sprintf("Daily Progress Report - %s - %s", rep.Site, rep.Date), true)
+	pdf.SetAutoPageBreak(true, 15)
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-10)
+		pdf.SetFont("Helvetica", "I", 8)
+		pdf.CellFormat(0, 5, fmt.Sprintf("Page %d", pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.AddPage()
+
+	pdf.SetFont("Helvetica", "B", 16)
+	pdf.CellFormat(0, 10, "Daily Progress Report", "", 1, "C", false, 0, "")
+	pdf.SetFont("Helvetica", "", 11)
+	pdf.CellFormat(0, 6, tr("Site: "+rep.Site), "", 1, "C", false, 0, "")
+	pdf.CellFormat(0, 6, "Date: "+rep.Date, "", 1, "C", false, 0, "")
+	pdf.Ln(4)
+
+	section := func(title string) {
+		pdf.Ln(3)
+		pdf.SetFont("Helvetica", "B", 12)
+		pdf.CellFormat(0, 7, title, "B", 1, "L", false, 0, "")
+		pdf.Ln(1)
+	}
+	table := func(widths []float64, header []string, rows [][]string) {
+		if len(rows) == 0 {
+			pdf.SetFont("Helvetica", "I", 9)
+			pdf.CellFormat(0, 6, "No entries", "", 1, "L", false, 0, "")
+			return
+		}
+		pdf.SetFont("Helvetica", "B", 9)
+		pdf.SetFillColor(230, 230, 230)
+		for i, h := range header {
+			pdf.CellFormat(widths[i], 6, h, "1", 0, "L", true, 0, "")
+		}
+		pdf.Ln(-1)
+		pdf.SetFont("Helvetica", "", 9)
+		for _, row := range rows {
+			for i, c := range row {
+				pdf.CellFormat(widths[i], 6, tr(truncate(pdf, c, widths[i])), "1", 0, "L", false, 0, "")
+			}
+			pdf.Ln(-1)
+		}
+	}
+	total := func(label string) {
+		pdf.SetFont("Helvetica", "B", 9)
+		pdf.CellFormat(0, 6, label, "", 1, "R", false, 0, "")
+	}
+
+	section("Work progress")
+	var rows [][]string
+	for _, p := range rep.Progress {
+		rows = append(rows, []string{p.ChainageFrom, p.ChainageTo, num(p.MetersLaid), p.TypeOfWorks, p.PipeDia, p.Contractor, p.SiteEngineer})
+	}
+	table([]float64{22, 22, 20, 34, 18, 37, 37}, []string{"Ch. from", "Ch. to", "Meters", "Type of works", "Dia", "Contractor", "Engineer"}, rows)
+	total("Total meters laid: " + num(rep.TotalMetersLaid))
+
+	section("Labour")
+	rows = nil
+	for _, l := range rep.Labour {
+		rows = append(rows, []string{l.Contractor, l.WorkDescription, fmt.Sprint(l.Skilled), fmt.Sprint(l.Unskilled), fmt.Sprint(l.Women)})
+	}
+	table([]float64{45, 75, 20, 25, 25}, []string{"Contractor", "Work", "Skilled", "Unskilled", "Women"}, rows)
+	total(fmt.Sprintf("Skilled: %d   Unskilled: %d   Women: %d", rep.TotalSkilledLabour, rep.TotalUnskilledLabour, rep.TotalWomen))
+
+	section("Equipment")
+	rows = nil
+	for _, e := range rep.Equipment {
+		rows = append(rows, []string{e.VehicleType, e.Vehicle, num(e.Hours), e.Description})
+	}
+	table([]float64{40, 45, 20, 85}, []string{"Type", "Vehicle / contractor", "Hours", "Work"}, rows)
+	total("Total equipment hours: " + num(rep.TotalEquipmentHours))
+
+	section("Diesel issued")
+	rows = nil
+	for _, d := range rep.Diesel {
+		rows = append(rows, []string{d.Vehicle, d.ToWhom, d.CardNumber, num(d.Liters), num(d.Amount)})
+	}
+	table([]float64{40, 50, 40, 30, 30}, []string{"Vehicle", "To whom", "Card", "Liters", "Amount (Rs)"}, rows)
+	total(fmt.Sprintf("Total: %s L   Rs %s", num(rep.TotalDieselLiters), num(rep.TotalDieselAmount)))
+
+	section("Stock movements")
+	rows = nil
+	for _, s := range rep.Stock {
+		rows = append(rows, []string{s.InOut, s.Item, s.PipeDia, num(s.Quantity), s.TotalLength, s.Vehicle})
+	}
+	table([]float64{15, 65, 20, 25, 30, 35}, []string{"In/Out", "Item", "Dia", "Qty", "Length", "Vehicle"}, rows)
+
+	section("Water supply")
+	rows = nil
+	for _, wt := range rep.Water {
+		rows = append(rows, []string{wt.Tanker, wt.Supplier, wt.Purpose, num(wt.Liters)})
+	}
+	table([]float64{40, 50, 70, 30}, []string{"Tanker", "Supplier", "Purpose", "Liters"}, rows)
+	total("Total water: " + num(rep.TotalWaterLiters) + " L")
+
+	section("Photos")
+	if len(rep.Photos) == 0 {
+		pdf.SetFont("Helvetica", "I", 9)
+		pdf.CellFormat(0, 6, "No photos", "", 1, "L", false, 0, "")
+	}
+	for _, p := range rep.Photos {
+		pdf.SetFont("Helvetica", "B", 8)
+		pdf.CellFormat(40, 5, p.Module+" - "+p.Label, "", 0, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "U", 8)
+		pdf.SetTextColor(0, 0, 200)
+		pdf.CellFormat(0, 5, truncate(pdf, p.URL, 150), "", 1, "L", false, 0, p.URL)
+		pdf.SetTextColor(0, 0, 0)
+	}
+
+	return pdf
+}
+
+func num(f float64) string {
+	return fmt.Sprint(helper.Round(f, 2))
+}
+
+// truncate shortens s so it fits in a cell of the given width
+func truncate(pdf *fpdf.Fpdf, s string, width float64) string {
+	if pdf.GetStringWidth(s) <= width-2 {
+		return s
+	}
+	r := []rune(s)
+	for len(r) > 0 && pdf.GetStringWidth(string(r)+"...") > width-2 {
+		r = r[:len(r)-1]
+	}
+	return string(r) + "..."
+}
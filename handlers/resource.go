@@ -0,0 +1,337 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+	"p9e.in/ugcl/config"
+	"p9e.in/ugcl/middleware"
+	"p9e.in/ugcl/models"
+)
+
+// RecordResource is a record module served by routes.RegisterRoutes. Each
+// method is the handler of one route; see Resource for their behaviour.
+type RecordResource interface {
+	Module() string
+	List(w http.ResponseWriter, r *http.Request)
+	Get(w http.ResponseWriter, r *http.Request)
+	Create(w http.ResponseWriter, r *http.Request)
+	Update(w http.ResponseWriter, r *http.Request)
+	Delete(w http.ResponseWriter, r *http.Request)
+	Batch(w http.ResponseWriter, r *http.Request)
+	Trash(w http.ResponseWriter, r *http.Request)
+	Restore(w http.ResponseWriter, r *http.Request)
+	Purge(w http.ResponseWriter, r *http.Request)
+}
+
+// Resource serves the form submissions of model T under Name, which is
+// both the path and the permission module:
+//
+//	GET    /admin/{name}              list, via models.ReportService
+//	POST   /{name}                    create
+//	GET    /admin/{name}/{id}         get, with an ETag
+//	PUT    /admin/{name}/{id}         update; PATCH takes a merge patch
+//	DELETE /admin/{name}/{id}         soft delete
+//	POST   /{name}/batch              offline batch, via models.BatchService
+//	GET    /admin/{name}/trash        list deleted records
+//	POST   /admin/{name}/{id}/restore undo a delete
+//	DELETE /admin/{name}/trash/{id}   purge a deleted record for good
+//
+// A create may list uploads to link to the new record in attachmentIds;
+// GET /admin/{name}/{id}/attachments lists them. Every write is
+// site-checked and audited. Records of geofenced modules
+// have their position checked against their site's fence on create, batch
+// and update; only new submissions can be rejected for it.
+type Resource[T any] struct {
+	Name string
+	// Stamp records who entered a new record from the submitting user, on
+	// create and batch
+	Stamp func(item *T, user models.User)
+	// Check vets a record after validation on create, update and batch. A
+	// models.ValidationError it returns is answered with 422.
+	Check func(item *T) error
+}
+
+// Module implements RecordResource
+func (res Resource[T]) Module() string { return res.Name }
+
+// List implements RecordResource
+// @Summary      List records
+// @Description  Lists the records of a module, paginated and filtered as any report
+// @Tags         records
+// @Security     BearerAuth
+// @Produce      json
+// @Param        module  path      string  true  "Record module"  Enums(dprsite, wrapping, eway, water, stock, dairysite, payment, material, mnr, nmr_vehicle, contractor, painting, diesel, tasks, vehiclelog)
+// @Param        page        query     int     false  "Page number"
+// @Param        limit       query     int     false  "Items per page"
+// @Param        fields      query     string  false  "Comma-separated columns to return"
+// @Param        sort        query     string  false  "Columns to sort by, each - prefixed for descending"
+// @Param        fromDate    query     string  false  "Earliest date, YYYY-MM-DD"
+// @Param        toDate      query     string  false  "Latest date, YYYY-MM-DD"
+// @Param        dateColumn  query     string  false  "Column the date range applies to"
+// @Param        format      query     string  false  "json, csv, xlsx or geojson"
+// @Param        cursor      query     string  false  "Keyset cursor; empty for the first page"
+// @Param        bbox        query     string  false  "Bounding box, minLng,minLat,maxLng,maxLat"
+// @Param        near        query     string  false  "Centre of a radius filter, lat,lng"
+// @Param        radiusKm    query     number  false  "Radius around near, in km"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {string}  string
+// @Failure      500  {string}  string
+// @Router       /api/v1/admin/{module} [get]
+func (res Resource[T]) List(w http.ResponseWriter, r *http.Request) {
+	serveReport(w, r, *new(T))
+}
+
+// Get implements RecordResource
+// @Summary      Get a record
+// @Description  Gets a record by ID, with its ETag
+// @Tags         records
+// @Security     BearerAuth
+// @Produce      json
+// @Param        module  path      string  true  "Record module"  Enums(dprsite, wrapping, eway, water, stock, dairysite, payment, material, mnr, nmr_vehicle, contractor, painting, diesel, tasks, vehiclelog)
+// @Param        id      path      string  true  "Record ID"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      403  {string}  string
+// @Failure      404  {string}  string
+// @Router       /api/v1/admin/{module}/{id} [get]
+func (res Resource[T]) Get(w http.ResponseWriter, r *http.Request) {
+	serveGet(w, r, *new(T))
+}
+
+// Create implements RecordResource
+// @Summary      Create a record
+// @Description  Creates a record. Uploads listed in attachmentIds are linked to it.
+// @Tags         records
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        module  path      string  true  "Record module"  Enums(dprsite, wrapping, eway, water, stock, dairysite, payment, material, mnr, nmr_vehicle, contractor, painting, diesel, tasks, vehiclelog)
+// @Param        record  body      object  true  "Record"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {string}  string
+// @Failure      403  {string}  string
+// @Failure      422  {object}  models.ValidationError
+// @Router       /api/v1/{module} [post]
+func (res Resource[T]) Create(w http.ResponseWriter, r *http.Request) {
+	item := new(T)
+	var links models.AttachmentLinks
+	if !decodeRecord(w, r, item, &links) {
+		return
+	}
+	if res.Stamp != nil {
+		res.Stamp(item, middleware.GetUser(r))
+	}
+	if !validateBody(w, item) {
+		return
+	}
+	if !siteAllowed(w, r, item) {
+		return
+	}
+	if !checkRecord(w, item, res.Check) {
+		return
+	}
+	if !enforceGeofence(w, item) {
+		return
+	}
+	if !checkAttachments(w, r, item, links.AttachmentIDs) {
+		return
+	}
+	if err := createAudited(r, item, links.AttachmentIDs...); err != nil {
+		var verr models.ValidationError
+		if errors.As(err, &verr) {
+			writeValidationError(w, verr)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("ETag", recordETag(item))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(item)
+}
+
+// Update implements RecordResource
+// @Summary      Update a record
+// @Description  Replaces a record with PUT or applies a JSON merge patch with PATCH. With If-Match the record must still carry that ETag.
+// @Tags         records
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        module  path      string  true  "Record module"  Enums(dprsite, wrapping, eway, water, stock, dairysite, payment, material, mnr, nmr_vehicle, contractor, painting, diesel, tasks, vehiclelog)
+// @Param        id      path      string  true  "Record ID"
+// @Param        record  body      object  true  "Record or merge patch"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {string}  string
+// @Failure      403  {string}  string
+// @Failure      404  {string}  string
+// @Failure      412  {string}  string
+// @Failure      422  {object}  models.ValidationError
+// @Router       /api/v1/admin/{module}/{id} [put]
+// @Router       /api/v1/admin/{module}/{id} [patch]
+func (res Resource[T]) Update(w http.ResponseWriter, r *http.Request) {
+	serveUpdate(w, r, *new(T), res.Check)
+}
+
+// Delete implements RecordResource. With If-Match the record must still
+// carry that ETag.
+//
+// @Summary      Delete a record
+// @Description  Soft deletes a record. With If-Match the record must still carry that ETag.
+// @Tags         records
+// @Security     BearerAuth
+// @Param        module  path      string  true  "Record module"  Enums(dprsite, wrapping, eway, water, stock, dairysite, payment, material, mnr, nmr_vehicle, contractor, painting, diesel, tasks, vehiclelog)
+// @Param        id      path      string  true  "Record ID"
+// @Success      204  {string}  string  "No Content"
+// @Failure      403  {string}  string
+// @Failure      404  {string}  string
+// @Failure      412  {string}  string
+// @Router       /api/v1/admin/{module}/{id} [delete]
+func (res Resource[T]) Delete(w http.ResponseWriter, r *http.Request) {
+	item, ok := findRecord[T](w, r)
+	if !ok {
+		return
+	}
+	if !siteAllowed(w, r, item) {
+		return
+	}
+	if !ifMatch(r, recordETag(item)) {
+		http.Error(w, errStale.Error(), http.StatusPreconditionFailed)
+		return
+	}
+	if err := deleteAudited(r, item); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Batch implements RecordResource
+// @Summary      Batch create records
+// @Description  Creates the records of an offline batch, reporting the outcome of each
+// @Tags         records
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        module  path      string  true  "Record module"  Enums(dprsite, wrapping, eway, water, stock, dairysite, payment, material, mnr, nmr_vehicle, contractor, painting, diesel, tasks, vehiclelog)
+// @Param        batch  body      []object  true  "Records"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {string}  string
+// @Failure      500  {string}  string
+// @Router       /api/v1/{module}/batch [post]
+func (res Resource[T]) Batch(w http.ResponseWriter, r *http.Request) {
+	var stamp func(*T)
+	if res.Stamp != nil {
+		user := middleware.GetUser(r)
+		stamp = func(item *T) { res.Stamp(item, user) }
+	}
+	serveBatch(w, r, *new(T), stamp, res.Check)
+}
+
+// Trash implements RecordResource. It takes the list parameters and adds
+// deletedAt to each row.
+//
+// @Summary      List deleted records
+// @Description  Lists the deleted records of a module, taking the list parameters
+// @Tags         records
+// @Security     BearerAuth
+// @Produce      json
+// @Param        module  path      string  true  "Record module"  Enums(dprsite, wrapping, eway, water, stock, dairysite, payment, material, mnr, nmr_vehicle, contractor, painting, diesel, tasks, vehiclelog)
+// @Param        page   query     int  false  "Page number"
+// @Param        limit  query     int  false  "Items per page"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {string}  string
+// @Router       /api/v1/admin/{module}/trash [get]
+func (res Resource[T]) Trash(w http.ResponseWriter, r *http.Request) {
+	serveReportFrom(w, r, models.NewReportService(config.DB, *new(T)).OnlyDeleted())
+}
+
+// Restore implements RecordResource. Only deleted records can be restored;
+// anything else is 404.
+//
+// @Summary      Restore a record
+// @Description  Undoes the delete of a record
+// @Tags         records
+// @Security     BearerAuth
+// @Produce      json
+// @Param        module  path      string  true  "Record module"  Enums(dprsite, wrapping, eway, water, stock, dairysite, payment, material, mnr, nmr_vehicle, contractor, painting, diesel, tasks, vehiclelog)
+// @Param        id      path      string  true  "Record ID"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      403  {string}  string
+// @Failure      404  {string}  string
+// @Router       /api/v1/admin/{module}/{id}/restore [post]
+func (res Resource[T]) Restore(w http.ResponseWriter, r *http.Request) {
+	item, ok := findDeleted[T](w, r)
+	if !ok {
+		return
+	}
+	if err := restoreAudited(r, item); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("ETag", recordETag(item))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(item)
+}
+
+// Purge implements RecordResource. Only deleted records can be purged; the
+// audit trail keeps their last state.
+//
+// @Summary      Purge a record
+// @Description  Removes a deleted record for good. Super admins only.
+// @Tags         records
+// @Security     BearerAuth
+// @Param        module  path      string  true  "Record module"  Enums(dprsite, wrapping, eway, water, stock, dairysite, payment, material, mnr, nmr_vehicle, contractor, painting, diesel, tasks, vehiclelog)
+// @Param        id      path      string  true  "Record ID"
+// @Success      204  {string}  string  "No Content"
+// @Failure      403  {string}  string
+// @Failure      404  {string}  string
+// @Router       /api/v1/admin/{module}/trash/{id} [delete]
+func (res Resource[T]) Purge(w http.ResponseWriter, r *http.Request) {
+	item, ok := findDeleted[T](w, r)
+	if !ok {
+		return
+	}
+	var purged bool
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		purged, err = models.PurgeRecord(tx, middleware.GetAuditActor(r), item)
+		return err
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !purged {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// findDeleted loads the soft-deleted record named by {id}, answering 404
+// when there is none and 403 when it is outside the caller's sites
+func findDeleted[T any](w http.ResponseWriter, r *http.Request) (*T, bool) {
+	item := new(T)
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return nil, false
+	}
+	err = config.DB.Unscoped().Where("deleted_at IS NOT NULL").First(item, "id = ?", id.String()).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "DB error: "+err.Error(), http.StatusInternalServerError)
+		}
+		return nil, false
+	}
+	if !siteAllowed(w, r, item) {
+		return nil, false
+	}
+	return item, true
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import "p9e.in/ugcl/models"
+
+// Resources are the record modules, in the order of models.RecordModules.
+// Stamp names the fields each form keeps its submitter in.
+var Resources = []RecordResource{
+	Resource[models.DprSite]{Name: "dprsite", Stamp: func(item *models.DprSite, user models.User) {
+		item.InformationEnteredBy = user.Name
+		item.PhoneNumberOfInformationEnteredPerson = user.Phone
+	}},
+	Resource[models.Wrapping]{Name: "wrapping", Stamp: func(item *models.Wrapping, user models.User) {
+		item.SiteEngineerName = user.Name
+		item.SiteEngineerPhone = user.Phone
+	}},
+	Resource[models.Eway]{Name: "eway", Stamp: func(item *models.Eway, user models.User) {
+		item.EnteredBy = user.Name
+	}},
+	Resource[models.Water]{Name: "water", Stamp: func(item *models.Water, user models.User) {
+		item.SiteEngineerName = user.Name
+		item.SiteEngineerPhone = user.Phone
+	}},
+	Resource[models.Stock]{Name: "stock", Stamp: func(item *models.Stock, user models.User) {
+		item.YardInchargeName = user.Name
+		item.YardInchargePhone = user.Phone
+	}},
+	Resource[models.DairySite]{Name: "dairysite", Stamp: func(item *models.DairySite, user models.User) {
+		item.SiteEngineerName = user.Name
+		item.SiteEngineerPhone = user.Phone
+	}},
+	Resource[models.Payment]{Name: "payment", Stamp: func(item *models.Payment, user models.User) {
+		item.SiteEngineerName = user.Name
+		item.SiteEngineerPhone = user.Phone
+	}},
+	Resource[models.Material]{Name: "material", Stamp: func(item *models.Material, user models.User) {
+		item.SiteEngineerName = user.Name
+		item.PhoneNumber = user.Phone
+	}},
+	Resource[models.Mnr]{Name: "mnr", Stamp: func(item *models.Mnr, user models.User) {
+		item.AttendanceTakenBy = user.Name
+		item.AttendancePhone = user.Phone
+	}},
+	Resource[models.Nmr_Vehicle]{Name: "nmr_vehicle", Stamp: func(item *models.Nmr_Vehicle, user models.User) {
+		item.AttendanceTakenBy = user.Name
+		item.AttendancePhone = user.Phone
+	}},
+	Resource[models.Contractor]{Name: "contractor", Stamp: func(item *models.Contractor, user models.User) {
+		item.SiteEngineerName = user.Name
+		item.SiteEngineerPhone = user.Phone
+	}},
+	Resource[models.Painting]{Name: "painting", Stamp: func(item *models.Painting, user models.User) {
+		item.SiteEngineerName = user.Name
+		item.PhoneNumber = user.Phone
+	}},
+	Resource[models.Diesel]{Name: "diesel", Stamp: func(item *models.Diesel, user models.User) {
+		item.PersonFilled = user.Name
+		item.PersonPhone = user.Phone
+	}},
+	Resource[models.Task]{Name: "tasks", Stamp: func(item *models.Task, user models.User) {
+		item.SiteEngineerName = user.Name
+		item.SiteEngineerPhone = user.Phone
+	}},
+	Resource[models.VehicleLog]{Name: "vehiclelog", Stamp: func(item *models.VehicleLog, user models.User) {
+		item.SiteEngineerName = user.Name
+		item.SiteEngineerPhone = user.Phone
+	}},
+}
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+	"p9e.in/ugcl/config"
+	"p9e.in/ugcl/models"
+)
+
+// userSites is both the body of PUT /admin/users/{id}/sites and its reply
+type userSites struct {
+	Sites    []string `json:"sites"`
+	Clusters []string `json:"clusters"`
+}
+
+// GetUserSites handles GET /api/v1/admin/users/{id}/sites
+func GetUserSites(w http.ResponseWriter, r *http.Request) {
+	userID, ok := findUserID(w, r)
+	if !ok {
+		return
+	}
+	out, err := loadUserSites(userID)
+	if err != nil {
+		http.Error(w, "DB error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(out)
+}
+
+// SetUserSites handles PUT /api/v1/admin/users/{id}/sites and replaces the
+// user's site and cluster assignments. Names must match the site registry.
+func SetUserSites(w http.ResponseWriter, r *http.Request) {
+	userID, ok := findUserID(w, r)
+	if !ok {
+		return
+	}
+	var req userSites
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	var registry []models.Site
+	if err := config.DB.Find(&registry).Error; err != nil {
+		http.Error(w, "DB error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	siteNames := map[string]string{}
+	clusters := map[string]bool{}
+	for _, s := range registry {
+		siteNames[models.NormalizeSiteName(s.Name)] = s.Name
+		if s.Cluster != "" {
+			clusters[s.Cluster] = true
+		}
+	}
+
+	var invalid models.ValidationError
+	var sites, userClusters []string
+	seen := map[string]bool{}
+	for i, name := range req.Sites {
+		canonical, found := siteNames[models.NormalizeSiteName(name)]
+		if !found {
+			invalid = append(invalid, models.FieldError{
+				Field:   "sites[" + strconv.Itoa(i) + "]",
+				Code:    "unknown",
+				Message: "unknown site " + name,
+			})
+			continue
+		}
+		if !seen["s:"+canonical] {
+			seen["s:"+canonical] = true
+			sites = append(sites, canonical)
+		}
+	}
+	for i, c := range req.Clusters {
+		c = strings.TrimSpace(c)
+		if !clusters[c] {
+			invalid = append(invalid, models.FieldError{
+				Field:   "clusters[" + strconv.Itoa(i) + "]",
+				Code:    "unknown",
+				Message: "unknown cluster " + c,
+			})
+			continue
+		}
+		if !seen["c:"+c] {
+			seen["c:"+c] = true
+			userClusters = append(userClusters, c)
+		}
+	}
+	if len(invalid) > 0 {
+		writeValidationError(w, invalid)
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.UserSite{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.UserCluster{}).Error; err != nil {
+			return err
+		}
+		for _, s := range sites {
+			if err := tx.Create(&models.UserSite{UserID: userID, SiteName: s}).Error; err != nil {
+				return err
+			}
+		}
+		for _, c := range userClusters {
+			if err := tx.Create(&models.UserCluster{UserID: userID, Cluster: c}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		http.Error(w, "DB error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	out, err := loadUserSites(userID)
+	if err != nil {
+		http.Error(w, "DB error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(out)
+}
+
+func findUserID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid user id", http.StatusBadRequest)
+		return uuid.Nil, false
+	}
+	var user models.User
+	if err := config.DB.Select("id").First(&user, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "user not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "DB error: "+err.Error(), http.StatusInternalServerError)
+		}
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func loadUserSites(userID uuid.UUID) (userSites, error) {
+	out := userSites{Sites: []string{}, Clusters: []string{}}
+	if err := config.DB.Model(&models.UserSite{}).Where("user_id = ?", userID).
+		Order("site_name").Pluck("site_name", &out.Sites).Error; err != nil {
+		return out, err
+	}
+	err := config.DB.Model(&models.UserCluster{}).Where("user_id = ?", userID).
+		Order("cluster").Pluck("cluster", &out.Clusters).Error
+	return out, err
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"p9e.in/ugcl/middleware"
+	"p9e.in/ugcl/models"
+)
+
+// decodeBody reads the JSON request body into v and answers 400 when it
+// cannot be parsed. It reports whether the handler should carry on.
+func decodeBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		http.Error(w, "invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// validateBody runs models.ValidateModel and answers 422 with the list of
+// {field, code, message} failures. It reports whether the model is valid.
+func validateBody(w http.ResponseWriter, model interface{}) bool {
+	err := models.ValidateModel(model)
+	if err == nil {
+		return true
+	}
+	var verr models.ValidationError
+	if !errors.As(err, &verr) {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	writeValidationError(w, verr)
+	return false
+}
+
+// writeValidationError answers 422 with the list of field failures
+func writeValidationError(w http.ResponseWriter, verr models.ValidationError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	json.NewEncoder(w).Encode(verr)
+}
+
+// siteAllowed answers 403 when record belongs to a site outside the
+// caller's scope. It reports whether the handler should carry on.
+func siteAllowed(w http.ResponseWriter, r *http.Request, record interface{}) bool {
+	scope, err := middleware.GetSiteScope(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	err = models.CheckRecordSite(record, scope)
+	if err == nil {
+		return true
+	}
+	var verr models.ValidationError
+	if !errors.As(err, &verr) {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusForbidden)
+	json.NewEncoder(w).Encode(verr)
+	return false
+}
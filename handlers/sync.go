@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"p9e.in/ugcl/config"
+	"p9e.in/ugcl/middleware"
+	"p9e.in/ugcl/models"
+)
+
+const (
+	defaultSyncLimit = 500
+	maxSyncLimit     = 2000
+)
+
+// GetSync handles GET /api/v1/sync?since=<watermark>&modules=dprsite,stock&limit=500
+//
+// Omit since for a first full pull. Store the returned watermark only after
+// the page has been applied locally and keep pulling while hasMore is true.
+func GetSync(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	limit := defaultSyncLimit
+	if raw := query.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxSyncLimit {
+			http.Error(w, "invalid parameter limit: must be between 1 and "+strconv.Itoa(maxSyncLimit), http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	var modules []string
+	for _, m := range strings.Split(query.Get("modules"), ",") {
+		if m = strings.TrimSpace(m); m != "" {
+			modules = append(modules, m)
+		}
+	}
+
+	scope, err := middleware.GetSiteScope(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	user := middleware.GetUser(r)
+	viewer := models.SyncViewer{
+		Name:  user.Name,
+		Phone: user.Phone,
+		// <module>:read pulls every record of a module, otherwise
+		// users only get back what they submitted themselves
+		CanReadAll: func(module string) bool {
+			return middleware.HasPermission(user.Role, models.PermissionName(module, models.ActionRead))
+		},
+		Scope: scope,
+	}
+
+	response, err := models.NewSyncService(config.DB).Pull(query.Get("since"), modules, limit, viewer)
+	if err != nil {
+		writeReportError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,287 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+	"p9e.in/ugcl/config"
+	"p9e.in/ugcl/middleware"
+	"p9e.in/ugcl/models"
+)
+
+// errStale is returned when a record changed after it was read
+var errStale = errors.New("record was changed by someone else; reload it and retry")
+
+// serveGet answers GET /{module}/{id} with the record and its ETag
+func serveGet[T any](w http.ResponseWriter, r *http.Request, model T) {
+	record, ok := findRecord[T](w, r)
+	if !ok {
+		return
+	}
+	if !siteAllowed(w, r, record) {
+		return
+	}
+	w.Header().Set("ETag", recordETag(record))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(record)
+}
+
+// serveUpdate answers PUT and PATCH /{module}/{id}. PUT decodes the body
+// over the stored record; PATCH applies it as a JSON Merge Patch (RFC 7396),
+// where null clears a field. With If-Match the record must still carry
+// that ETag. Either way the save only goes through if nobody else saved the
+// record since it was read, and 412 is returned otherwise. check, when set,
+// vets the updated record after validation.
+func serveUpdate[T any](w http.ResponseWriter, r *http.Request, model T, check func(*T) error) {
+	record, ok := findRecord[T](w, r)
+	if !ok {
+		return
+	}
+	if !siteAllowed(w, r, record) {
+		return
+	}
+	if !ifMatch(r, recordETag(record)) {
+		http.Error(w, errStale.Error(), http.StatusPreconditionFailed)
+		return
+	}
+
+	v := reflect.ValueOf(record).Elem()
+	id := v.FieldByName("ID").Interface()
+	createdAt := v.FieldByName("CreatedAt").Interface()
+	version := recordVersion(record)
+	before := models.AuditSnapshot(record)
+	if r.Method == http.MethodPatch {
+		if !mergePatchBody(w, r, record) {
+			return
+		}
+	} else if !decodeBody(w, r, record) {
+		return
+	}
+	// Neither the identity nor the creation time can be edited
+	v.FieldByName("ID").Set(reflect.ValueOf(id))
+	v.FieldByName("CreatedAt").Set(reflect.ValueOf(createdAt))
+
+	if !validateBody(w, record) {
+		return
+	}
+	if !siteAllowed(w, r, record) {
+		return
+	}
+	if !checkRecord(w, record, check) {
+		return
+	}
+	// An edit may move the record, so its geofence outcome is redone
+	if _, _, err := models.ApplyGeofence(config.DB, record); err != nil {
+		http.Error(w, "DB error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := saveVersioned(r, before, record, version); err != nil {
+		if errors.Is(err, errStale) {
+			http.Error(w, err.Error(), http.StatusPreconditionFailed)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("ETag", recordETag(record))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(record)
+}
+
+// checkRecord runs a module's check on record, answering 422 with the
+// failures it reports. It reports whether the handler should carry on.
+func checkRecord[T any](w http.ResponseWriter, record *T, check func(*T) error) bool {
+	if check == nil {
+		return true
+	}
+	err := check(record)
+	if err == nil {
+		return true
+	}
+	var verr models.ValidationError
+	if !errors.As(err, &verr) {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	writeValidationError(w, verr)
+	return false
+}
+
+// saveVersioned saves record and its audit entry in one transaction,
+// provided its updated_at is still version, and reloads it so the new
+// version is the one stored. It returns errStale when it is not.
+func saveVersioned(r *http.Request, before models.AuditState, record interface{}, version time.Time) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(record).Where("updated_at = ?", version).Select("*").Updates(record)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errStale
+		}
+		if err := tx.First(record).Error; err != nil {
+			return err
+		}
+		return models.WriteAudit(tx, middleware.GetAuditActor(r), models.AuditUpdate, record, before)
+	})
+}
+
+// findRecord loads the record named by {id}, answering 404 when there is
+// none
+func findRecord[T any](w http.ResponseWriter, r *http.Request) (*T, bool) {
+	record := new(T)
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return nil, false
+	}
+	if err := config.DB.First(record, "id = ?", id.String()).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "DB error: "+err.Error(), http.StatusInternalServerError)
+		}
+		return nil, false
+	}
+	return record, true
+}
+
+// recordVersion is the record's last save time, which every save changes
+func recordVersion(record interface{}) time.Time {
+	t, _ := reflect.Indirect(reflect.ValueOf(record)).FieldByName("UpdatedAt").Interface().(time.Time)
+	return t
+}
+
+// recordETag identifies the stored version of a record
+func recordETag(record interface{}) string {
+	return `"` + strconv.FormatInt(recordVersion(record).UnixMicro(), 36) + `"`
+}
+
+// ifMatch reports whether the If-Match header, when given, lists etag or *
+func ifMatch(r *http.Request, etag string) bool {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		return true
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// mergePatchBody applies the request body to record as a JSON Merge Patch
+// and answers 400 when it is not a JSON object. It reports whether the
+// handler should carry on.
+func mergePatchBody(w http.ResponseWriter, r *http.Request, record interface{}) bool {
+	var patch map[string]interface{}
+	dec := json.NewDecoder(r.Body)
+	dec.UseNumber()
+	if err := dec.Decode(&patch); err != nil {
+		http.Error(w, "invalid merge patch: "+err.Error(), http.StatusBadRequest)
+		return false
+	}
+
+	var current map[string]interface{}
+	raw, err := json.Marshal(record)
+	if err == nil {
+		dec = json.NewDecoder(bytes.NewReader(raw))
+		dec.UseNumber()
+		err = dec.Decode(&current)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return false
+	}
+
+	// Only the fields named in the patch are decoded over the record, each
+	// reset first: decoding leaves a null untouched for anything but
+	// pointers, maps and slices, and merges into a map rather than replacing
+	// it. Patch keys match fields as case-insensitively as decoding does, so
+	// each is looked up under the name the record marshals it with.
+	changed := make(map[string]interface{}, len(patch))
+	for k, v := range patch {
+		field, name, ok := jsonField(reflect.ValueOf(record).Elem(), k)
+		if !ok {
+			continue
+		}
+		field.Set(reflect.Zero(field.Type()))
+		if v != nil {
+			changed[name] = mergePatch(current[name], v)
+		}
+	}
+	raw, err = json.Marshal(changed)
+	if err == nil {
+		err = json.Unmarshal(raw, record)
+	}
+	if err != nil {
+		http.Error(w, "invalid merge patch: "+err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// jsonField finds the field of struct v that decodes the JSON member
+// name, matching names the way encoding/json does, and returns it with the
+// name it is marshalled under. Unknown names are not found, and decoding
+// ignores them anyway.
+func jsonField(v reflect.Value, name string) (reflect.Value, string, bool) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if tag == "-" {
+			continue
+		}
+		if f.Anonymous && tag == "" && f.Type.Kind() == reflect.Struct {
+			if field, fname, ok := jsonField(v.Field(i), name); ok {
+				return field, fname, true
+			}
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if tag == "" {
+			tag = f.Name
+		}
+		if strings.EqualFold(tag, name) {
+			return v.Field(i), tag, true
+		}
+	}
+	return reflect.Value{}, "", false
+}
+
+// mergePatch applies patch to target as RFC 7396 describes: objects merge
+// key by key, null removes a key and anything else replaces the target
+func mergePatch(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	merged := map[string]interface{}{}
+	if t, ok := target.(map[string]interface{}); ok {
+		for k, v := range t {
+			merged[k] = v
+		}
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(merged, k)
+		} else {
+			merged[k] = mergePatch(merged[k], v)
+		}
+	}
+	return merged
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		name          string
+		target, patch interface{}
+		want          interface{}
+	}{
+		{"replace scalar", "a", "b", "b"},
+		{"replace object with scalar", map[string]interface{}{"a": "b"}, "c", "c"},
+		{"add key", map[string]interface{}{"a": "b"}, map[string]interface{}{"c": "d"}, map[string]interface{}{"a": "b", "c": "d"}},
+		{"remove key", map[string]interface{}{"a": "b", "c": "d"}, map[string]interface{}{"a": nil}, map[string]interface{}{"c": "d"}},
+		{"replace array", []interface{}{"a"}, []interface{}{"b"}, []interface{}{"b"}},
+		{"merge into scalar", "a", map[string]interface{}{"b": "c"}, map[string]interface{}{"b": "c"}},
+		{
+			"nested",
+			map[string]interface{}{"a": map[string]interface{}{"b": "c", "d": "e"}},
+			map[string]interface{}{"a": map[string]interface{}{"d": nil, "f": "g"}},
+			map[string]interface{}{"a": map[string]interface{}{"b": "c", "f": "g"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergePatch(tt.target, tt.patch); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergePatch = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type patchAudit struct {
+	Note string `json:"note"`
+}
+
+type patchRecord struct {
+	patchAudit
+	Name   string                 `json:"name"`
+	Count  int                    `json:"count,omitempty"`
+	Meta   map[string]interface{} `json:"meta"`
+	Owner  string
+	Secret string `json:"-"`
+}
+
+func TestMergePatchBody(t *testing.T) {
+	stored := func() *patchRecord {
+		return &patchRecord{
+			patchAudit: patchAudit{Note: "late"},
+			Name:       "Yard A",
+			Count:      3,
+			Meta:       map[string]interface{}{"shift": "day", "crew": "4"},
+			Owner:      "ops",
+			Secret:     "s",
+		}
+	}
+	tests := []struct {
+		name   string
+		body   string
+		want   func(*patchRecord)
+		status int
+	}{
+		{"replace", `{"name":"Yard B"}`, func(r *patchRecord) { r.Name = "Yard B" }, 0},
+		{"null zeroes", `{"count":null,"name":null}`, func(r *patchRecord) { r.Count, r.Name = 0, "" }, 0},
+		{"embedded field", `{"note":null}`, func(r *patchRecord) { r.Note = "" }, 0},
+		{"untagged field", `{"owner":"stores"}`, func(r *patchRecord) { r.Owner = "stores" }, 0},
+		{"object merges", `{"meta":{"crew":null,"lead":"Ravi"}}`, func(r *patchRecord) {
+			r.Meta = map[string]interface{}{"shift": "day", "lead": "Ravi"}
+		}, 0},
+		{"keys match case-insensitively", `{"META":{"lead":"Ravi"},"Count":null}`, func(r *patchRecord) {
+			r.Meta = map[string]interface{}{"shift": "day", "crew": "4", "lead": "Ravi"}
+			r.Count = 0
+		}, 0},
+		{"unknown and hidden keys ignored", `{"colour":"red","Secret":null}`, func(*patchRecord) {}, 0},
+		{"not an object", `["name"]`, nil, http.StatusBadRequest},
+		{"wrong type", `{"count":"many"}`, nil, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := stored()
+			w := httptest.NewRecorder()
+			ok := mergePatchBody(w, httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(tt.body)), record)
+			if tt.status != 0 {
+				if ok || w.Code != tt.status {
+					t.Errorf("mergePatchBody = %v, %d; want %d", ok, w.Code, tt.status)
+				}
+				return
+			}
+			if !ok {
+				t.Fatalf("mergePatchBody = %d %s", w.Code, w.Body)
+			}
+			want := stored()
+			tt.want(want)
+			if !reflect.DeepEqual(record, want) {
+				t.Errorf("record = %+v, want %+v", record, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"strings"
+	"time"
+)
+
+// EXIF tags read by readEXIF
+const (
+	tagOrientation        = 0x0112
+	tagExifIFD            = 0x8769
+	tagGPSIFD             = 0x8825
+	tagDateTimeOriginal   = 0x9003
+	tagOffsetTimeOriginal = 0x9011
+	tagGPSLatitudeRef     = 0x0001
+	tagGPSLatitude        = 0x0002
+	tagGPSLongitudeRef    = 0x0003
+	tagGPSLongitude       = 0x0004
+	tagGPSTimeStamp       = 0x0007
+	tagGPSDateStamp       = 0x001d
+)
+
+// maxIFDEntries bounds the directories of a damaged or hostile file
+const maxIFDEntries = 512
+
+// exifData is what readEXIF found; zero fields were absent or unreadable
+type exifData struct {
+	orientation int
+	latitude    *float64
+	longitude   *float64
+	capturedAt  *time.Time
+}
+
+// tiff reads the TIFF structure EXIF data is stored in
+type tiff struct {
+	data  []byte
+	order binary.ByteOrder
+}
+
+type ifdEntry struct {
+	typ   uint16
+	count uint32
+	value []byte
+}
+
+// typeSizes are the byte sizes of the TIFF field types, by type number
+var typeSizes = map[uint16]uint32{1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 7: 1, 9: 4, 10: 8}
+
+// readEXIF returns the EXIF data of a JPEG file, or nothing when it has
+// none. Damaged data is skipped rather than reported.
+func readEXIF(jpeg []byte) exifData {
+	var out exifData
+	t, ok := jpegEXIF(jpeg)
+	if !ok {
+		return out
+	}
+	ifd0, ok := t.ifd(t.order.Uint32(t.data[4:8]))
+	if !ok {
+		return out
+	}
+	if e, ok := ifd0[tagOrientation]; ok {
+		if v, ok := t.uint(e); ok && v >= 1 && v <= 8 {
+			out.orientation = int(v)
+		}
+	}
+
+	var taken, offset string
+	if e, ok := ifd0[tagExifIFD]; ok {
+		if off, ok := t.uint(e); ok {
+			if exif, ok := t.ifd(off); ok {
+				taken = t.ascii(exif[tagDateTimeOriginal])
+				offset = t.ascii(exif[tagOffsetTimeOriginal])
+			}
+		}
+	}
+
+	var gpsTime *time.Time
+	if e, ok := ifd0[tagGPSIFD]; ok {
+		if off, ok := t.uint(e); ok {
+			if gps, ok := t.ifd(off); ok {
+				lat, latOK := t.degrees(gps[tagGPSLatitude], t.ascii(gps[tagGPSLatitudeRef]), "S")
+				lng, lngOK := t.degrees(gps[tagGPSLongitude], t.ascii(gps[tagGPSLongitudeRef]), "W")
+				if latOK && lngOK && math.Abs(lat) <= 90 && math.Abs(lng) <= 180 && (lat != 0 || lng != 0) {
+					out.latitude, out.longitude = &lat, &lng
+				}
+				gpsTime = t.gpsTime(gps[tagGPSDateStamp], gps[tagGPSTimeStamp])
+			}
+		}
+	}
+
+	// The camera's clock is only meaningful with its zone. Without a
+	// recorded offset the GPS fix, which is in UTC, is the better guess,
+	// and failing that the server's zone.
+	const layout = "2006:01:02 15:04:05"
+	if t, err := time.Parse(layout+"-07:00", taken+offset); err == nil && offset != "" {
+		out.capturedAt = &t
+	} else if gpsTime != nil {
+		out.capturedAt = gpsTime
+	} else if t, err := time.ParseInLocation(layout, taken, time.Local); err == nil {
+		out.capturedAt = &t
+	}
+	return out
+}
+
+// jpegEXIF finds the EXIF segment among the markers ahead of the image data
+func jpegEXIF(data []byte) (tiff, bool) {
+	if len(data) < 4 || data[0] != 0xff || data[1] != 0xd8 {
+		return tiff{}, false
+	}
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xff {
+			return tiff{}, false
+		}
+		marker := data[i+1]
+		if marker == 0xff {
+			i++ // fill byte
+			continue
+		}
+		if marker == 0xda || marker == 0xd9 {
+			return tiff{}, false // start of scan or end of image
+		}
+		size := int(binary.BigEndian.Uint16(data[i+2 : i+4]))
+		if size < 2 || i+2+size > len(data) {
+			return tiff{}, false
+		}
+		segment := data[i+4 : i+2+size]
+		if marker == 0xe1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return newTIFF(segment[6:])
+		}
+		i += 2 + size
+	}
+	return tiff{}, false
+}
+
+func newTIFF(data []byte) (tiff, bool) {
+	if len(data) < 8 {
+		return tiff{}, false
+	}
+	t := tiff{data: data}
+	switch string(data[:2]) {
+	case "II":
+		t.order = binary.LittleEndian
+	case "MM":
+		t.order = binary.BigEndian
+	default:
+		return tiff{}, false
+	}
+	return t, t.order.Uint16(data[2:4]) == 42
+}
+
+// ifd reads the directory at offset into its entries by tag
+func (t tiff) ifd(offset uint32) (map[uint16]ifdEntry, bool) {
+	if uint64(offset)+2 > uint64(len(t.data)) {
+		return nil, false
+	}
+	n := int(t.order.Uint16(t.data[offset:]))
+	if n > maxIFDEntries || int(offset)+2+12*n > len(t.data) {
+		return nil, false
+	}
+	entries := make(map[uint16]ifdEntry, n)
+	for i := 0; i < n; i++ {
+		raw := t.data[int(offset)+2+12*i:]
+		e := ifdEntry{typ: t.order.Uint16(raw[2:4]), count: t.order.Uint32(raw[4:8])}
+		size, ok := typeSizes[e.typ]
+		if !ok || e.count > uint32(len(t.data)) {
+			continue
+		}
+		size *= e.count
+		if size <= 4 {
+			e.value = raw[8 : 8+size]
+		} else {
+			at := t.order.Uint32(raw[8:12])
+			if uint64(at)+uint64(size) > uint64(len(t.data)) {
+				continue
+			}
+			e.value = t.data[at : at+size]
+		}
+		entries[t.order.Uint16(raw[0:2])] = e
+	}
+	return entries, true
+}
+
+// uint reads a SHORT or LONG entry
+func (t tiff) uint(e ifdEntry) (uint32, bool) {
+	switch {
+	case e.typ == 3 && len(e.value) >= 2:
+		return uint32(t.order.Uint16(e.value)), true
+	case e.typ == 4 && len(e.value) >= 4:
+		return t.order.Uint32(e.value), true
+	}
+	return 0, false
+}
+
+// ascii reads an ASCII entry without its terminating NUL
+func (t tiff) ascii(e ifdEntry) string {
+	if e.typ != 2 {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimRight(string(e.value), "\x00"))
+}
+
+// rationals reads the unsigned fractions of a RATIONAL entry
+func (t tiff) rationals(e ifdEntry) ([]float64, bool) {
+	if e.typ != 5 {
+		return nil, false
+	}
+	out := make([]float64, len(e.value)/8)
+	for i := range out {
+		num, den := t.order.Uint32(e.value[8*i:]), t.order.Uint32(e.value[8*i+4:])
+		if den == 0 {
+			return nil, false
+		}
+		out[i] = float64(num) / float64(den)
+	}
+	return out, true
+}
+
+// degrees reads a GPS coordinate stored as degrees, minutes and seconds,
+// negative when ref is negativeRef
+func (t tiff) degrees(e ifdEntry, ref, negativeRef string) (float64, bool) {
+	dms, ok := t.rationals(e)
+	if !ok || len(dms) != 3 || ref == "" {
+		return 0, false
+	}
+	v := dms[0] + dms[1]/60 + dms[2]/3600
+	if strings.EqualFold(ref, negativeRef) {
+		v = -v
+	}
+	return v, true
+}
+
+// gpsTime reads the UTC time of the GPS fix
+func (t tiff) gpsTime(date, clock ifdEntry) *time.Time {
+	day, err := time.Parse("2006:01:02", t.ascii(date))
+	if err != nil {
+		return nil
+	}
+	hms, ok := t.rationals(clock)
+	if !ok || len(hms) != 3 {
+		return nil
+	}
+	at := day.Add(time.Duration((hms[0]*3600 + hms[1]*60 + hms[2]) * float64(time.Second)))
+	return &at
+}
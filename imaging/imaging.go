@@ -0,0 +1,222 @@
+// Package imaging prepares uploaded photos for the admin views. It makes
+// downsized copies that carry no metadata, so they can be handed out
+// freely, and reads the EXIF position and capture time of the original.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"math/bits"
+	"time"
+
+	_ "image/gif" // decoders for image.Decode
+	_ "image/png"
+)
+
+// Longest sides and JPEG qualities of the copies Process makes
+const (
+	ThumbnailSize    = 320
+	WebSize          = 1600
+	thumbnailQuality = 75
+	webQuality       = 82
+)
+
+// maxPixels keeps a small file that claims huge dimensions from
+// exhausting memory when decoded
+const maxPixels = 50_000_000
+
+// ErrUnsupported is returned by Process for data it cannot decode
+var ErrUnsupported = errors.New("imaging: unsupported image")
+
+// ErrTooLarge is returned by Process for images over maxPixels
+var ErrTooLarge = errors.New("imaging: image too large")
+
+// Metadata describes an image. Width and Height are as displayed, after
+// the EXIF orientation; Latitude, Longitude and CapturedAt come from EXIF
+// and are nil when absent.
+type Metadata struct {
+	Width      int
+	Height     int
+	Latitude   *float64
+	Longitude  *float64
+	CapturedAt *time.Time
+	// PerceptualHash is the difference hash of the upright image. Resaving,
+	// resizing or recompressing a photo changes few if any of its bits; see
+	// Distance.
+	PerceptualHash uint64
+}
+
+// Result is what Process makes of an image. Thumbnail and Web are JPEGs.
+type Result struct {
+	Metadata
+	Thumbnail []byte
+	Web       []byte
+}
+
+// Process decodes a JPEG, PNG or GIF image, reads its metadata and makes a
+// thumbnail and a compressed web copy of it. The copies are re-encoded
+// from the pixels alone, turned upright and flattened onto white, so no
+// EXIF data or transparency survives. Images smaller than a copy's size
+// are re-encoded at their own size.
+func Process(data []byte) (*Result, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupported
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return nil, ErrUnsupported
+	}
+	if cfg.Width*cfg.Height > maxPixels {
+		return nil, ErrTooLarge
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupported
+	}
+
+	exif := readEXIF(data)
+	img := orient(flatten(src), exif.orientation)
+	res := &Result{Metadata: Metadata{
+		Width:      img.Rect.Dx(),
+		Height:     img.Rect.Dy(),
+		Latitude:   exif.latitude,
+		Longitude:  exif.longitude,
+		CapturedAt: exif.capturedAt,
+	}}
+
+	web := fit(img, WebSize)
+	res.PerceptualHash = dHash(web)
+	if res.Web, err = encode(web, webQuality); err != nil {
+		return nil, err
+	}
+	if res.Thumbnail, err = encode(fit(web, ThumbnailSize), thumbnailQuality); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func encode(img image.Image, quality int) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// flatten draws src onto a white canvas anchored at the origin
+func flatten(src image.Image) *image.RGBA {
+	b := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Rect, image.White, image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Rect, src, b.Min, draw.Over)
+	return dst
+}
+
+// orient turns img upright according to its EXIF orientation, 1 to 8
+func orient(img *image.RGBA, orientation int) *image.RGBA {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+	sw, sh := img.Rect.Dx(), img.Rect.Dy()
+	w, h := sw, sh
+	if orientation >= 5 {
+		w, h = sh, sw
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2: // mirrored
+				sx, sy = w-1-x, y
+			case 3: // upside down
+				sx, sy = w-1-x, h-1-y
+			case 4: // mirrored upside down
+				sx, sy = x, h-1-y
+			case 5: // transposed
+				sx, sy = y, x
+			case 6: // needs turning clockwise
+				sx, sy = y, w-1-x
+			case 7: // transversed
+				sx, sy = h-1-y, w-1-x
+			case 8: // needs turning anticlockwise
+				sx, sy = h-1-y, x
+			}
+			copy(dst.Pix[y*dst.Stride+4*x:][:4], img.Pix[sy*img.Stride+4*sx:][:4])
+		}
+	}
+	return dst
+}
+
+// Distance is the number of bits two perceptual hashes differ in. Copies
+// of one photo are usually within 5 of each other, different photos rarely
+// within 10.
+func Distance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// dHash computes the difference hash of img: a bit per horizontally
+// adjacent pair of pixels of a 9×8 grey miniature, set where the left one
+// is brighter
+func dHash(img *image.RGBA) uint64 {
+	small := shrink(img, 9, 8)
+	luma := func(x, y int) uint32 {
+		p := small.Pix[y*small.Stride+4*x:]
+		return 299*uint32(p[0]) + 587*uint32(p[1]) + 114*uint32(p[2])
+	}
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			if luma(x, y) > luma(x+1, y) {
+				hash |= 1 << (8*y + x)
+			}
+		}
+	}
+	return hash
+}
+
+// fit scales img down so its longest side is at most size
+func fit(img *image.RGBA, size int) *image.RGBA {
+	sw, sh := img.Rect.Dx(), img.Rect.Dy()
+	if sw <= size && sh <= size {
+		return img
+	}
+	w, h := size, sh*size/sw
+	if sh > sw {
+		w, h = sw*size/sh, size
+	}
+	return shrink(img, max(w, 1), max(h, 1))
+}
+
+// shrink scales img to w×h, averaging the source pixels each target pixel
+// covers
+func shrink(img *image.RGBA, w, h int) *image.RGBA {
+	sw, sh := img.Rect.Dx(), img.Rect.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		y0, y1 := y*sh/h, max((y+1)*sh/h, y*sh/h+1)
+		for x := 0; x < w; x++ {
+			x0, x1 := x*sw/w, max((x+1)*sw/w, x*sw/w+1)
+			var sum [4]uint64
+			for sy := y0; sy < y1; sy++ {
+				row := img.Pix[sy*img.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					p := row[4*sx : 4*sx+4]
+					sum[0] += uint64(p[0])
+					sum[1] += uint64(p[1])
+					sum[2] += uint64(p[2])
+					sum[3] += uint64(p[3])
+				}
+			}
+			n := uint64((y1 - y0) * (x1 - x0))
+			d := dst.Pix[y*dst.Stride+4*x:]
+			for c := 0; c < 4; c++ {
+				d[c] = uint8(sum[c] / n)
+			}
+		}
+	}
+	return dst
+}
@@ -6,11 +6,19 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"time"
 
 	"p9e.in/ugcl/config"
+	"p9e.in/ugcl/models"
 	"p9e.in/ugcl/routes"
 )
 
+// trashPurgeInterval is how often deleted records past retention are purged
+const trashPurgeInterval = time.Hour
+
+// attachmentReapInterval is how often uploads left unlinked are deleted
+const attachmentReapInterval = time.Hour
+
 var (
 	Version   = "dev"
 	BuildTime = ""
@@ -27,6 +35,7 @@ func main() {
 		os.Exit(0)
 	}
 	config.Connect()
+	config.ConnectStorage()
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080"
@@ -35,18 +44,53 @@ func main() {
 	if err := config.Migrations(config.DB); err != nil {
 		log.Fatalf("could not run migrations: %v", err)
 	}
+	if retention := config.TrashRetention(); retention > 0 {
+		go purgeTrash(retention)
+	}
+	if retention := config.AttachmentRetention(); retention > 0 {
+		go reapAttachments(retention)
+	}
 	handler := routes.RegisterRoutes()
 	handlerWithCORS := enableCORS(handler)
 	log.Println("Server starting at port", port)
 	log.Fatal(http.ListenAndServe(":"+port, handlerWithCORS))
 }
 
+// purgeTrash permanently removes records deleted more than retention ago,
+// at start and then every trashPurgeInterval
+func purgeTrash(retention time.Duration) {
+	for {
+		n, err := models.PurgeExpiredTrash(config.DB, retention)
+		if err != nil {
+			log.Printf("trash retention: %v", err)
+		} else if n > 0 {
+			log.Printf("trash retention: purged %d records deleted before %s", n, time.Now().Add(-retention).Format(time.RFC3339))
+		}
+		time.Sleep(trashPurgeInterval)
+	}
+}
+
+// reapAttachments deletes uploads that were not linked to any record
+// within retention, at start and then every attachmentReapInterval
+func reapAttachments(retention time.Duration) {
+	for {
+		n, err := models.DeleteUnlinkedAttachments(config.DB, config.Storage, retention)
+		if err != nil {
+			log.Printf("attachment retention: %v", err)
+		} else if n > 0 {
+			log.Printf("attachment retention: deleted %d uploads left unlinked since before %s", n, time.Now().Add(-retention).Format(time.RFC3339))
+		}
+		time.Sleep(attachmentReapInterval)
+	}
+}
+
 func enableCORS(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Required CORS headers
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, x-api-key, X-Requested-With")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, x-api-key, X-Requested-With, If-Match")
+		w.Header().Set("Access-Control-Expose-Headers", "ETag")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
 
 		// Handle preflight (OPTIONS)
 		if r.Method == http.MethodOptions {
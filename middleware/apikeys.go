@@ -0,0 +1,184 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"p9e.in/ugcl/config"
+	"p9e.in/ugcl/models"
+)
+
+const (
+	// apiKeyReloadInterval bounds how long a change made through another
+	// instance takes to apply here. Changes made through this instance
+	// apply at once via InvalidateAPIKeys.
+	apiKeyReloadInterval = 30 * time.Second
+	// apiKeyTouchInterval throttles last_used_at writes to one per client
+	apiKeyTouchInterval = time.Minute
+	apiKeyPrefix        = "ugk_"
+)
+
+// apiKeyEntry is an APIClient compiled for request checks
+type apiKeyEntry struct {
+	id          uuid.UUID
+	config      APIClientConfig
+	networks    []*net.IPNet
+	expiresAt   *time.Time
+	graceEndsAt *time.Time // set for the entry of a rotated out key
+}
+
+// apiKeyStore caches the api_clients table by key hash
+type apiKeyStore struct {
+	mu       sync.RWMutex
+	byHash   map[string]*apiKeyEntry
+	loadedAt time.Time
+	touched  map[uuid.UUID]time.Time
+}
+
+var apiKeys = &apiKeyStore{touched: map[uuid.UUID]time.Time{}}
+
+// InvalidateAPIKeys makes the next request reload the api_clients table
+func InvalidateAPIKeys() {
+	apiKeys.mu.Lock()
+	apiKeys.loadedAt = time.Time{}
+	apiKeys.mu.Unlock()
+}
+
+// NewAPIKey generates a key and the hash to store for it
+func NewAPIKey() (key, hash string, err error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	key = apiKeyPrefix + base64.RawURLEncoding.EncodeToString(b)
+	return key, models.HashAPIKey(key), nil
+}
+
+// lookup finds the client for a key, reloading the cache when it is stale
+func (s *apiKeyStore) lookup(key string) (*apiKeyEntry, bool) {
+	if key == "" {
+		return nil, false
+	}
+	s.mu.RLock()
+	stale := time.Since(s.loadedAt) > apiKeyReloadInterval
+	s.mu.RUnlock()
+	if stale {
+		s.reload()
+	}
+
+	s.mu.RLock()
+	entry, ok := s.byHash[models.HashAPIKey(key)]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	now := time.Now()
+	if entry.expiresAt != nil && now.After(*entry.expiresAt) {
+		return nil, false
+	}
+	if entry.graceEndsAt != nil && now.After(*entry.graceEndsAt) {
+		return nil, false
+	}
+	s.touch(entry.id, now)
+	return entry, true
+}
+
+// reload replaces the cache with the open clients. On a database error the
+// previous cache is kept so an outage does not lock every client out.
+func (s *apiKeyStore) reload() {
+	var clients []models.APIClient
+	if err := config.DB.Where("revoked_at IS NULL").Find(&clients).Error; err != nil {
+		log.Printf("[SECURITY] could not load api clients: %v", err)
+		s.mu.Lock()
+		s.loadedAt = time.Now()
+		s.mu.Unlock()
+		return
+	}
+
+	byHash := make(map[string]*apiKeyEntry, len(clients))
+	for _, c := range clients {
+		entry := compileAPIClient(c)
+		byHash[c.KeyHash] = entry
+		if c.PreviousKeyHash != "" && c.PreviousKeyExpiresAt != nil {
+			previous := *entry
+			previous.graceEndsAt = c.PreviousKeyExpiresAt
+			byHash[c.PreviousKeyHash] = &previous
+		}
+	}
+
+	s.mu.Lock()
+	s.byHash = byHash
+	s.loadedAt = time.Now()
+	s.mu.Unlock()
+}
+
+func compileAPIClient(c models.APIClient) *apiKeyEntry {
+	entry := &apiKeyEntry{
+		id: c.ID,
+		config: APIClientConfig{
+			AppName:        c.AppName,
+			AllowedPaths:   c.AllowedPaths,
+			AllowedMethods: map[string]bool{},
+			SkipIPCheck:    len(c.AllowedCIDRs) == 0,
+			ReadPaths:      c.ReadPaths,
+			Sites:          c.Sites,
+		},
+		expiresAt: c.ExpiresAt,
+	}
+	for _, m := range c.AllowedMethods {
+		entry.config.AllowedMethods[strings.ToUpper(m)] = true
+	}
+	for _, cidr := range c.AllowedCIDRs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			// Validated on save, so this only happens after manual edits
+			log.Printf("[SECURITY] ignoring bad CIDR %q for app %s", cidr, c.AppName)
+			continue
+		}
+		entry.networks = append(entry.networks, network)
+	}
+	return entry
+}
+
+// allowsIP reports whether the client may call from ip
+func (e *apiKeyEntry) allowsIP(ip string) bool {
+	if e.config.SkipIPCheck {
+		return true
+	}
+	parsed := net.ParseIP(strings.TrimSpace(ip))
+	if parsed == nil {
+		return false
+	}
+	for _, n := range e.networks {
+		if n.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// touch records last_used_at at most once per apiKeyTouchInterval
+func (s *apiKeyStore) touch(id uuid.UUID, now time.Time) {
+	s.mu.Lock()
+	last := s.touched[id]
+	due := now.Sub(last) >= apiKeyTouchInterval
+	if due {
+		s.touched[id] = now
+	}
+	s.mu.Unlock()
+	if !due {
+		return
+	}
+	go func() {
+		if err := config.DB.Model(&models.APIClient{}).Where("id = ?", id).
+			UpdateColumn("last_used_at", now).Error; err != nil {
+			log.Printf("[SECURITY] could not record api client use: %v", err)
+		}
+	}()
+}
@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"p9e.in/ugcl/config"
 	"p9e.in/ugcl/models"
 )
 
@@ -24,6 +25,8 @@ type Claims struct {
 	Name   string `json:"name"`
 	Phone  string `json:"phone"`
 	Role   string `json:"role"`
+	// SessionID ties the token to a models.Session so it can be revoked
+	SessionID string `json:"sid"`
 	jwt.RegisteredClaims
 }
 
@@ -32,17 +35,19 @@ type ctxKey int
 
 const (
 	userClaimsKey ctxKey = iota
+	apiClientKey
 )
 
-// GenerateToken creates a signed JWT valid for 24 h
-func GenerateToken(userID, role, name, phone string) (string, error) {
+// GenerateToken creates a signed JWT for a session, valid for AccessTokenTTL
+func GenerateToken(userID, role, name, phone, sessionID string) (string, error) {
 	claims := Claims{
-		UserID: userID,
-		Name:   name,
-		Phone:  phone,
-		Role:   role,
+		UserID:    userID,
+		Name:      name,
+		Phone:     phone,
+		Role:      role,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(AccessTokenTTL)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
@@ -50,6 +55,25 @@ func GenerateToken(userID, role, name, phone string) (string, error) {
 	return token.SignedString(jwtKey)
 }
 
+// ParseToken validates a bearer token, including that its session is still
+// open and its user still active
+func ParseToken(tokenStr string) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenStr, &Claims{}, func(t *jwt.Token) (interface{}, error) {
+		return jwtKey, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil || !token.Valid {
+		return nil, ErrInvalidToken
+	}
+	claims, ok := token.Claims.(*Claims)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	if err := checkSession(config.DB, claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
 // JWTMiddleware validates the token and stashes the Claims in ctx
 func JWTMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -64,18 +88,13 @@ func JWTMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		tokenStr := parts[1]
-		token, err := jwt.ParseWithClaims(tokenStr, &Claims{}, func(t *jwt.Token) (interface{}, error) {
-			return jwtKey, nil
-		})
-		if err != nil || !token.Valid {
-			http.Error(w, "invalid or expired token", http.StatusUnauthorized)
-			return
-		}
-
-		claims, ok := token.Claims.(*Claims)
-		if !ok {
-			http.Error(w, "invalid token claims", http.StatusUnauthorized)
+		claims, err := ParseToken(parts[1])
+		if err != nil {
+			status := http.StatusUnauthorized
+			if !IsAuthError(err) {
+				status = http.StatusInternalServerError
+			}
+			http.Error(w, err.Error(), status)
 			return
 		}
 
@@ -155,90 +174,57 @@ func GetRole(r *http.Request) string {
 	return ""
 }
 
+// GetAuditActor describes who is making the request for the audit trail
+func GetAuditActor(r *http.Request) models.AuditActor {
+	actor := models.AuditActor{IP: ClientIP(r)}
+	if c := GetClaims(r); c != nil {
+		actor.UserID, actor.Name, actor.Role = c.UserID, c.Name, c.Role
+	}
+	if client := GetAPIClient(r); client != nil {
+		actor.APIClient = client.AppName
+	}
+	return actor
+}
+
+// APIClientConfig is what SecurityMiddleware checks for an API client,
+// compiled from its models.APIClient row
 type APIClientConfig struct {
 	AppName        string
 	AllowedPaths   []string        // Exact or prefix match (supports "*")
 	AllowedMethods map[string]bool // e.g., "GET": true, "POST": true
 	SkipIPCheck    bool
-}
-
-var apiKeyConfigs = map[string]APIClientConfig{
-	os.Getenv("MOBILE_APP_KEY"): {
-		AppName:      "MobileApp",
-		AllowedPaths: []string{"/api/v1"},
-		AllowedMethods: map[string]bool{
-			http.MethodPost: true,
-		},
-		SkipIPCheck: true,
-	},
-	os.Getenv("PARTNER_PORTAL_KEY"): {
-		AppName:      "PartnerPortal",
-		AllowedPaths: []string{"/api/v1"},
-		AllowedMethods: map[string]bool{
-			http.MethodGet: true,
-		},
-		SkipIPCheck: false,
-	},
-	os.Getenv("INTERNAL_OPS_KEY"): {
-		AppName:      "InternalOps",
-		AllowedPaths: []string{"/api/v1/*"},
-		AllowedMethods: map[string]bool{
-			http.MethodGet:    true,
-			http.MethodPost:   true,
-			http.MethodPut:    true,
-			http.MethodDelete: true,
-		},
-		SkipIPCheck: true,
-	},
-}
-
-// Define fixed IP whitelist for server-to-server apps (skip for mobile)
-var whitelistedIPs = map[string]bool{
-	"20.204.19.129": true,
-	"127.0.0.1":     true,
-	"::1":           true,
+	ReadPaths      []string // Open to GET even when AllowedMethods does not include it
+	Sites          []string // Sites the client may see; empty allows every site
 }
 
 // SecurityMiddleware enforces API key, IP filtering, and logging
 func SecurityMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		apiKey := r.Header.Get("x-api-key")
-		clientConfig, ok := apiKeyConfigs[apiKey]
+		client, ok := apiKeys.lookup(r.Header.Get("x-api-key"))
 		if !ok {
 			http.Error(w, "Invalid or missing API key", http.StatusUnauthorized)
-			log.Printf("[SECURITY] 🔒 Blocked - Invalid API key. IP=%s Path=%s", getClientIP(r), r.URL.Path)
+			log.Printf("[SECURITY] 🔒 Blocked - Invalid API key. IP=%s Path=%s", ClientIP(r), r.URL.Path)
 			return
 		}
 
-		clientIP := getClientIP(r)
-		if !clientConfig.SkipIPCheck && !whitelistedIPs[clientIP] {
+		clientConfig := client.config
+		clientIP := ClientIP(r)
+		if !client.allowsIP(clientIP) {
 			http.Error(w, "Access from this IP is not allowed", http.StatusForbidden)
 			log.Printf("[SECURITY] 🚫 Blocked - IP not whitelisted. App=%s IP=%s Path=%s", clientConfig.AppName, clientIP, r.URL.Path)
 			return
 		}
 
 		// ✅ Path-based access check
-		pathAllowed := false
-		for _, path := range clientConfig.AllowedPaths {
-			if strings.HasSuffix(path, "*") {
-				prefix := strings.TrimSuffix(path, "*")
-				if strings.HasPrefix(r.URL.Path, prefix) {
-					pathAllowed = true
-					break
-				}
-			} else if r.URL.Path == path || strings.HasPrefix(r.URL.Path, path+"/") {
-				pathAllowed = true
-				break
-			}
-		}
-		if !pathAllowed {
+		if !matchesPath(clientConfig.AllowedPaths, r.URL.Path) {
 			http.Error(w, "Access to this endpoint is not allowed for this app", http.StatusForbidden)
 			log.Printf("[SECURITY] ⛔️ Denied - Path not allowed. App=%s IP=%s Path=%s", clientConfig.AppName, clientIP, r.URL.Path)
 			return
 		}
 
 		// ✅ Method-based access check
-		if !clientConfig.AllowedMethods[r.Method] {
+		readAllowed := r.Method == http.MethodGet && matchesPath(clientConfig.ReadPaths, r.URL.Path)
+		if !clientConfig.AllowedMethods[r.Method] && !readAllowed {
 			http.Error(w, "This HTTP method is not allowed for this app", http.StatusMethodNotAllowed)
 			log.Printf("[SECURITY] ⛔️ Denied - Method not allowed. App=%s Method=%s Path=%s", clientConfig.AppName, r.Method, r.URL.Path)
 			return
@@ -257,12 +243,36 @@ func SecurityMiddleware(next http.Handler) http.Handler {
 			clientConfig.AppName, userID, userName, userRole,
 			clientIP, r.URL.Path, r.Method, time.Now().Format(time.RFC3339))
 
-		next.ServeHTTP(w, r)
+		ctx := context.WithValue(r.Context(), apiClientKey, &clientConfig)
+		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
-// Extracts client IP from headers or remote addr
-func getClientIP(r *http.Request) string {
+// GetAPIClient returns the API client SecurityMiddleware admitted (or nil)
+func GetAPIClient(r *http.Request) *APIClientConfig {
+	if c, ok := r.Context().Value(apiClientKey).(*APIClientConfig); ok {
+		return c
+	}
+	return nil
+}
+
+// matchesPath reports whether path is one of paths or below it. An entry
+// ending in "*" matches any path with that prefix.
+func matchesPath(paths []string, path string) bool {
+	for _, p := range paths {
+		if strings.HasSuffix(p, "*") {
+			if strings.HasPrefix(path, strings.TrimSuffix(p, "*")) {
+				return true
+			}
+		} else if path == p || strings.HasPrefix(path, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP extracts the client IP from headers or the remote address
+func ClientIP(r *http.Request) string {
 	// Priority: X-Forwarded-For → X-Real-IP → RemoteAddr
 	if ip := r.Header.Get("X-Forwarded-For"); ip != "" {
 		return strings.Split(ip, ",")[0]
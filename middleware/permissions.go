@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"p9e.in/ugcl/config"
+	"p9e.in/ugcl/models"
+)
+
+// permissionReloadInterval bounds how long a change made through another
+// instance takes to apply here
+const permissionReloadInterval = 30 * time.Second
+
+// permissionStore caches role_permissions by role
+type permissionStore struct {
+	mu       sync.RWMutex
+	byRole   map[string][]string
+	loadedAt time.Time
+}
+
+var permissions = &permissionStore{}
+
+// InvalidatePermissions makes the next check reload role_permissions
+func InvalidatePermissions() {
+	permissions.mu.Lock()
+	permissions.loadedAt = time.Time{}
+	permissions.mu.Unlock()
+}
+
+// HasPermission reports whether role has been granted permission
+func HasPermission(role, permission string) bool {
+	if role == "" {
+		return false
+	}
+	permissions.mu.RLock()
+	stale := time.Since(permissions.loadedAt) > permissionReloadInterval
+	permissions.mu.RUnlock()
+	if stale {
+		permissions.reload()
+	}
+
+	permissions.mu.RLock()
+	defer permissions.mu.RUnlock()
+	for _, grant := range permissions.byRole[role] {
+		if models.GrantCovers(grant, permission) {
+			return true
+		}
+	}
+	return false
+}
+
+// reload replaces the cache. On a database error the previous grants are
+// kept.
+func (s *permissionStore) reload() {
+	var grants []models.RolePermission
+	if err := config.DB.Find(&grants).Error; err != nil {
+		log.Printf("[SECURITY] could not load role permissions: %v", err)
+		s.mu.Lock()
+		s.loadedAt = time.Now()
+		s.mu.Unlock()
+		return
+	}
+	byRole := map[string][]string{}
+	for _, g := range grants {
+		byRole[g.Role] = append(byRole[g.Role], g.Permission)
+	}
+	s.mu.Lock()
+	s.byRole = byRole
+	s.loadedAt = time.Now()
+	s.mu.Unlock()
+}
+
+// RequirePermission wraps a handler and ensures the JWT's role has been
+// granted permission, e.g. "stock:read"
+func RequirePermission(permission string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if HasPermission(GetRole(r), permission) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		http.Error(w, "forbidden: requires "+permission, http.StatusForbidden)
+	})
+}
@@ -0,0 +1,232 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"p9e.in/ugcl/models"
+)
+
+const (
+	// AccessTokenTTL is how long a JWT stays valid. Revocation and
+	// deactivation are also checked on every request, so this mainly bounds
+	// how stale the role and name in the token can get.
+	AccessTokenTTL = 15 * time.Minute
+	// RefreshTokenTTL is how long a session survives without being used.
+	// Each refresh pushes it out again, so an app that syncs at least once
+	// a month stays signed in.
+	RefreshTokenTTL = 30 * 24 * time.Hour
+	// refreshReuseGrace lets the previous refresh token be replayed for a
+	// short while, for apps that lost the response to their last refresh
+	// on a bad connection.
+	refreshReuseGrace = 2 * time.Minute
+)
+
+var (
+	ErrInvalidToken        = errors.New("invalid or expired token")
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrSessionRevoked      = errors.New("session has been revoked")
+	ErrUserInactive        = errors.New("user is inactive")
+)
+
+// IsAuthError reports whether err means the caller must sign in again, as
+// opposed to a server side failure
+func IsAuthError(err error) bool {
+	return errors.Is(err, ErrInvalidToken) || errors.Is(err, ErrInvalidRefreshToken) ||
+		errors.Is(err, ErrSessionRevoked) || errors.Is(err, ErrUserInactive)
+}
+
+// TokenPair is returned by login and refresh
+type TokenPair struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refreshToken"`
+	ExpiresIn    int    `json:"expiresIn"` // seconds until Token expires
+}
+
+// StartSession records a new session for u and issues its first tokens
+func StartSession(db *gorm.DB, u models.User, r *http.Request) (TokenPair, error) {
+	refresh, hash, err := newRefreshToken()
+	if err != nil {
+		return TokenPair{}, err
+	}
+	now := time.Now()
+	session := models.Session{
+		ID:          uuid.New(),
+		UserID:      u.ID,
+		RefreshHash: hash,
+		RotatedAt:   now,
+		ExpiresAt:   now.Add(RefreshTokenTTL),
+		LastUsedAt:  now,
+		UserAgent:   truncateString(r.UserAgent(), 255),
+		IP:          ClientIP(r),
+	}
+	if err := db.Create(&session).Error; err != nil {
+		return TokenPair{}, err
+	}
+	return issueTokens(u, session.ID, refresh)
+}
+
+// RefreshSession swaps a refresh token for a new access and refresh token.
+// Replaying an already rotated token outside the grace period is treated
+// as theft and revokes the session, as does refreshing for a user who has
+// since been deactivated.
+func RefreshSession(db *gorm.DB, refreshToken string) (TokenPair, error) {
+	hash := hashToken(refreshToken)
+	var pair TokenPair
+	var reused, inactive bool
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var session models.Session
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("refresh_hash = ? OR previous_hash = ?", hash, hash).
+			First(&session).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidRefreshToken
+		}
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if session.RevokedAt != nil {
+			return ErrSessionRevoked
+		}
+		if now.After(session.ExpiresAt) {
+			return ErrInvalidRefreshToken
+		}
+		current := session.RefreshHash == hash
+		if !current && now.Sub(session.RotatedAt) > refreshReuseGrace {
+			reused = true
+			return revokeSession(tx, session.ID, models.RevokedReuse)
+		}
+
+		var u models.User
+		if err := tx.First(&u, "id = ?", session.UserID).Error; err != nil {
+			return err
+		}
+		if !u.IsActive {
+			inactive = true
+			return revokeSession(tx, session.ID, models.RevokedInactive)
+		}
+
+		refresh, newHash, err := newRefreshToken()
+		if err != nil {
+			return err
+		}
+		updates := map[string]interface{}{
+			"refresh_hash": newHash,
+			"expires_at":   now.Add(RefreshTokenTTL),
+			"last_used_at": now,
+		}
+		// A replay within the grace period keeps the original previous
+		// hash and rotation time so it cannot extend its own window
+		if current {
+			updates["previous_hash"] = hash
+			updates["rotated_at"] = now
+		}
+		if err := tx.Model(&session).Updates(updates).Error; err != nil {
+			return err
+		}
+		pair, err = issueTokens(u, session.ID, refresh)
+		return err
+	})
+	// The revocations above have been committed
+	if reused {
+		return TokenPair{}, ErrSessionRevoked
+	}
+	if inactive {
+		return TokenPair{}, ErrUserInactive
+	}
+	return pair, err
+}
+
+// RevokeSession ends one session, e.g. on logout
+func RevokeSession(db *gorm.DB, sessionID, reason string) error {
+	return revokeSession(db, sessionID, reason)
+}
+
+// RevokeUserSessions ends every open session of a user and returns how
+// many there were
+func RevokeUserSessions(db *gorm.DB, userID, reason string) (int64, error) {
+	res := db.Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Updates(map[string]interface{}{"revoked_at": time.Now(), "revoked_reason": reason})
+	return res.RowsAffected, res.Error
+}
+
+func revokeSession(db *gorm.DB, sessionID interface{}, reason string) error {
+	return db.Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", sessionID).
+		Updates(map[string]interface{}{"revoked_at": time.Now(), "revoked_reason": reason}).Error
+}
+
+// checkSession confirms the session behind an access token is still open
+// and its user still active
+func checkSession(db *gorm.DB, claims *Claims) error {
+	if claims.SessionID == "" {
+		return ErrSessionRevoked
+	}
+	var state struct {
+		RevokedAt *time.Time
+		IsActive  bool
+	}
+	err := db.Model(&models.Session{}).
+		Select("sessions.revoked_at, users.is_active").
+		Joins("JOIN users ON users.id = sessions.user_id").
+		Where("sessions.id = ? AND sessions.user_id = ?", claims.SessionID, claims.UserID).
+		Take(&state).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrSessionRevoked
+	}
+	if err != nil {
+		return err
+	}
+	if state.RevokedAt != nil {
+		return ErrSessionRevoked
+	}
+	if !state.IsActive {
+		return ErrUserInactive
+	}
+	return nil
+}
+
+func issueTokens(u models.User, sessionID uuid.UUID, refresh string) (TokenPair, error) {
+	token, err := GenerateToken(u.ID.String(), u.Role, u.Name, u.Phone, sessionID.String())
+	if err != nil {
+		return TokenPair{}, err
+	}
+	return TokenPair{
+		Token:        token,
+		RefreshToken: refresh,
+		ExpiresIn:    int(AccessTokenTTL / time.Second),
+	}, nil
+}
+
+// newRefreshToken returns a random token and the hash stored for it
+func newRefreshToken() (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, hashToken(token), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func truncateString(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
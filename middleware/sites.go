@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http"
+
+	"p9e.in/ugcl/config"
+	"p9e.in/ugcl/models"
+)
+
+// GetSiteScope returns the sites a request may see and submit for. Users
+// holding sites:all are unrestricted; everyone else is limited to the sites
+// assigned to them directly or through a cluster. An API client with a
+// site list narrows the result further. Either way the aliases of the
+// allowed sites are allowed too.
+func GetSiteScope(r *http.Request) (models.SiteScope, error) {
+	scope := models.AllSites()
+
+	if claims := GetClaims(r); claims != nil &&
+		!HasPermission(claims.Role, models.PermissionName("sites", models.ActionAll)) {
+		sites, err := AssignedSites(claims.UserID)
+		if err == nil {
+			sites, err = models.WithSiteAliases(config.DB, sites)
+		}
+		if err != nil {
+			return scope, err
+		}
+		scope = models.RestrictSites(sites)
+	}
+
+	if client := GetAPIClient(r); client != nil && len(client.Sites) > 0 {
+		sites, err := models.WithSiteAliases(config.DB, client.Sites)
+		if err != nil {
+			return scope, err
+		}
+		scope = scope.Intersect(models.RestrictSites(sites))
+	}
+	return scope, nil
+}
+
+// AssignedSites lists the sites of a user, directly or through a cluster
+func AssignedSites(userID string) ([]string, error) {
+	var sites []string
+	err := config.DB.Raw(`SELECT site_name FROM user_sites WHERE user_id = ?
+		UNION SELECT sites.name FROM sites JOIN user_clusters ON user_clusters.cluster = sites.cluster
+		WHERE user_clusters.user_id = ?`, userID, userID).Scan(&sites).Error
+	return sites, err
+}
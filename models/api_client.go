@@ -0,0 +1,44 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// APIClient is an application allowed to call the API with an x-api-key.
+// Only the SHA-256 of the key is stored; the key itself is shown once when
+// it is created or rotated.
+type APIClient struct {
+	ID        uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	AppName   string    `gorm:"size:100;not null" json:"appName"`
+	KeyHash   string    `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	KeyPrefix string    `gorm:"size:16" json:"keyPrefix"` // first characters of the key, to tell keys apart
+	// PreviousKeyHash keeps the key replaced by the last rotation working
+	// until PreviousKeyExpiresAt, so a partner can switch over without downtime
+	PreviousKeyHash      string     `gorm:"size:64;index" json:"-"`
+	PreviousKeyExpiresAt *time.Time `json:"previousKeyExpiresAt,omitempty"`
+	// AllowedPaths are exact paths or prefixes ending in "*"
+	AllowedPaths   pq.StringArray `gorm:"type:text[]" json:"allowedPaths"`
+	AllowedMethods pq.StringArray `gorm:"type:text[]" json:"allowedMethods"`
+	// ReadPaths are open to GET even when AllowedMethods does not include it
+	ReadPaths pq.StringArray `gorm:"type:text[]" json:"readPaths"`
+	// AllowedCIDRs limits the client IPs; empty allows any address
+	AllowedCIDRs pq.StringArray `gorm:"column:allowed_cidrs;type:text[]" json:"allowedCidrs"`
+	// Sites limits the records the client sees; empty allows every site
+	Sites      pq.StringArray `gorm:"type:text[]" json:"sites"`
+	ExpiresAt  *time.Time     `json:"expiresAt,omitempty"`
+	RevokedAt  *time.Time     `json:"revokedAt,omitempty"`
+	LastUsedAt *time.Time     `json:"lastUsedAt,omitempty"`
+	CreatedAt  time.Time      `json:"createdAt"`
+	UpdatedAt  time.Time      `json:"updatedAt"`
+}
+
+// HashAPIKey returns the value stored in APIClient.KeyHash for a key
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
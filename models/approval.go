@@ -0,0 +1,406 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Approval statuses. A request moves draft → submitted → approved or
+// rejected, and an approved one on to its workflow's Done status. A
+// rejected request can be submitted again.
+const (
+	ApprovalDraft     = "draft"
+	ApprovalSubmitted = "submitted"
+	ApprovalApproved  = "approved"
+	ApprovalRejected  = "rejected"
+	ApprovalPaid      = "paid"
+	ApprovalFulfilled = "fulfilled"
+)
+
+// Approval actions other than the Done action of a workflow
+const (
+	ApprovalSubmit  = "submit"
+	ApprovalApprove = "approve"
+	ApprovalReject  = "reject"
+)
+
+var (
+	// ErrApprovalState is returned for an action the request's status does
+	// not allow
+	ErrApprovalState = errors.New("action not allowed in the current status")
+	// ErrApprovalRole is returned when the actor's role is not the one the
+	// current stage waits for
+	ErrApprovalRole = errors.New("the current stage is for another role")
+)
+
+// Approvable is implemented by records that go through an approval
+// workflow. ApprovalAmount returns false when the amount is missing or not
+// a number; such requests go through every stage.
+type Approvable interface {
+	ApprovalAmount() (float64, bool)
+	ApprovalDraft() bool
+}
+
+// ApprovalWorkflow describes the workflow of one record module. DoneAction
+// moves an approved request to DoneStatus and is also the permission
+// needed for it.
+type ApprovalWorkflow struct {
+	Module     string
+	Model      interface{}
+	DoneAction string
+	DoneStatus string
+}
+
+// ApprovalWorkflows are the record modules that go through approval
+var ApprovalWorkflows = []ApprovalWorkflow{
+	{Module: "payment", Model: Payment{}, DoneAction: "pay", DoneStatus: ApprovalPaid},
+	{Module: "material", Model: Material{}, DoneAction: "fulfil", DoneStatus: ApprovalFulfilled},
+}
+
+// FindApprovalWorkflow returns the workflow of module
+func FindApprovalWorkflow(module string) (ApprovalWorkflow, bool) {
+	for _, w := range ApprovalWorkflows {
+		if w.Module == module {
+			return w, true
+		}
+	}
+	return ApprovalWorkflow{}, false
+}
+
+// ApprovalStage is one step of a module's approval, decided by users with
+// Role. A stage only applies to requests of at least MinAmount, so larger
+// amounts collect more approvals. Stages run in Position order.
+type ApprovalStage struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Module    string    `gorm:"size:50;not null;uniqueIndex:idx_approval_stages_position,priority:1" json:"module"`
+	Position  int       `gorm:"not null;uniqueIndex:idx_approval_stages_position,priority:2" json:"position"`
+	Name      string    `gorm:"size:100;not null" json:"name" validate:"required,max=100"`
+	Role      string    `gorm:"size:50;not null" json:"role" validate:"required,max=50"`
+	MinAmount float64   `gorm:"not null;default:0" json:"minAmount" validate:"gte=0"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// ApprovalRequest is the approval state of one record. Stage and StageRole
+// name the stage waiting for a decision while the request is submitted.
+type ApprovalRequest struct {
+	ID              uuid.UUID        `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	Module          string           `gorm:"size:50;not null;uniqueIndex:idx_approval_requests_record,priority:1" json:"module"`
+	RecordID        uuid.UUID        `gorm:"type:uuid;not null;uniqueIndex:idx_approval_requests_record,priority:2" json:"recordId"`
+	Status          string           `gorm:"size:20;not null;index" json:"status"`
+	Amount          *float64         `json:"amount"`
+	Site            string           `gorm:"size:200;index" json:"site"`
+	Stage           int              `gorm:"not null;default:0" json:"stage,omitempty"`
+	StageName       string           `gorm:"size:100" json:"stageName,omitempty"`
+	StageRole       string           `gorm:"size:50;index" json:"stageRole,omitempty"`
+	RequestedBy     string           `gorm:"size:64;index" json:"requestedBy,omitempty"`
+	RequestedByName string           `gorm:"size:200" json:"requestedByName,omitempty"`
+	SubmittedAt     *time.Time       `json:"submittedAt,omitempty"`
+	DecidedAt       *time.Time       `json:"decidedAt,omitempty"`
+	CompletedAt     *time.Time       `json:"completedAt,omitempty"`
+	CreatedAt       time.Time        `json:"createdAt"`
+	UpdatedAt       time.Time        `json:"updatedAt"`
+	Actions         []ApprovalAction `gorm:"foreignKey:RequestID;constraint:OnDelete:CASCADE" json:"actions,omitempty"`
+	Record          interface{}      `gorm:"-" json:"record,omitempty"`
+}
+
+// ApprovalAction is one step taken on a request, with the approver's
+// comment
+type ApprovalAction struct {
+	ID         int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	RequestID  uuid.UUID `gorm:"type:uuid;not null;index" json:"requestId"`
+	Action     string    `gorm:"size:20;not null" json:"action"`
+	FromStatus string    `gorm:"size:20;not null" json:"fromStatus"`
+	ToStatus   string    `gorm:"size:20;not null" json:"toStatus"`
+	Stage      int       `json:"stage,omitempty"`
+	StageName  string    `gorm:"size:100" json:"stageName,omitempty"`
+	ActorID    string    `gorm:"size:64" json:"actorId,omitempty"`
+	ActorName  string    `gorm:"size:200" json:"actorName,omitempty"`
+	ActorRole  string    `gorm:"size:50" json:"actorRole,omitempty"`
+	Comment    string    `gorm:"type:text" json:"comment,omitempty"`
+	CreatedAt  time.Time `json:"at"`
+}
+
+// ApprovalAmount implements Approvable
+func (p Payment) ApprovalAmount() (float64, bool) {
+	return parseApprovalAmount(p.BillValue)
+}
+
+// ApprovalDraft implements Approvable
+func (p Payment) ApprovalDraft() bool { return p.Draft }
+
+// ApprovalAmount implements Approvable
+func (m Material) ApprovalAmount() (float64, bool) {
+	return parseApprovalAmount(m.EstimatedCost)
+}
+
+// ApprovalDraft implements Approvable
+func (m Material) ApprovalDraft() bool { return m.Draft }
+
+func parseApprovalAmount(raw *string) (float64, bool) {
+	if raw == nil || strings.TrimSpace(*raw) == "" {
+		return 0, false
+	}
+	q, err := ParseQuantity(*raw)
+	if err != nil {
+		return 0, false
+	}
+	return float64(q), true
+}
+
+// StartApproval opens the request of a newly created record: submitted, or
+// draft when the record asked for it. Records outside the workflows and
+// records that already have a request are left alone. Call it in the
+// transaction that created the record.
+func StartApproval(db *gorm.DB, actor AuditActor, record interface{}) error {
+	subject, ok := record.(Approvable)
+	if !ok {
+		return nil
+	}
+	module := AuditModule(record)
+	if _, ok := FindApprovalWorkflow(module); !ok {
+		return nil
+	}
+	req := ApprovalRequest{
+		Module:          module,
+		RecordID:        approvalRecordID(record),
+		Status:          ApprovalDraft,
+		RequestedBy:     actor.UserID,
+		RequestedByName: actor.Name,
+	}
+	req.Site, _, _ = RecordSite(record)
+	res := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&req)
+	if res.Error != nil || res.RowsAffected == 0 || subject.ApprovalDraft() {
+		return res.Error
+	}
+	_, err := req.apply(db, record, ApprovalSubmit, actor, "")
+	return err
+}
+
+// ActOnApproval takes action on the request of a record, locking it for the
+// rest of the transaction. Records created before the workflow existed
+// get a draft request on their first action. It returns
+// gorm.ErrRecordNotFound when the record does not exist.
+func ActOnApproval(db *gorm.DB, module string, recordID uuid.UUID, action string, actor AuditActor, comment string) (*ApprovalRequest, error) {
+	flow, ok := FindApprovalWorkflow(module)
+	if !ok {
+		return nil, &ParamError{Param: "module", Message: module + " has no approval workflow"}
+	}
+	record := reflect.New(reflect.TypeOf(flow.Model)).Interface()
+	if err := db.First(record, "id = ?", recordID).Error; err != nil {
+		return nil, err
+	}
+
+	var req ApprovalRequest
+	err := db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("module = ? AND record_id = ?", module, recordID).First(&req).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		req = ApprovalRequest{Module: module, RecordID: recordID, Status: ApprovalDraft}
+		req.Site, _, _ = RecordSite(record)
+		err = db.Create(&req).Error
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := req.apply(db, record, action, actor, comment); err != nil {
+		return nil, err
+	}
+	if err := db.Preload("Actions", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		First(&req, "id = ?", req.ID).Error; err != nil {
+		return nil, err
+	}
+	req.Record = record
+	return &req, nil
+}
+
+// apply moves the request on by action, stores it and records the step
+func (req *ApprovalRequest) apply(db *gorm.DB, record interface{}, action string, actor AuditActor, comment string) (*ApprovalAction, error) {
+	flow, _ := FindApprovalWorkflow(req.Module)
+	step := ApprovalAction{
+		RequestID:  req.ID,
+		Action:     action,
+		FromStatus: req.Status,
+		Stage:      req.Stage,
+		StageName:  req.StageName,
+		ActorID:    actor.UserID,
+		ActorName:  actor.Name,
+		ActorRole:  actor.Role,
+		Comment:    strings.TrimSpace(comment),
+	}
+	now := time.Now()
+
+	switch action {
+	case ApprovalSubmit:
+		if req.Status != ApprovalDraft && req.Status != ApprovalRejected {
+			return nil, ErrApprovalState
+		}
+		amount, known := record.(Approvable).ApprovalAmount()
+		req.Amount = nil
+		if known {
+			req.Amount = &amount
+		}
+		req.Site, _, _ = RecordSite(record)
+		req.Status, req.SubmittedAt, req.DecidedAt = ApprovalSubmitted, &now, nil
+		req.Stage = 0
+		if err := req.advance(db, now); err != nil {
+			return nil, err
+		}
+	case ApprovalApprove, ApprovalReject:
+		if req.Status != ApprovalSubmitted {
+			return nil, ErrApprovalState
+		}
+		if actor.Role != req.StageRole {
+			return nil, ErrApprovalRole
+		}
+		if action == ApprovalReject {
+			if step.Comment == "" {
+				return nil, &ParamError{Param: "comment", Message: "is required to reject"}
+			}
+			req.Status, req.DecidedAt = ApprovalRejected, &now
+			req.Stage, req.StageName, req.StageRole = 0, "", ""
+		} else if err := req.advance(db, now); err != nil {
+			return nil, err
+		}
+	case flow.DoneAction:
+		if req.Status != ApprovalApproved {
+			return nil, ErrApprovalState
+		}
+		req.Status, req.CompletedAt = flow.DoneStatus, &now
+	default:
+		return nil, &ParamError{Param: "action", Message: fmt.Sprintf("must be %s, %s, %s or %s",
+			ApprovalSubmit, ApprovalApprove, ApprovalReject, flow.DoneAction)}
+	}
+
+	step.ToStatus = req.Status
+	if err := db.Omit("Actions").Save(req).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Create(&step).Error; err != nil {
+		return nil, err
+	}
+	return &step, nil
+}
+
+// advance moves a submitted request to the next stage that applies to its
+// amount, or approves it when none is left
+func (req *ApprovalRequest) advance(db *gorm.DB, now time.Time) error {
+	query := db.Where("module = ? AND position > ?", req.Module, req.Stage)
+	if req.Amount != nil {
+		query = query.Where("min_amount <= ?", *req.Amount)
+	}
+	var next ApprovalStage
+	err := query.Order("position").First(&next).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		req.Status, req.DecidedAt = ApprovalApproved, &now
+		req.Stage, req.StageName, req.StageRole = 0, "", ""
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	req.Stage, req.StageName, req.StageRole = next.Position, next.Name, next.Role
+	return nil
+}
+
+// PendingApprovals lists the requests waiting on role, oldest first:
+// submitted ones at a stage for role, in modules it may approve, and
+// approved ones in modules it may complete. can reports whether role holds
+// a permission. Each request carries its record.
+func PendingApprovals(db *gorm.DB, role string, can func(permission string) bool, scope SiteScope) ([]ApprovalRequest, error) {
+	pending := []ApprovalRequest{}
+	for _, flow := range ApprovalWorkflows {
+		var conds []string
+		var vars []interface{}
+		if can(PermissionName(flow.Module, ActionApprove)) {
+			conds = append(conds, "(status = ? AND stage_role = ?)")
+			vars = append(vars, ApprovalSubmitted, role)
+		}
+		if can(PermissionName(flow.Module, flow.DoneAction)) {
+			conds = append(conds, "status = ?")
+			vars = append(vars, ApprovalApproved)
+		}
+		if len(conds) == 0 {
+			continue
+		}
+		query := db.Where("module = ?", flow.Module).Where(strings.Join(conds, " OR "), vars...)
+		if !scope.All {
+			query = query.Where("LOWER(TRIM(site)) IN ?", scope.Sites)
+		}
+		var requests []ApprovalRequest
+		if err := query.Order("submitted_at, created_at").Find(&requests).Error; err != nil {
+			return nil, err
+		}
+		if err := attachApprovalRecords(db, flow, requests); err != nil {
+			return nil, err
+		}
+		pending = append(pending, requests...)
+	}
+	return pending, nil
+}
+
+// attachApprovalRecords loads the record of each request. Requests whose
+// record was deleted keep a nil record.
+func attachApprovalRecords(db *gorm.DB, flow ApprovalWorkflow, requests []ApprovalRequest) error {
+	if len(requests) == 0 {
+		return nil
+	}
+	ids := make([]uuid.UUID, len(requests))
+	for i, req := range requests {
+		ids[i] = req.RecordID
+	}
+	records := reflect.New(reflect.SliceOf(reflect.TypeOf(flow.Model)))
+	if err := db.Where("id IN ?", ids).Find(records.Interface()).Error; err != nil {
+		return err
+	}
+	byID := map[uuid.UUID]interface{}{}
+	for i := 0; i < records.Elem().Len(); i++ {
+		record := records.Elem().Index(i).Addr().Interface()
+		byID[approvalRecordID(record)] = record
+	}
+	for i := range requests {
+		if record, ok := byID[requests[i].RecordID]; ok {
+			requests[i].Record = record
+		}
+	}
+	return nil
+}
+
+// FindApproval returns the request of a record with its actions, or
+// gorm.ErrRecordNotFound when it has none
+func FindApproval(db *gorm.DB, module string, recordID uuid.UUID) (*ApprovalRequest, error) {
+	var req ApprovalRequest
+	err := db.Preload("Actions", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Where("module = ? AND record_id = ?", module, recordID).First(&req).Error
+	if err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+// ReplaceApprovalStages sets the stages of module, numbering them in the
+// order given
+func ReplaceApprovalStages(db *gorm.DB, module string, stages []ApprovalStage) error {
+	if err := db.Where("module = ?", module).Delete(&ApprovalStage{}).Error; err != nil {
+		return err
+	}
+	for i := range stages {
+		stages[i].ID = 0
+		stages[i].Module = module
+		stages[i].Position = i + 1
+	}
+	if len(stages) == 0 {
+		return nil
+	}
+	return db.Create(&stages).Error
+}
+
+func approvalRecordID(record interface{}) uuid.UUID {
+	id, _ := reflect.Indirect(reflect.ValueOf(record)).FieldByName("ID").Interface().(uuid.UUID)
+	return id
+}
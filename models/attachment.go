@@ -0,0 +1,346 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"path"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"p9e.in/ugcl/storage"
+)
+
+// attachmentReapBatch bounds how many unlinked uploads one pass loads at once
+const attachmentReapBatch = 200
+
+// Downsized copies kept of an image attachment, as named by ?variant= on
+// /files/{id}. Unlike the original they carry no EXIF data.
+const (
+	VariantThumbnail = "thumbnail"
+	VariantWeb       = "web"
+)
+
+// Attachment is an uploaded file. The object itself is private in the
+// storage backend under Key; clients fetch it through /files/{id}, which
+// hands out short-lived signed URLs.
+//
+// An upload starts out unlinked. Creating a record with its ID in
+// attachmentIds links it to that record, Module and RecordID; uploads that
+// are never linked are reaped by DeleteUnlinkedAttachments.
+//
+// Images also get a thumbnail and a web copy, and whatever their EXIF data
+// tells of where and when they were taken. Once linked, DistanceMeters and
+// CaptureLagMinutes compare that with the record's position and
+// submission time, which gives away photos taken elsewhere or long before,
+// and photos reused from earlier records are flagged as PhotoMatches.
+type Attachment struct {
+	ID                uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	Key               string     `gorm:"size:300;not null;uniqueIndex" json:"-"`
+	Name              string     `gorm:"size:255;not null" json:"name"`
+	ContentType       string     `gorm:"size:100;not null" json:"contentType"`
+	Size              int64      `gorm:"not null" json:"size"`
+	SHA256            string     `gorm:"column:sha256;size:64;index" json:"sha256"`
+	PHash             *int64     `gorm:"column:phash" json:"-"`                                      // perceptual hash of images, see imaging.Distance
+	Label             string     `gorm:"size:50" json:"label,omitempty" validate:"omitempty,max=50"` // e.g. "meter reading", "bill"
+	CapturedAt        *time.Time `json:"capturedAt,omitempty" validate:"omitempty,notfuture"`        // from EXIF, else as the client stated
+	Width             int        `json:"width,omitempty"`
+	Height            int        `json:"height,omitempty"`
+	Latitude          *float64   `json:"latitude,omitempty"` // from EXIF
+	Longitude         *float64   `json:"longitude,omitempty"`
+	ThumbnailKey      string     `gorm:"size:300" json:"-"`
+	WebKey            string     `gorm:"size:300" json:"-"`
+	Module            string     `gorm:"size:30;index:idx_attachments_record" json:"module,omitempty"`
+	RecordID          *uuid.UUID `gorm:"type:uuid;index:idx_attachments_record" json:"recordId,omitempty"`
+	LinkedAt          *time.Time `json:"linkedAt,omitempty"`
+	DistanceMeters    *float64   `json:"distanceMeters,omitempty"`    // from the record's position, set when linked
+	CaptureLagMinutes *int       `json:"captureLagMinutes,omitempty"` // before the record's submission, set when linked
+	UploadedBy        string     `gorm:"size:64;index" json:"uploadedBy,omitempty"`
+	UploadedByName    string     `gorm:"size:100" json:"uploadedByName,omitempty"`
+	CreatedAt         time.Time  `gorm:"index" json:"createdAt"`
+
+	URL          string `gorm:"-" json:"url"`
+	ThumbnailURL string `gorm:"-" json:"thumbnailUrl,omitempty"`
+	WebURL       string `gorm:"-" json:"webUrl,omitempty"`
+}
+
+// AfterFind fills in the URLs
+func (a *Attachment) AfterFind(tx *gorm.DB) error {
+	a.fillURLs()
+	return nil
+}
+
+// AfterCreate fills in the URLs
+func (a *Attachment) AfterCreate(tx *gorm.DB) error {
+	a.fillURLs()
+	return nil
+}
+
+func (a *Attachment) fillURLs() {
+	a.URL = AttachmentURL(a.ID)
+	a.ThumbnailURL, a.WebURL = "", ""
+	if a.ThumbnailKey != "" {
+		a.ThumbnailURL = a.URL + "?variant=" + VariantThumbnail
+	}
+	if a.WebKey != "" {
+		a.WebURL = a.URL + "?variant=" + VariantWeb
+	}
+}
+
+// VariantKey returns the object key of a variant of the attachment, the
+// original for "". It reports false when there is no such variant.
+func (a Attachment) VariantKey(variant string) (string, bool) {
+	var key string
+	switch variant {
+	case "":
+		key = a.Key
+	case VariantThumbnail:
+		key = a.ThumbnailKey
+	case VariantWeb:
+		key = a.WebKey
+	}
+	return key, key != ""
+}
+
+// Keys lists the objects the attachment is stored as
+func (a Attachment) Keys() []string {
+	keys := []string{a.Key}
+	for _, k := range []string{a.ThumbnailKey, a.WebKey} {
+		if k != "" {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// AttachmentURL is the stable URL records keep for an attachment
+func AttachmentURL(id uuid.UUID) string {
+	return "/api/v1/files/" + id.String()
+}
+
+// AttachmentKey names the object of a new attachment. It is derived from
+// the ID, never the client's file name, so uploads cannot overwrite each
+// other; only a short extension is kept for the benefit of downloads.
+func AttachmentKey(id uuid.UUID, name string, at time.Time) string {
+	ext := strings.ToLower(path.Ext(strings.ReplaceAll(name, "\\", "/")))
+	clean := ""
+	for _, r := range strings.TrimPrefix(ext, ".") {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			clean += string(r)
+		}
+	}
+	key := at.UTC().Format("2006/01/") + id.String()
+	if clean != "" && len(clean) <= 10 {
+		key += "." + clean
+	}
+	return key
+}
+
+// AttachmentVariantKey names the object of a variant next to the original
+// under key. Variants are always JPEGs.
+func AttachmentVariantKey(key, variant string) string {
+	return strings.TrimSuffix(key, path.Ext(key)) + "_" + variant + ".jpg"
+}
+
+// AttachmentLinks is the part of a create body naming the uploads the new
+// record keeps. It sits next to the record's own fields.
+type AttachmentLinks struct {
+	AttachmentIDs []uuid.UUID `json:"attachmentIds"`
+}
+
+// CheckAttachments returns a ValidationError unless every attachment in
+// ids was uploaded by uploader and is either unlinked or already linked to
+// the record, as on a retried submission
+func CheckAttachments(db *gorm.DB, ids []uuid.UUID, uploader string, record interface{}) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	module, recordID := AuditModule(record), approvalRecordID(record)
+	var found []Attachment
+	err := db.Select("id", "uploaded_by", "module", "record_id").
+		Where("id IN ?", ids).Find(&found).Error
+	if err != nil {
+		return err
+	}
+	byID := make(map[uuid.UUID]Attachment, len(found))
+	for _, a := range found {
+		byID[a.ID] = a
+	}
+	var verr ValidationError
+	for _, id := range ids {
+		a, ok := byID[id]
+		var problem string
+		switch {
+		case !ok:
+			problem = "does not exist"
+		case a.UploadedBy != uploader:
+			problem = "was uploaded by someone else"
+		case a.RecordID != nil && (a.Module != module || *a.RecordID != recordID):
+			problem = "belongs to another record"
+		default:
+			continue
+		}
+		verr = append(verr, FieldError{
+			Field:   "attachmentIds",
+			Code:    "attachment",
+			Message: fmt.Sprintf("attachment %s %s", id, problem),
+		})
+	}
+	if len(verr) > 0 {
+		return verr
+	}
+	return nil
+}
+
+// LinkAttachments links the attachments in ids to record. Run it in the
+// transaction that creates the record, after CheckAttachments; an
+// attachment linked elsewhere in the meantime fails the whole link.
+func LinkAttachments(db *gorm.DB, ids []uuid.UUID, record interface{}) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	module, recordID := AuditModule(record), approvalRecordID(record)
+	unique := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		unique[id] = true
+	}
+	res := db.Model(&Attachment{}).
+		Where("id IN ? AND (record_id IS NULL OR (module = ? AND record_id = ?))", ids, module, recordID).
+		Updates(map[string]interface{}{
+			"module":    module,
+			"record_id": recordID,
+			"linked_at": gorm.Expr("COALESCE(linked_at, ?)", time.Now()),
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if int(res.RowsAffected) != len(unique) {
+		return ValidationError{{Field: "attachmentIds", Code: "attachment", Message: "an attachment was linked to another record"}}
+	}
+	if err := compareAttachments(db, ids, record); err != nil {
+		return err
+	}
+	return matchPhotos(db, ids, record)
+}
+
+// compareAttachments sets DistanceMeters and CaptureLagMinutes of the
+// attachments in ids from the position and submission time of record,
+// for those that know where or when they were taken
+func compareAttachments(db *gorm.DB, ids []uuid.UUID, record interface{}) error {
+	pos, hasPos := recordPosition(record)
+	submitted, hasTime := recordSubmittedAt(record)
+	if !hasPos && !hasTime {
+		return nil
+	}
+	var list []Attachment
+	err := db.Select("id", "latitude", "longitude", "captured_at").
+		Where("id IN ? AND (latitude IS NOT NULL OR captured_at IS NOT NULL)", ids).Find(&list).Error
+	if err != nil {
+		return err
+	}
+	for _, a := range list {
+		updates := map[string]interface{}{}
+		if hasPos && a.Latitude != nil && a.Longitude != nil {
+			updates["distance_meters"] = math.Round(DistanceKm(pos, GeoPoint{Lat: *a.Latitude, Lng: *a.Longitude}) * 1000)
+		}
+		if hasTime && a.CapturedAt != nil {
+			updates["capture_lag_minutes"] = int(submitted.Sub(*a.CapturedAt).Round(time.Minute) / time.Minute)
+		}
+		if len(updates) == 0 {
+			continue
+		}
+		if err := db.Model(&Attachment{}).Where("id = ?", a.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordPosition returns the Latitude and Longitude of a record that has
+// them set
+func recordPosition(record interface{}) (GeoPoint, bool) {
+	v := reflect.Indirect(reflect.ValueOf(record))
+	lat, latOK := fieldValue(v, "Latitude").(float64)
+	lng, lngOK := fieldValue(v, "Longitude").(float64)
+	if !latOK || !lngOK || (lat == 0 && lng == 0) {
+		return GeoPoint{}, false
+	}
+	return GeoPoint{Lat: lat, Lng: lng}, true
+}
+
+// recordSubmittedAt returns the SubmittedAt of a record that has it set
+func recordSubmittedAt(record interface{}) (time.Time, bool) {
+	var t time.Time
+	switch at := fieldValue(reflect.Indirect(reflect.ValueOf(record)), "SubmittedAt").(type) {
+	case JSONTime:
+		t = time.Time(at)
+	case time.Time:
+		t = at
+	}
+	return t, !t.IsZero()
+}
+
+func fieldValue(v reflect.Value, name string) interface{} {
+	f := v.FieldByName(name)
+	if !f.IsValid() {
+		return nil
+	}
+	return f.Interface()
+}
+
+// unlinkAttachments releases the attachments of a purged record, leaving
+// them to DeleteUnlinkedAttachments
+func unlinkAttachments(db *gorm.DB, module string, recordID uuid.UUID) error {
+	return db.Model(&Attachment{}).
+		Where("module = ? AND record_id = ?", module, recordID).
+		Updates(map[string]interface{}{"module": "", "record_id": nil, "linked_at": nil}).Error
+}
+
+// RecordAttachments lists the attachments linked to a record, oldest first
+func RecordAttachments(db *gorm.DB, module, recordID string) ([]Attachment, error) {
+	var list []Attachment
+	err := db.Where("module = ? AND record_id = ?", module, recordID).
+		Order("created_at, id").Find(&list).Error
+	return list, err
+}
+
+// DeleteUnlinkedAttachments removes the uploads that are still unlinked
+// more than age after they were made, objects and all, and returns how
+// many went. Each is removed in its own transaction, so one that is
+// linked while the pass runs is left alone.
+func DeleteUnlinkedAttachments(db *gorm.DB, store storage.Backend, age time.Duration) (int, error) {
+	cutoff := time.Now().Add(-age)
+	deleted := 0
+	for {
+		var batch []Attachment
+		err := db.Where("record_id IS NULL AND created_at < ?", cutoff).
+			Order("created_at").Limit(attachmentReapBatch).Find(&batch).Error
+		if err != nil {
+			return deleted, err
+		}
+		for _, a := range batch {
+			err := db.Transaction(func(tx *gorm.DB) error {
+				res := tx.Where("record_id IS NULL").Delete(&Attachment{}, "id = ?", a.ID)
+				if res.Error != nil || res.RowsAffected == 0 {
+					return res.Error
+				}
+				for _, key := range a.Keys() {
+					if err := store.Delete(context.Background(), key); err != nil {
+						return err
+					}
+				}
+				deleted++
+				return nil
+			})
+			if err != nil {
+				return deleted, err
+			}
+		}
+		if len(batch) < attachmentReapBatch {
+			return deleted, nil
+		}
+	}
+}
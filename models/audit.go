@@ -0,0 +1,193 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// Audit actions
+const (
+	AuditCreate  = "create"
+	AuditUpdate  = "update"
+	AuditDelete  = "delete"
+	AuditRestore = "restore"
+	// AuditPurge is the permanent removal of a deleted record
+	AuditPurge = "purge"
+)
+
+// auditIgnored are fields every save touches, left out of update diffs
+var auditIgnored = map[string]bool{"createdAt": true, "updatedAt": true}
+
+// AuditLog is one append-only entry of the audit trail. Changes maps each
+// JSON field to its {before, after} values; before is null on create and
+// after is null on delete.
+type AuditLog struct {
+	ID        int64          `gorm:"primaryKey;autoIncrement" json:"id"`
+	Module    string         `gorm:"size:50;not null;index:idx_audit_logs_record,priority:1" json:"module"`
+	RecordID  string         `gorm:"size:64;not null;index:idx_audit_logs_record,priority:2" json:"recordId"`
+	Action    string         `gorm:"size:20;not null" json:"action"`
+	ActorID   string         `gorm:"size:64;index" json:"actorId,omitempty"`
+	ActorName string         `gorm:"size:200" json:"actorName,omitempty"`
+	ActorRole string         `gorm:"size:50" json:"actorRole,omitempty"`
+	APIClient string         `gorm:"size:100" json:"apiClient,omitempty"`
+	IP        string         `gorm:"size:64" json:"ip,omitempty"`
+	Changes   datatypes.JSON `gorm:"type:jsonb;not null" json:"changes" swaggertype:"object"`
+	CreatedAt time.Time      `gorm:"index" json:"at"`
+}
+
+// AuditActor is who made a change
+type AuditActor struct {
+	UserID    string
+	Name      string
+	Role      string
+	APIClient string
+	IP        string
+}
+
+// AuditChange is the before and after value of one field
+type AuditChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// AuditState is a record's fields as JSON values, taken with AuditSnapshot
+type AuditState map[string]interface{}
+
+// AuditSnapshot captures record before it is changed. Take it before
+// decoding a request body into the record, as decoding reuses its slices.
+func AuditSnapshot(record interface{}) AuditState {
+	raw, err := json.Marshal(record)
+	if err != nil {
+		return nil
+	}
+	var state AuditState
+	if err := json.Unmarshal(raw, &state); err != nil {
+		return nil
+	}
+	return state
+}
+
+// AuditModule names the module of a record model, as used in its routes,
+// or "" for models outside the audit trail
+func AuditModule(record interface{}) string {
+	t := reflect.Indirect(reflect.ValueOf(record)).Type()
+	for _, m := range SyncModules {
+		if reflect.TypeOf(m.Model) == t {
+			return m.Name
+		}
+	}
+	for _, m := range MasterTables {
+		if reflect.TypeOf(m.Model) == t {
+			return "master/" + m.Kind
+		}
+	}
+	return ""
+}
+
+// AuditModel returns the record model of an audited module
+func AuditModel(module string) (interface{}, bool) {
+	for _, m := range SyncModules {
+		if m.Name == module {
+			return m.Model, true
+		}
+	}
+	for _, m := range MasterTables {
+		if "master/"+m.Kind == module {
+			return m.Model, true
+		}
+	}
+	return nil, false
+}
+
+// WriteAudit appends an entry for record. before is the AuditSnapshot
+// taken ahead of an update; it is ignored on create and restore, and taken
+// from record on delete and purge when nil. An update that changed nothing is not
+// recorded. Call it in the transaction that made the change.
+func WriteAudit(db *gorm.DB, actor AuditActor, action string, record interface{}, before AuditState) error {
+	module := AuditModule(record)
+	if module == "" {
+		return fmt.Errorf("%T is not an audited model", record)
+	}
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(record); err != nil {
+		return err
+	}
+	pk := stmt.Schema.PrioritizedPrimaryField
+	if pk == nil {
+		return fmt.Errorf("%T has no primary key", record)
+	}
+	id, _ := pk.ValueOf(context.Background(), reflect.Indirect(reflect.ValueOf(record)))
+
+	current := AuditSnapshot(record)
+	var changes map[string]AuditChange
+	switch action {
+	case AuditCreate:
+		changes = diffAuditStates(nil, current, false)
+	case AuditDelete, AuditPurge:
+		if before == nil {
+			before = current
+		}
+		changes = diffAuditStates(before, nil, false)
+	case AuditRestore:
+		changes = diffAuditStates(nil, current, false)
+	default:
+		changes = diffAuditStates(before, current, true)
+		if len(changes) == 0 {
+			return nil
+		}
+	}
+	raw, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+
+	return db.Create(&AuditLog{
+		Module:    module,
+		RecordID:  fmt.Sprint(id),
+		Action:    action,
+		ActorID:   actor.UserID,
+		ActorName: actor.Name,
+		ActorRole: actor.Role,
+		APIClient: actor.APIClient,
+		IP:        actor.IP,
+		Changes:   datatypes.JSON(raw),
+	}).Error
+}
+
+// diffAuditStates lists the fields that differ between two states. With
+// skipIgnored the bookkeeping timestamps are left out.
+func diffAuditStates(before, after AuditState, skipIgnored bool) map[string]AuditChange {
+	changes := map[string]AuditChange{}
+	for k, b := range before {
+		if skipIgnored && auditIgnored[k] {
+			continue
+		}
+		a, ok := after[k]
+		if !ok || !reflect.DeepEqual(a, b) {
+			changes[k] = AuditChange{Before: b, After: a}
+		}
+	}
+	for k, a := range after {
+		if skipIgnored && auditIgnored[k] {
+			continue
+		}
+		if _, ok := before[k]; !ok {
+			changes[k] = AuditChange{Before: nil, After: a}
+		}
+	}
+	return changes
+}
+
+// AuditHistory lists the entries of one record, oldest first
+func AuditHistory(db *gorm.DB, module, recordID string) ([]AuditLog, error) {
+	entries := []AuditLog{}
+	err := db.Where("module = ? AND record_id = ?", module, recordID).
+		Order("id").Find(&entries).Error
+	return entries, err
+}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffAuditStates(t *testing.T) {
+	tests := []struct {
+		name          string
+		before, after AuditState
+		skipIgnored   bool
+		want          map[string]AuditChange
+	}{
+		{
+			name:   "unchanged",
+			before: AuditState{"item": "HSD", "photos": []interface{}{"a.jpg"}},
+			after:  AuditState{"item": "HSD", "photos": []interface{}{"a.jpg"}},
+			want:   map[string]AuditChange{},
+		},
+		{
+			name:   "changed values",
+			before: AuditState{"item": "HSD", "quantityInLiters": 40.0, "photos": []interface{}{"a.jpg"}},
+			after:  AuditState{"item": "HSD", "quantityInLiters": 45.5, "photos": []interface{}{"a.jpg", "b.jpg"}},
+			want: map[string]AuditChange{
+				"quantityInLiters": {Before: 40.0, After: 45.5},
+				"photos":           {Before: []interface{}{"a.jpg"}, After: []interface{}{"a.jpg", "b.jpg"}},
+			},
+		},
+		{
+			name:   "fields appearing and disappearing",
+			before: AuditState{"remarks": "late"},
+			after:  AuditState{"vehicleId": "v1"},
+			want: map[string]AuditChange{
+				"remarks":   {Before: "late", After: nil},
+				"vehicleId": {Before: nil, After: "v1"},
+			},
+		},
+		{
+			name:   "create",
+			before: nil,
+			after:  AuditState{"item": "HSD", "createdAt": "2026-10-01T00:00:00Z"},
+			want: map[string]AuditChange{
+				"item":      {Before: nil, After: "HSD"},
+				"createdAt": {Before: nil, After: "2026-10-01T00:00:00Z"},
+			},
+		},
+		{
+			name:        "timestamps skipped",
+			before:      AuditState{"item": "HSD", "updatedAt": "2026-10-01T00:00:00Z"},
+			after:       AuditState{"item": "HSD", "updatedAt": "2026-10-02T00:00:00Z", "createdAt": "2026-10-01T00:00:00Z"},
+			skipIgnored: true,
+			want:        map[string]AuditChange{},
+		},
+		{
+			name:   "timestamps kept",
+			before: AuditState{"updatedAt": "2026-10-01T00:00:00Z"},
+			after:  AuditState{"updatedAt": "2026-10-02T00:00:00Z"},
+			want: map[string]AuditChange{
+				"updatedAt": {Before: "2026-10-01T00:00:00Z", After: "2026-10-02T00:00:00Z"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := diffAuditStates(tt.before, tt.after, tt.skipIgnored)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffAuditStates = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuditSnapshotCopies(t *testing.T) {
+	// Decoding into the record afterwards must not change the snapshot
+	d := Diesel{NameOfSite: "Yard A", BillPhotos: []string{"a.jpg"}}
+	before := AuditSnapshot(&d)
+	d.NameOfSite = "Yard B"
+	d.BillPhotos[0] = "b.jpg"
+
+	changes := diffAuditStates(before, AuditSnapshot(&d), true)
+	want := map[string]AuditChange{
+		"nameOfSite": {Before: "Yard A", After: "Yard B"},
+		"billPhotos": {Before: []interface{}{"a.jpg"}, After: []interface{}{"b.jpg"}},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("changes = %v, want %v", changes, want)
+	}
+}
@@ -0,0 +1,305 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Per-item outcomes reported by BatchService.Ingest
+const (
+	BatchCreated   = "created"
+	BatchDuplicate = "duplicate"
+	BatchInvalid   = "invalid"
+	BatchFailed    = "failed"
+	BatchSkipped   = "skipped" // not written because an atomic batch was rolled back
+)
+
+// Transaction modes for a batch
+const (
+	// BatchModePartial writes every valid item on its own, so one bad item
+	// does not hold back the rest
+	BatchModePartial = "partial"
+	// BatchModeAtomic writes nothing unless every item is valid and inserts
+	// cleanly
+	BatchModeAtomic = "atomic"
+)
+
+// batchNamespace seeds the record IDs derived from idempotency keys
+var batchNamespace = uuid.MustParse("8f0c6a52-3b1e-4f7d-9c2a-6d5e4b3a2f10")
+
+// BatchResult describes what happened to one item of a batch, in request order
+type BatchResult struct {
+	Index          int             `json:"index"`
+	ID             string          `json:"id,omitempty"`
+	IdempotencyKey string          `json:"idempotencyKey,omitempty"`
+	Status         string          `json:"status"`
+	Errors         ValidationError `json:"errors,omitempty"`
+	Error          string          `json:"error,omitempty"`
+}
+
+// BatchResponse is the body returned by the /batch endpoints
+type BatchResponse struct {
+	Mode       string        `json:"mode"`
+	Committed  bool          `json:"committed"` // false when an atomic batch was rolled back
+	Created    int           `json:"created"`
+	Duplicates int           `json:"duplicates"`
+	Invalid    int           `json:"invalid"`
+	Failed     int           `json:"failed"`
+	Skipped    int           `json:"skipped"`
+	Results    []BatchResult `json:"results"`
+}
+
+// BatchService ingests offline submissions for one model
+type BatchService[T any] struct {
+	db    *gorm.DB
+	model T
+	scope SiteScope
+	actor *AuditActor
+	check func(*T) error
+}
+
+// NewBatchService creates a new generic batch service
+func NewBatchService[T any](db *gorm.DB, model T) *BatchService[T] {
+	return &BatchService[T]{db: db, model: model, scope: AllSites()}
+}
+
+// WithSiteScope rejects items for sites outside scope as invalid
+func (s *BatchService[T]) WithSiteScope(scope SiteScope) *BatchService[T] {
+	s.scope = scope
+	return s
+}
+
+// WithAudit records every created item in the audit trail as made by actor
+func (s *BatchService[T]) WithAudit(actor AuditActor) *BatchService[T] {
+	s.actor = &actor
+	return s
+}
+
+// WithCheck runs check on every item that passed validation; an error
+// makes the item invalid
+func (s *BatchService[T]) WithCheck(check func(*T) error) *BatchService[T] {
+	s.check = check
+	return s
+}
+
+type batchItem[T any] struct {
+	result      BatchResult
+	record      *T
+	attachments []uuid.UUID
+}
+
+// Ingest decodes, validates and inserts each raw item. stamp is called on
+// every decoded item before validation so handlers can fill in the
+// submitting user. owner scopes idempotency keys so two users can never
+// collide on the same key.
+//
+// An item is identified by its client-generated "id" when present,
+// otherwise by an ID derived from its "idempotencyKey". Either way a retry
+// of an item that was already stored reports "duplicate" instead of
+// inserting it again. Uploads listed in an item's "attachmentIds" must have
+// been made by owner and are linked to the new record.
+func (s *BatchService[T]) Ingest(raw []json.RawMessage, mode, owner string, stamp func(*T)) (*BatchResponse, error) {
+	if mode == "" {
+		mode = BatchModePartial
+	}
+	if mode != BatchModePartial && mode != BatchModeAtomic {
+		return nil, &ParamError{Param: "mode", Message: fmt.Sprintf("must be %s or %s", BatchModePartial, BatchModeAtomic)}
+	}
+
+	stmt := &gorm.Statement{DB: s.db}
+	if err := stmt.Parse(s.model); err != nil {
+		return nil, err
+	}
+	table := stmt.Schema.Table
+
+	items := make([]batchItem[T], len(raw))
+	for i, data := range raw {
+		items[i] = s.prepare(i, data, table, owner, stamp)
+	}
+
+	resp := &BatchResponse{Mode: mode, Committed: true, Results: make([]BatchResult, len(items))}
+	if mode == BatchModeAtomic {
+		resp.Committed = s.ingestAtomic(items)
+	} else {
+		for i := range items {
+			if items[i].record != nil {
+				s.db.Transaction(func(tx *gorm.DB) error {
+					return s.insert(tx, &items[i])
+				})
+			}
+		}
+	}
+
+	for i, it := range items {
+		resp.Results[i] = it.result
+		switch it.result.Status {
+		case BatchCreated:
+			resp.Created++
+		case BatchDuplicate:
+			resp.Duplicates++
+		case BatchInvalid:
+			resp.Invalid++
+		case BatchFailed:
+			resp.Failed++
+		case BatchSkipped:
+			resp.Skipped++
+		}
+	}
+	return resp, nil
+}
+
+// prepare decodes and validates one item. The returned record is nil when
+// the item is invalid.
+func (s *BatchService[T]) prepare(index int, data json.RawMessage, table, owner string, stamp func(*T)) batchItem[T] {
+	it := batchItem[T]{result: BatchResult{Index: index}}
+
+	var meta struct {
+		IdempotencyKey string `json:"idempotencyKey"`
+		AttachmentLinks
+	}
+	json.Unmarshal(data, &meta)
+	it.result.IdempotencyKey = meta.IdempotencyKey
+
+	record := new(T)
+	if err := json.Unmarshal(data, record); err != nil {
+		it.result.Status = BatchInvalid
+		it.result.Errors = ValidationError{{Code: "json", Message: err.Error()}}
+		return it
+	}
+
+	id := reflect.ValueOf(record).Elem().FieldByName("ID")
+	if !id.IsValid() || !id.CanSet() {
+		it.result.Status = BatchInvalid
+		it.result.Errors = ValidationError{{Field: "id", Code: "unsupported", Message: "model has no settable ID"}}
+		return it
+	}
+	if id.IsZero() {
+		// Without a client ID a key is the only way to recognise a retry;
+		// with neither the item is always inserted as new.
+		generated := uuid.New()
+		if meta.IdempotencyKey != "" {
+			generated = uuid.NewSHA1(batchNamespace, []byte(table+"\x00"+owner+"\x00"+meta.IdempotencyKey))
+		}
+		setRecordID(id, generated)
+	}
+	it.result.ID = fmt.Sprint(id.Interface())
+
+	if stamp != nil {
+		stamp(record)
+	}
+	err := ValidateModel(record)
+	if err == nil {
+		err = CheckRecordSite(record, s.scope)
+	}
+	if err == nil && s.check != nil {
+		err = s.check(record)
+	}
+	if err == nil {
+		err = EnforceGeofence(s.db, record)
+	}
+	if err == nil {
+		err = CheckAttachments(s.db, meta.AttachmentIDs, owner, record)
+	}
+	if err != nil {
+		var verr ValidationError
+		if !errors.As(err, &verr) {
+			verr = ValidationError{{Code: "validation", Message: err.Error()}}
+		}
+		it.result.Status = BatchInvalid
+		it.result.Errors = verr
+		return it
+	}
+	it.record = record
+	it.attachments = meta.AttachmentIDs
+	return it
+}
+
+func setRecordID(field reflect.Value, id uuid.UUID) {
+	switch {
+	case field.Type() == reflect.TypeOf(uuid.UUID{}):
+		field.Set(reflect.ValueOf(id))
+	case field.Kind() == reflect.String:
+		field.SetString(id.String())
+	}
+}
+
+// insert writes one record, treating a primary key conflict as a duplicate.
+// A new record of an approval workflow has its request opened as well.
+func (s *BatchService[T]) insert(db *gorm.DB, it *batchItem[T]) error {
+	res := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoNothing: true,
+	}).Create(it.record)
+	if res.Error != nil {
+		it.result.Status = BatchFailed
+		it.result.Error = res.Error.Error()
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		it.result.Status = BatchDuplicate
+		return nil
+	}
+	if err := LinkAttachments(db, it.attachments, it.record); err != nil {
+		it.result.Status = BatchFailed
+		it.result.Error = err.Error()
+		return err
+	}
+	var actor AuditActor
+	if s.actor != nil {
+		actor = *s.actor
+		if err := WriteAudit(db, actor, AuditCreate, it.record, nil); err != nil {
+			it.result.Status = BatchFailed
+			it.result.Error = err.Error()
+			return err
+		}
+	}
+	if err := StartApproval(db, actor, it.record); err != nil {
+		it.result.Status = BatchFailed
+		it.result.Error = err.Error()
+		return err
+	}
+	it.result.Status = BatchCreated
+	return nil
+}
+
+// ingestAtomic inserts every item in one transaction, or none of them if
+// any item is invalid or fails to insert. It reports whether it committed.
+func (s *BatchService[T]) ingestAtomic(items []batchItem[T]) bool {
+	for _, it := range items {
+		if it.record == nil {
+			markSkipped(items)
+			return false
+		}
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		for i := range items {
+			if err := s.insert(tx, &items[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		markSkipped(items)
+		return false
+	}
+	return true
+}
+
+// markSkipped reports every item that was not itself the cause of a
+// rolled back atomic batch as skipped
+func markSkipped[T any](items []batchItem[T]) {
+	for i := range items {
+		switch items[i].result.Status {
+		case BatchInvalid, BatchFailed:
+		default:
+			items[i].result.Status = BatchSkipped
+		}
+	}
+}
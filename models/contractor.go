@@ -10,25 +10,33 @@ import (
 
 // Contractor corresponds to your Dart ContractorModel.
 type Contractor struct {
-	ID                uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
-	SiteName          string         `gorm:"not null" json:"siteName"`
-	ContractorName    string         `gorm:"not null" json:"contractorName"`
-	ContractorPhone   string         `gorm:"not null" json:"contractorPhone"`
-	ChainageFrom      string         `gorm:"not null" json:"chainageFrom"`
-	ChainageTo        string         `gorm:"not null" json:"chainageTo"`
-	ActualMeters      string         `gorm:"not null" json:"actualMeters"`
-	DieselTaken       string         `gorm:"not null" json:"dieselTaken"`
-	VehicleType       string         `json:"vehicleType"`
-	WoringHours       string         `json:"woringHours"`
-	MeterPhotos       pq.StringArray `gorm:"type:text[]" json:"meterPhotos" swaggertype:"array,string"`
-	CardNumber        string         `gorm:"not null" json:"cardNumber"`
-	AreaPhotos        pq.StringArray `gorm:"type:text[]" json:"areaPhotos" swaggertype:"array,string"`
-	SiteEngineerName  string         `gorm:"not null" json:"siteEngineerName"`
-	SiteEngineerPhone string         `gorm:"not null" json:"siteEngineerPhone"`
-	Latitude          float64        `gorm:"not null" json:"latitude"`
-	Longitude         float64        `gorm:"not null" json:"longitude"`
-	SubmittedAt       JSONTime       `gorm:"not null" json:"submittedAt"`
-	CreatedAt         time.Time      `gorm:"autoCreateTime" json:"createdAt"`
-	UpdatedAt         time.Time      `gorm:"autoUpdateTime" json:"updatedAt"`
-	DeletedAt         gorm.DeletedAt `gorm:"index" json:"-"`
+	ID                     uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	SiteName               string         `gorm:"not null" json:"siteName" validate:"required"`
+	SiteID                 *uuid.UUID     `gorm:"type:uuid;index" json:"siteId,omitempty"`
+	ContractorName         string         `gorm:"not null" json:"contractorName" validate:"required"`
+	ContractorID           *uuid.UUID     `gorm:"type:uuid;index" json:"contractorId,omitempty"`
+	ContractorPhone        string         `gorm:"not null" json:"contractorPhone"`
+	ChainageFrom           string         `gorm:"not null" json:"chainageFrom"`
+	ChainageTo             string         `gorm:"not null" json:"chainageTo"`
+	ActualMeters           *Quantity      `gorm:"type:numeric" json:"actualMeters" validate:"required,gte=0"`
+	DieselTaken            string         `gorm:"not null" json:"dieselTaken" validate:"omitempty,numeric"`
+	VehicleType            string         `json:"vehicleType"`
+	WoringHours            string         `json:"woringHours" validate:"omitempty,numeric"`
+	MeterPhotos            pq.StringArray `gorm:"type:text[]" json:"meterPhotos" swaggertype:"array,string"`
+	CardNumber             string         `gorm:"not null" json:"cardNumber"`
+	FuelCardID             *uuid.UUID     `gorm:"type:uuid;index" json:"fuelCardId,omitempty"`
+	AreaPhotos             pq.StringArray `gorm:"type:text[]" json:"areaPhotos" swaggertype:"array,string"`
+	SiteEngineerName       string         `gorm:"not null" json:"siteEngineerName"`
+	SiteEngineerPhone      string         `gorm:"not null" json:"siteEngineerPhone"`
+	Latitude               float64        `gorm:"not null" json:"latitude" validate:"latitude"`
+	Longitude              float64        `gorm:"not null" json:"longitude" validate:"longitude"`
+	GeofenceStatus         string         `gorm:"size:10;index" json:"geofenceStatus,omitempty"`
+	GeofenceDistanceMeters *float64       `json:"geofenceDistanceMeters,omitempty"`
+	SubmittedAt            JSONTime       `gorm:"not null" json:"submittedAt" validate:"required,notfuture"`
+	CreatedAt              time.Time      `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt              time.Time      `gorm:"autoUpdateTime" json:"updatedAt"`
+	DeletedAt              gorm.DeletedAt `gorm:"index" json:"-"`
 }
+
+// SiteField implements SiteScoped
+func (Contractor) SiteField() string { return "SiteName" }
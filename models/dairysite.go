@@ -3,20 +3,27 @@ package models
 import (
 	"time"
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
 type DairySite struct {
-	ID                string   `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
-	NameOfSite        string   `json:"nameOfSite"`
-	TodaysWork        string   `json:"todaysWork"`
-	SiteEngineerName  string   `json:"siteEngineerName"`
-	SiteEngineerPhone string   `json:"siteEngineerPhone"`
-	Latitude          float64  `json:"latitude"`
-	Longitude         float64  `json:"longitude"`
-	SubmittedAt       JSONTime `json:"submittedAt"`
+	ID                     string     `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
+	NameOfSite             string     `json:"nameOfSite" validate:"required"`
+	SiteID                 *uuid.UUID `gorm:"type:uuid;index" json:"siteId,omitempty"`
+	TodaysWork             string     `json:"todaysWork" validate:"required"`
+	SiteEngineerName       string     `json:"siteEngineerName"`
+	SiteEngineerPhone      string     `json:"siteEngineerPhone"`
+	Latitude               float64    `json:"latitude" validate:"latitude"`
+	Longitude              float64    `json:"longitude" validate:"longitude"`
+	GeofenceStatus         string     `gorm:"size:10;index" json:"geofenceStatus,omitempty"`
+	GeofenceDistanceMeters *float64   `json:"geofenceDistanceMeters,omitempty"`
+	SubmittedAt            JSONTime   `json:"submittedAt" validate:"required,notfuture"`
 
 	CreatedAt time.Time      `json:"-"`
 	UpdatedAt time.Time      `json:"-"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 }
+
+// SiteField implements SiteScoped
+func (DairySite) SiteField() string { return "NameOfSite" }
@@ -10,25 +10,34 @@ import (
 
 // Diesel represents a DPR diesel‐usage entry.
 type Diesel struct {
-	ID                 uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
-	NameOfSite         string         `gorm:"not null" json:"nameOfSite"`
-	ToWhom             string         `gorm:"not null" json:"toWhom"`
-	Item               string         `gorm:"not null" json:"item"`
-	CardNumber         string         `gorm:"not null" json:"cardNumber"`
-	VehicleNumber      string         `gorm:"not null" json:"vehicleNumber"`
-	QuantityInLiters   string         `gorm:"not null" json:"quantityInLiters"`
-	AmountPaid         string         `gorm:"not null" json:"amountPaid"`
-	ContractorName     string         `gorm:"not null" json:"contractorName"`
-	ContractorPhone    string         `gorm:"not null" json:"contractorPhone"`
-	MeterReadingPhotos pq.StringArray `gorm:"type:text[]" json:"meterReadingPhotos"`
-	BillPhotos         pq.StringArray `gorm:"type:text[]" json:"billPhotos"`
-	PersonFilled       string         `json:"personFilled,omitempty"`
-	PersonPhone        string         `json:"personPhone,omitempty"`
-	Remarks            *string        `json:"remarks,omitempty"`
-	Latitude           float64        `gorm:"not null" json:"latitude"`
-	Longitude          float64        `gorm:"not null" json:"longitude"`
-	SubmittedAt        JSONTime       `gorm:"not null" json:"submittedAt"`
-	CreatedAt          time.Time      `gorm:"autoCreateTime" json:"createdAt"`
-	UpdatedAt          time.Time      `gorm:"autoUpdateTime" json:"updatedAt"`
-	DeletedAt          gorm.DeletedAt `gorm:"index" json:"-"`
+	ID                     uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	NameOfSite             string         `gorm:"not null" json:"nameOfSite" validate:"required"`
+	SiteID                 *uuid.UUID     `gorm:"type:uuid;index" json:"siteId,omitempty"`
+	ToWhom                 string         `gorm:"not null" json:"toWhom"`
+	Item                   string         `gorm:"not null" json:"item"`
+	CardNumber             string         `gorm:"not null" json:"cardNumber" validate:"required"`
+	FuelCardID             *uuid.UUID     `gorm:"type:uuid;index" json:"fuelCardId,omitempty"`
+	VehicleNumber          string         `gorm:"not null" json:"vehicleNumber" validate:"required"`
+	VehicleID              *uuid.UUID     `gorm:"type:uuid;index" json:"vehicleId,omitempty"`
+	QuantityInLiters       *Quantity      `gorm:"type:numeric" json:"quantityInLiters" validate:"required,gt=0"`
+	AmountPaid             string         `gorm:"not null" json:"amountPaid"`
+	ContractorName         string         `gorm:"not null" json:"contractorName"`
+	ContractorID           *uuid.UUID     `gorm:"type:uuid;index" json:"contractorId,omitempty"`
+	ContractorPhone        string         `gorm:"not null" json:"contractorPhone"`
+	MeterReadingPhotos     pq.StringArray `gorm:"type:text[]" json:"meterReadingPhotos"`
+	BillPhotos             pq.StringArray `gorm:"type:text[]" json:"billPhotos"`
+	PersonFilled           string         `json:"personFilled,omitempty"`
+	PersonPhone            string         `json:"personPhone,omitempty"`
+	Remarks                *string        `json:"remarks,omitempty"`
+	Latitude               float64        `gorm:"not null" json:"latitude" validate:"latitude"`
+	Longitude              float64        `gorm:"not null" json:"longitude" validate:"longitude"`
+	GeofenceStatus         string         `gorm:"size:10;index" json:"geofenceStatus,omitempty"`
+	GeofenceDistanceMeters *float64       `json:"geofenceDistanceMeters,omitempty"`
+	SubmittedAt            JSONTime       `gorm:"not null" json:"submittedAt" validate:"required,notfuture"`
+	CreatedAt              time.Time      `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt              time.Time      `gorm:"autoUpdateTime" json:"updatedAt"`
+	DeletedAt              gorm.DeletedAt `gorm:"index" json:"-"`
 }
+
+// SiteField implements SiteScoped
+func (Diesel) SiteField() string { return "NameOfSite" }
@@ -10,15 +10,16 @@ import (
 // DprSite represents a DPR Site form submission.
 type DprSite struct {
 	ID                                    uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
-	NameOfSite                            string         `gorm:"not null" json:"nameOfSite"`
+	NameOfSite                            string         `gorm:"not null" json:"nameOfSite" validate:"required"`
+	SiteID                                *uuid.UUID     `gorm:"type:uuid;index" json:"siteId,omitempty"`
 	LabelNumber                           string         `gorm:"not null" json:"labelNumber"`
 	ClassOfPipes                          string         `gorm:"not null" json:"classOfPipes"`
 	MaterialOfPipe                        string         `gorm:"not null" json:"materialOfPipe"`
 	PipeDia                               string         `gorm:"not null" json:"pipeDia"`
-	TypeOfWorks                           string         `gorm:"not null" json:"typeOfWorks"`
+	TypeOfWorks                           string         `gorm:"not null" json:"typeOfWorks" validate:"required"`
 	ChainageFrom                          string         `gorm:"not null" json:"chainageFrom"`
 	ChainageTo                            string         `gorm:"not null" json:"chainageTo"`
-	ActualMetersLaidOnDay                 string         `gorm:"not null" json:"actualMetersLaidOnDay"`
+	ActualMetersLaidOnDay                 *Quantity      `gorm:"type:numeric" json:"actualMetersLaidOnDay" validate:"required,gte=0"`
 	Width                                 string         `gorm:"not null" json:"width"`
 	LineType                              string         `gorm:"not null" json:"lineType"`
 	UploadWorkingSitePhoto                string         `gorm:"not null" json:"uploadWorkingSitePhoto"`
@@ -28,18 +29,22 @@ type DprSite struct {
 	DieselIssuedInLitres                  string         `gorm:"not null" json:"dieselIssuedInLitres"`
 	AmountInRs                            string         `gorm:"not null" json:"amountInRs"`
 	CardNumber                            string         `gorm:"not null" json:"cardNumber"`
+	FuelCardID                            *uuid.UUID     `gorm:"type:uuid;index" json:"fuelCardId,omitempty"`
 	UploadTheDieselBillPhoto              string         `gorm:"not null" json:"uploadTheDieselBillPhoto"`
 	Remarks                               *string        `json:"remarks,omitempty"`
-	NameOfContractor                      string         `gorm:"not null" json:"nameOfContractor"`
+	NameOfContractor                      string         `gorm:"not null" json:"nameOfContractor" validate:"required"`
+	ContractorID                          *uuid.UUID     `gorm:"type:uuid;index" json:"contractorId,omitempty"`
 	PhoneNumberOfContractor               string         `gorm:"not null" json:"phoneNumberOfContractor"`
 	NameOfSiteEngineer                    string         `gorm:"not null" json:"nameOfSiteEngineer"`
 	PhoneNumberOfSiteEngineer             string         `gorm:"not null" json:"phoneNumberOfSiteEngineer"`
 	NameOfSupervisor                      *string        `json:"nameOfSupervisor,omitempty"`
 	InformationEnteredBy                  string         `gorm:"not null" json:"informationEnteredBy"`
 	PhoneNumberOfInformationEnteredPerson string         `json:"phoneNumberOfInformationEnteredPerson,omitempty"`
-	Latitude                              float64        `gorm:"not null" json:"latitude"`
-	Longitude                             float64        `gorm:"not null" json:"longitude"`
-	SubmittedAt                           JSONTime       `gorm:"not null" json:"submittedAt"`
+	Latitude                              float64        `gorm:"not null" json:"latitude" validate:"latitude"`
+	Longitude                             float64        `gorm:"not null" json:"longitude" validate:"longitude"`
+	GeofenceStatus                        string         `gorm:"size:10;index" json:"geofenceStatus,omitempty"`
+	GeofenceDistanceMeters                *float64       `json:"geofenceDistanceMeters,omitempty"`
+	SubmittedAt                           JSONTime       `gorm:"not null" json:"submittedAt" validate:"required,notfuture"`
 	CreatedAt                             time.Time      `gorm:"autoCreateTime" json:"createdAt"`
 	UpdatedAt                             time.Time      `gorm:"autoUpdateTime" json:"updatedAt"`
 	DeletedAt                             gorm.DeletedAt `gorm:"index" json:"-"`
@@ -48,3 +53,6 @@ type DprSite struct {
 func (d DprSite) TableName() string {
 	return "dpr_sites"
 }
+
+// SiteField implements SiteScoped
+func (DprSite) SiteField() string { return "NameOfSite" }
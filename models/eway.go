@@ -9,35 +9,36 @@ import (
 
 // Eway represents a submitted E-Way Bill form.
 type Eway struct {
-	ID                     uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
-	BillNo                 string    `gorm:"not null" json:"billNo"`
-	GeneratedDate          JSONTime  `gorm:"not null" json:"generatedDate"`
-	GeneratedBy            string    `gorm:"not null" json:"generatedBy"`
-	ValidUpto              *JSONTime `json:"validUpto,omitempty"`
-	Mode                   *string   `json:"mode,omitempty"`
-	Type                   *string   `json:"type,omitempty"`
-	DocumentDetails        *string   `json:"documentDetails,omitempty"`
-	DispatchFrom           string    `gorm:"not null" json:"dispatchFrom"`
-	DispatchPincode        string    `gorm:"not null" json:"dispatchPincode"`
-	ShipToAddress          *string   `json:"shipToAddress,omitempty"`
-	ShipToPincode          string    `gorm:"not null" json:"shipToPincode"`
-	ProductName            string    `gorm:"not null" json:"productName"`
-	SpecialItemDescription *string   `json:"specialItemDescription,omitempty"`
-	PipeDia                *string   `json:"pipeDia,omitempty"`
-	UOM                    *string   `json:"uom,omitempty"`
-	Quantity               string    `gorm:"not null" json:"quantity"`
-	HSNCode                *string   `json:"hsnCode,omitempty"`
-	TaxableAmount          *string   `json:"taxableAmount,omitempty"`
-	TransporterIDName      *string   `json:"transporterIdName,omitempty"`
-	TransporterDocNo       *string   `json:"transporterDocNo,omitempty"`
-	DocumentDate           *JSONTime `json:"documentDate,omitempty"`
-	VehicleNo              *string   `json:"vehicleNo,omitempty"`
-	EnteredBy              string    `gorm:"not null" json:"enteredBy"`
-	EnteredDate            JSONTime  `gorm:"not null" json:"enteredDate"`
-	Remarks                *string   `json:"remarks,omitempty"`
-	Latitude               float64   `gorm:"not null" json:"latitude"`
-	Longitude              float64   `gorm:"not null" json:"longitude"`
-	SubmittedAt            JSONTime  `gorm:"not null" json:"submittedAt"`
+	ID                     uuid.UUID  `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	BillNo                 string     `gorm:"not null" json:"billNo" validate:"required"`
+	GeneratedDate          JSONTime   `gorm:"not null" json:"generatedDate"`
+	GeneratedBy            string     `gorm:"not null" json:"generatedBy"`
+	ValidUpto              *JSONTime  `json:"validUpto,omitempty"`
+	Mode                   *string    `json:"mode,omitempty"`
+	Type                   *string    `json:"type,omitempty"`
+	DocumentDetails        *string    `json:"documentDetails,omitempty"`
+	DispatchFrom           string     `gorm:"not null" json:"dispatchFrom"`
+	DispatchPincode        string     `gorm:"not null" json:"dispatchPincode" validate:"omitempty,numeric,len=6"`
+	ShipToAddress          *string    `json:"shipToAddress,omitempty"`
+	ShipToPincode          string     `gorm:"not null" json:"shipToPincode" validate:"omitempty,numeric,len=6"`
+	ProductName            string     `gorm:"not null" json:"productName" validate:"required"`
+	SpecialItemDescription *string    `json:"specialItemDescription,omitempty"`
+	PipeDia                *string    `json:"pipeDia,omitempty"`
+	UOM                    *string    `json:"uom,omitempty"`
+	Quantity               string     `gorm:"not null" json:"quantity"`
+	HSNCode                *string    `json:"hsnCode,omitempty"`
+	TaxableAmount          *string    `json:"taxableAmount,omitempty"`
+	TransporterIDName      *string    `json:"transporterIdName,omitempty"`
+	TransporterDocNo       *string    `json:"transporterDocNo,omitempty"`
+	DocumentDate           *JSONTime  `json:"documentDate,omitempty"`
+	VehicleNo              *string    `json:"vehicleNo,omitempty"`
+	VehicleID              *uuid.UUID `gorm:"type:uuid;index" json:"vehicleId,omitempty"`
+	EnteredBy              string     `gorm:"not null" json:"enteredBy"`
+	EnteredDate            JSONTime   `gorm:"not null" json:"enteredDate"`
+	Remarks                *string    `json:"remarks,omitempty"`
+	Latitude               float64    `gorm:"not null" json:"latitude" validate:"latitude"`
+	Longitude              float64    `gorm:"not null" json:"longitude" validate:"longitude"`
+	SubmittedAt            JSONTime   `gorm:"not null" json:"submittedAt" validate:"required,notfuture"`
 
 	CreatedAt time.Time      `gorm:"autoCreateTime" json:"createdAt"`
 	UpdatedAt time.Time      `gorm:"autoUpdateTime" json:"updatedAt"`
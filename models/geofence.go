@@ -0,0 +1,383 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Geofence outcomes stored in a record's GeofenceStatus. Records saved
+// before geofencing have none.
+const (
+	GeofenceInside  = "inside"
+	GeofenceOutside = "outside"
+	// GeofenceUnknown covers records without a position and sites that are
+	// unknown or have no fence
+	GeofenceUnknown = "unknown"
+)
+
+// Geofence modes, set per module by a GeofencePolicy
+const (
+	// GeofenceFlag saves outside submissions; they show in the outside
+	// fence report
+	GeofenceFlag = "flag"
+	// GeofenceReject refuses outside submissions with a validation error
+	GeofenceReject = "reject"
+)
+
+// GeofencePolicy is the geofence mode of one module. Modules without one
+// flag.
+type GeofencePolicy struct {
+	Module    string    `gorm:"size:50;primaryKey" json:"module"`
+	Mode      string    `gorm:"size:10;not null" json:"mode" validate:"oneof=flag reject"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// GeoPolygon is a site boundary as a ring of [longitude, latitude] points,
+// the order GeoJSON uses. The ring need not repeat its first point.
+type GeoPolygon [][2]float64
+
+// Value implements driver.Valuer
+func (p GeoPolygon) Value() (driver.Value, error) {
+	if len(p) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(p)
+}
+
+// Scan implements sql.Scanner
+func (p *GeoPolygon) Scan(value interface{}) error {
+	if value == nil {
+		*p = nil
+		return nil
+	}
+	return json.Unmarshal(toBytes(value), p)
+}
+
+// GeofencedModule reports whether records of model store a geofence outcome
+func GeofencedModule(model interface{}) bool {
+	_, ok := reflect.Indirect(reflect.ValueOf(model)).Type().FieldByName("GeofenceStatus")
+	return ok
+}
+
+// Fence places p against the site's geofence: its boundary when it has one,
+// otherwise the circle of RadiusMeters around its centre. The distance is
+// how many metres p lies outside the fence, 0 inside. Sites without a fence
+// give GeofenceUnknown.
+func (s Site) Fence(p GeoPoint) (string, float64) {
+	if len(s.Boundary) >= 3 {
+		if distance := polygonDistance(s.Boundary, p); distance > 0 {
+			return GeofenceOutside, distance
+		}
+		return GeofenceInside, 0
+	}
+	if s.Latitude == nil || s.Longitude == nil || s.RadiusMeters == nil {
+		return GeofenceUnknown, 0
+	}
+	distance := DistanceKm(GeoPoint{Lat: *s.Latitude, Lng: *s.Longitude}, p)*1000 - *s.RadiusMeters
+	if distance > 0 {
+		return GeofenceOutside, distance
+	}
+	return GeofenceInside, 0
+}
+
+// polygonDistance returns how far p lies outside ring in metres, or 0 when
+// it is inside. Sites are small enough to treat as flat, so the ring is
+// projected onto metres around p.
+func polygonDistance(ring GeoPolygon, p GeoPoint) float64 {
+	scale := kmPerDegree * 1000
+	cos := math.Cos(radians(p.Lat))
+	pts := make([][2]float64, len(ring))
+	for i, c := range ring {
+		pts[i] = [2]float64{(c[0] - p.Lng) * scale * cos, (c[1] - p.Lat) * scale}
+	}
+
+	inside := false
+	nearest := math.Inf(1)
+	for i := range pts {
+		a, b := pts[i], pts[(i+1)%len(pts)]
+		// Ray cast from p, now the origin, along the positive x axis
+		if (a[1] > 0) != (b[1] > 0) && a[0]+(0-a[1])*(b[0]-a[0])/(b[1]-a[1]) > 0 {
+			inside = !inside
+		}
+		nearest = math.Min(nearest, segmentDistance(a, b))
+	}
+	if inside {
+		return 0
+	}
+	return nearest
+}
+
+// segmentDistance is the distance from the origin to the segment ab
+func segmentDistance(a, b [2]float64) float64 {
+	dx, dy := b[0]-a[0], b[1]-a[1]
+	t := 0.0
+	if l := dx*dx + dy*dy; l > 0 {
+		t = math.Max(0, math.Min(1, -(a[0]*dx+a[1]*dy)/l))
+	}
+	return math.Hypot(a[0]+t*dx, a[1]+t*dy)
+}
+
+// ApplyGeofence compares the position of a record with its site's geofence
+// and stores the outcome in its GeofenceStatus and GeofenceDistanceMeters,
+// overwriting whatever the client sent. Records of models without those
+// fields are left alone. It returns the outcome and the site it matched.
+func ApplyGeofence(db *gorm.DB, record interface{}) (string, *Site, error) {
+	v := reflect.Indirect(reflect.ValueOf(record))
+	status := v.FieldByName("GeofenceStatus")
+	if !status.IsValid() {
+		return "", nil, nil
+	}
+	distanceField := v.FieldByName("GeofenceDistanceMeters")
+
+	result, distance := GeofenceUnknown, 0.0
+	lat, _ := v.FieldByName("Latitude").Interface().(float64)
+	lng, _ := v.FieldByName("Longitude").Interface().(float64)
+	site, err := findRecordSite(db, record)
+	if err != nil {
+		return "", nil, err
+	}
+	if site != nil && (lat != 0 || lng != 0) {
+		result, distance = site.Fence(GeoPoint{Lat: lat, Lng: lng})
+	}
+
+	status.SetString(result)
+	if result == GeofenceUnknown {
+		distanceField.Set(reflect.Zero(distanceField.Type()))
+	} else {
+		rounded := math.Round(distance)
+		distanceField.Set(reflect.ValueOf(&rounded))
+	}
+	return result, site, nil
+}
+
+// EnforceGeofence is ApplyGeofence for a new submission. Outside
+// submissions to a module whose policy rejects them come back as a
+// ValidationError.
+func EnforceGeofence(db *gorm.DB, record interface{}) error {
+	result, site, err := ApplyGeofence(db, record)
+	if err != nil || result != GeofenceOutside {
+		return err
+	}
+	mode, err := GeofenceMode(db, AuditModule(record))
+	if err != nil || mode != GeofenceReject {
+		return err
+	}
+	distance := reflect.Indirect(reflect.ValueOf(record)).FieldByName("GeofenceDistanceMeters").Interface().(*float64)
+	return ValidationError{{
+		Field:   LatitudeField,
+		Code:    "geofence",
+		Message: fmt.Sprintf("is %.0f m outside the geofence of site %q", *distance, site.Name),
+	}}
+}
+
+// GeofenceMode returns the geofence mode of module
+func GeofenceMode(db *gorm.DB, module string) (string, error) {
+	var policy GeofencePolicy
+	err := db.First(&policy, "module = ?", module).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return GeofenceFlag, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return policy.Mode, nil
+}
+
+// findRecordSite loads the site a record belongs to: the linked master site
+// when it has one, otherwise the site named by its site field, matching
+// aliases too. It returns nil when there is none.
+func findRecordSite(db *gorm.DB, record interface{}) (*Site, error) {
+	var site Site
+	if f := reflect.Indirect(reflect.ValueOf(record)).FieldByName("SiteID"); f.IsValid() {
+		if id, ok := f.Interface().(*uuid.UUID); ok && id != nil {
+			err := db.First(&site, "id = ?", *id).Error
+			if err == nil {
+				return &site, nil
+			}
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, err
+			}
+		}
+	}
+	name, _, ok := RecordSite(record)
+	name = NormalizeSiteName(name)
+	if !ok || name == "" {
+		return nil, nil
+	}
+	err := db.Where("LOWER(TRIM(name)) = ? OR EXISTS (SELECT 1 FROM UNNEST(aliases) AS a WHERE LOWER(TRIM(a)) = ?)", name, name).
+		Order("name").First(&site).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &site, nil
+}
+
+// OutsideFenceEngineer groups the outside fence submissions of one
+// engineer, who is known by the phone number or name their forms carry
+type OutsideFenceEngineer struct {
+	Engineer          string                   `json:"engineer"`
+	Name              string                   `json:"name,omitempty"`
+	Total             int                      `json:"total"`
+	Modules           map[string]int           `json:"modules"`
+	MaxDistanceMeters float64                  `json:"maxDistanceMeters"`
+	LastAt            time.Time                `json:"lastAt"`
+	Submissions       []OutsideFenceSubmission `json:"submissions"`
+}
+
+// OutsideFenceSubmission is one record saved from outside its site's fence
+type OutsideFenceSubmission struct {
+	Module         string    `json:"module"`
+	ID             string    `json:"id"`
+	Site           string    `json:"site"`
+	DistanceMeters float64   `json:"distanceMeters"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+// OutsideFenceReport lists the live outside fence submissions created in
+// [from, to) by engineer, most offending first. Zero times leave that end
+// open and module, when set, limits it to one module.
+func OutsideFenceReport(db *gorm.DB, scope SiteScope, module string, from, to time.Time) ([]OutsideFenceEngineer, error) {
+	byEngineer := map[string]*OutsideFenceEngineer{}
+	var phones []string
+	found := false
+	for _, m := range SyncModules {
+		if !GeofencedModule(m.Model) || (module != "" && m.Name != module) {
+			continue
+		}
+		found = true
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(m.Model); err != nil {
+			return nil, err
+		}
+		owner := stmt.Schema.LookUpField(m.OwnerField)
+		site := stmt.Schema.LookUpField(m.Model.(SiteScoped).SiteField())
+
+		query := db.Model(m.Model).
+			Select("id, "+owner.DBName+" AS engineer, "+site.DBName+" AS site, geofence_distance_meters AS distance_meters, created_at").
+			Where("geofence_status = ?", GeofenceOutside)
+		if !from.IsZero() {
+			query = query.Where("created_at >= ?", from)
+		}
+		if !to.IsZero() {
+			query = query.Where("created_at < ?", to)
+		}
+		cond, err := siteCondition(db, m.Model, scope)
+		if err != nil {
+			return nil, err
+		}
+		if cond != nil {
+			query = query.Where(cond.SQL, cond.Args...)
+		}
+		var rows []struct {
+			ID             string
+			Engineer       string
+			Site           string
+			DistanceMeters *float64
+			CreatedAt      time.Time
+		}
+		if err := query.Order("created_at").Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+
+		for _, row := range rows {
+			key := strings.TrimSpace(row.Engineer)
+			e, ok := byEngineer[key]
+			if !ok {
+				e = &OutsideFenceEngineer{Engineer: key, Modules: map[string]int{}}
+				if m.OwnerByName {
+					e.Name = key
+				} else {
+					phones = append(phones, key)
+				}
+				byEngineer[key] = e
+			}
+			sub := OutsideFenceSubmission{Module: m.Name, ID: row.ID, Site: row.Site, CreatedAt: row.CreatedAt}
+			if row.DistanceMeters != nil {
+				sub.DistanceMeters = *row.DistanceMeters
+			}
+			e.Total++
+			e.Modules[m.Name]++
+			e.MaxDistanceMeters = math.Max(e.MaxDistanceMeters, sub.DistanceMeters)
+			if row.CreatedAt.After(e.LastAt) {
+				e.LastAt = row.CreatedAt
+			}
+			e.Submissions = append(e.Submissions, sub)
+		}
+	}
+	if !found {
+		return nil, &ParamError{Param: "module", Message: fmt.Sprintf("%q has no geofence", module)}
+	}
+
+	if len(phones) > 0 {
+		var users []User
+		if err := db.Select("name, phone").Where("phone IN ?", phones).Find(&users).Error; err != nil {
+			return nil, err
+		}
+		for _, u := range users {
+			if e, ok := byEngineer[u.Phone]; ok && e.Name == "" {
+				e.Name = u.Name
+			}
+		}
+	}
+
+	out := make([]OutsideFenceEngineer, 0, len(byEngineer))
+	for _, e := range byEngineer {
+		out = append(out, *e)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Total != out[j].Total {
+			return out[i].Total > out[j].Total
+		}
+		return out[i].Engineer < out[j].Engineer
+	})
+	return out, nil
+}
+
+// GeofencePolicies returns the policy of every geofenced module, in
+// SyncModules order, with the default for modules that have none stored
+func GeofencePolicies(db *gorm.DB) ([]GeofencePolicy, error) {
+	var stored []GeofencePolicy
+	if err := db.Find(&stored).Error; err != nil {
+		return nil, err
+	}
+	byModule := map[string]GeofencePolicy{}
+	for _, p := range stored {
+		byModule[p.Module] = p
+	}
+	var out []GeofencePolicy
+	for _, m := range SyncModules {
+		if !GeofencedModule(m.Model) {
+			continue
+		}
+		p, ok := byModule[m.Name]
+		if !ok {
+			p = GeofencePolicy{Module: m.Name, Mode: GeofenceFlag}
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+// FindGeofencedModule looks up a module whose records store a geofence
+// outcome
+func FindGeofencedModule(name string) (SyncModule, bool) {
+	for _, m := range SyncModules {
+		if m.Name == name && GeofencedModule(m.Model) {
+			return m, true
+		}
+	}
+	return SyncModule{}, false
+}
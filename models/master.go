@@ -0,0 +1,429 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+// Master data kinds, named as in their routes
+const (
+	MasterSites       = "sites"
+	MasterContractors = "contractors"
+	MasterVehicles    = "vehicles"
+	MasterFuelCards   = "fuel-cards"
+	MasterYards       = "yards"
+)
+
+// MasterContractor is the registry entry of a contractor firm
+type MasterContractor struct {
+	ID        uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	Name      string         `gorm:"size:200;not null;uniqueIndex" json:"name" validate:"required,max=200"`
+	Phone     string         `gorm:"size:20" json:"phone" validate:"omitempty,max=20"`
+	Aliases   pq.StringArray `gorm:"type:text[]" json:"aliases" validate:"dive,max=200" swaggertype:"array,string"`
+	CreatedAt time.Time      `json:"createdAt"`
+	UpdatedAt time.Time      `json:"updatedAt"`
+}
+
+// Vehicle is a registered vehicle or machine. Number is stored as
+// normalized by NormalizeMasterNumber.
+type Vehicle struct {
+	ID           uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	Number       string         `gorm:"size:50;not null;uniqueIndex" json:"number" validate:"required,max=50"`
+	VehicleType  string         `gorm:"size:100" json:"vehicleType" validate:"max=100"`
+	ContractorID *uuid.UUID     `gorm:"type:uuid;index" json:"contractorId,omitempty"`
+	Aliases      pq.StringArray `gorm:"type:text[]" json:"aliases" validate:"dive,max=50" swaggertype:"array,string"`
+	CreatedAt    time.Time      `json:"createdAt"`
+	UpdatedAt    time.Time      `json:"updatedAt"`
+}
+
+// FuelCard is a diesel card. Number is stored as normalized by
+// NormalizeMasterNumber.
+type FuelCard struct {
+	ID        uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	Number    string         `gorm:"size:50;not null;uniqueIndex" json:"number" validate:"required,max=50"`
+	Provider  string         `gorm:"size:100" json:"provider" validate:"max=100"`
+	VehicleID *uuid.UUID     `gorm:"type:uuid;index" json:"vehicleId,omitempty"`
+	SiteID    *uuid.UUID     `gorm:"type:uuid;index" json:"siteId,omitempty"`
+	Aliases   pq.StringArray `gorm:"type:text[]" json:"aliases" validate:"dive,max=50" swaggertype:"array,string"`
+	CreatedAt time.Time      `json:"createdAt"`
+	UpdatedAt time.Time      `json:"updatedAt"`
+}
+
+// Yard is a pipe storage or coating yard
+type Yard struct {
+	ID        uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	Name      string         `gorm:"size:200;not null;uniqueIndex" json:"name" validate:"required,max=200"`
+	SiteID    *uuid.UUID     `gorm:"type:uuid;index" json:"siteId,omitempty"`
+	Latitude  *float64       `json:"latitude,omitempty" validate:"omitempty,latitude"`
+	Longitude *float64       `json:"longitude,omitempty" validate:"omitempty,longitude"`
+	Aliases   pq.StringArray `gorm:"type:text[]" json:"aliases" validate:"dive,max=200" swaggertype:"array,string"`
+	CreatedAt time.Time      `json:"createdAt"`
+	UpdatedAt time.Time      `json:"updatedAt"`
+}
+
+// MasterTable describes one master data table
+type MasterTable struct {
+	Kind  string
+	Model interface{}
+	// KeyField is the Go field holding the unique name or number
+	KeyField string
+	// Numbered tables hold registration or card numbers, which are compared
+	// without spaces or punctuation rather than as names
+	Numbered bool
+}
+
+// MasterTables lists every master data table
+var MasterTables = []MasterTable{
+	{Kind: MasterSites, Model: Site{}, KeyField: "Name"},
+	{Kind: MasterContractors, Model: MasterContractor{}, KeyField: "Name"},
+	{Kind: MasterVehicles, Model: Vehicle{}, KeyField: "Number", Numbered: true},
+	{Kind: MasterFuelCards, Model: FuelCard{}, KeyField: "Number", Numbered: true},
+	{Kind: MasterYards, Model: Yard{}, KeyField: "Name"},
+}
+
+// FindMasterTable looks up a master table by kind
+func FindMasterTable(kind string) (MasterTable, bool) {
+	for _, t := range MasterTables {
+		if t.Kind == kind {
+			return t, true
+		}
+	}
+	return MasterTable{}, false
+}
+
+// Normalize is the form keys of this table are compared in
+func (t MasterTable) Normalize(s string) string {
+	if t.Numbered {
+		return NormalizeMasterNumber(s)
+	}
+	return NormalizeMasterName(s)
+}
+
+// MasterLink is a free-text column of a record model and the optional
+// foreign key that points it at a master record
+type MasterLink struct {
+	Kind      string
+	Model     interface{}
+	TextField string
+	IDField   string
+}
+
+// MasterLinks lists every free-text column that has a master foreign key
+var MasterLinks = []MasterLink{
+	{MasterSites, Contractor{}, "SiteName", "SiteID"},
+	{MasterContractors, Contractor{}, "ContractorName", "ContractorID"},
+	{MasterFuelCards, Contractor{}, "CardNumber", "FuelCardID"},
+	{MasterSites, DairySite{}, "NameOfSite", "SiteID"},
+	{MasterSites, Diesel{}, "NameOfSite", "SiteID"},
+	{MasterFuelCards, Diesel{}, "CardNumber", "FuelCardID"},
+	{MasterVehicles, Diesel{}, "VehicleNumber", "VehicleID"},
+	{MasterContractors, Diesel{}, "ContractorName", "ContractorID"},
+	{MasterSites, DprSite{}, "NameOfSite", "SiteID"},
+	{MasterFuelCards, DprSite{}, "CardNumber", "FuelCardID"},
+	{MasterContractors, DprSite{}, "NameOfContractor", "ContractorID"},
+	{MasterVehicles, Eway{}, "VehicleNo", "VehicleID"},
+	{MasterSites, Material{}, "NameOfSite", "SiteID"},
+	{MasterSites, Mnr{}, "NameOfSite", "SiteID"},
+	{MasterContractors, Mnr{}, "ContractorName", "ContractorID"},
+	{MasterSites, Nmr_Vehicle{}, "NameOfSite", "SiteID"},
+	{MasterContractors, Nmr_Vehicle{}, "ContractorName", "ContractorID"},
+	{MasterYards, Painting{}, "NameOfYard", "YardID"},
+	{MasterContractors, Painting{}, "ContractorName", "ContractorID"},
+	{MasterSites, Payment{}, "NameOfSite", "SiteID"},
+	{MasterYards, Stock{}, "YardName", "YardID"},
+	{MasterContractors, Stock{}, "ContractorName", "ContractorID"},
+	{MasterVehicles, Stock{}, "VehicleNumber", "VehicleID"},
+	{MasterSites, VehicleLog{}, "SiteLocation", "SiteID"},
+	{MasterSites, Water{}, "SiteName", "SiteID"},
+	{MasterVehicles, Water{}, "TankerVehicleNumber", "VehicleID"},
+	{MasterYards, Wrapping{}, "YardName", "YardID"},
+	{MasterContractors, Wrapping{}, "ContractorName", "ContractorID"},
+}
+
+// masterNameNoise are words that do not tell two names apart
+var masterNameNoise = map[string]bool{
+	"m/s": true, "ms": true, "the": true, "pvt": true, "private": true,
+	"ltd": true, "limited": true, "co": true, "company": true,
+}
+
+// NormalizeMasterName lower-cases a name, drops punctuation and noise words
+// such as "Pvt Ltd" and strips plural endings, so "M/s Raju Constructions"
+// and "raju construction" compare equal
+func NormalizeMasterName(s string) string {
+	s = strings.ToLower(strings.ReplaceAll(s, "&", " and "))
+	words := strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '/'
+	})
+	out := words[:0]
+	for _, w := range words {
+		w = strings.Trim(w, "/")
+		if w == "" || masterNameNoise[w] {
+			continue
+		}
+		if len(w) > 3 && strings.HasSuffix(w, "s") && !strings.HasSuffix(w, "ss") {
+			w = strings.TrimSuffix(w, "s")
+		}
+		out = append(out, w)
+	}
+	return strings.Join(out, " ")
+}
+
+// NormalizeMasterNumber upper-cases a vehicle or card number and drops
+// everything but letters and digits, so "TS 09 ab-1234" becomes "TS09AB1234"
+func NormalizeMasterNumber(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// masterSimilarity scores two normalized keys from 0 to 1 by edit distance
+func masterSimilarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	ra, rb := []rune(a), []rune(b)
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 0
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// MasterMatch is one distinct free-text value and the master record it
+// matches best
+type MasterMatch struct {
+	Table     string     `json:"table"`
+	Column    string     `json:"column"`
+	Value     string     `json:"value"`
+	Rows      int64      `json:"rows"`
+	MasterID  *uuid.UUID `json:"masterId,omitempty"`
+	MasterKey string     `json:"masterKey,omitempty"`
+	Score     float64    `json:"score"`
+	// Ambiguous is set when a different master record scores almost as
+	// well; such values are never linked automatically
+	Ambiguous bool `json:"ambiguous,omitempty"`
+	Linked    bool `json:"linked"`
+}
+
+// MasterLinkReport is the outcome of a LinkMasterData run
+type MasterLinkReport struct {
+	Kind      string        `json:"kind"`
+	Threshold float64       `json:"threshold"`
+	Applied   bool          `json:"applied"`
+	Linked    int64         `json:"linked"`
+	Matches   []MasterMatch `json:"matches"`
+	Unmatched []MasterMatch `json:"unmatched"`
+}
+
+// DefaultMasterThreshold is the similarity LinkMasterData links at unless
+// told otherwise
+const DefaultMasterThreshold = 0.85
+
+// masterAmbiguityMargin is how close a second candidate must score for a
+// match to count as ambiguous
+const masterAmbiguityMargin = 0.05
+
+type masterCandidate struct {
+	id  uuid.UUID
+	key string
+	// normalized forms of the key and its aliases
+	forms []string
+}
+
+// LinkMasterData matches the distinct unlinked free-text values of every
+// column linked to kind against the master records and their aliases. With
+// apply set, values scoring at least threshold, and not ambiguous, have
+// their rows' foreign key set. Soft-deleted rows are linked too so that
+// restoring them keeps them consistent.
+func LinkMasterData(db *gorm.DB, kind string, threshold float64, apply bool) (*MasterLinkReport, error) {
+	table, ok := FindMasterTable(kind)
+	if !ok {
+		return nil, &ParamError{Param: "kind", Message: fmt.Sprintf("unknown master data kind %q", kind)}
+	}
+	if threshold <= 0 || threshold > 1 {
+		return nil, &ParamError{Param: "threshold", Message: "must be above 0 and at most 1"}
+	}
+
+	candidates, err := loadMasterCandidates(db, table)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &MasterLinkReport{Kind: kind, Threshold: threshold, Applied: apply,
+		Matches: []MasterMatch{}, Unmatched: []MasterMatch{}}
+	for _, link := range MasterLinks {
+		if link.Kind != kind {
+			continue
+		}
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(link.Model); err != nil {
+			return nil, err
+		}
+		text := stmt.Schema.LookUpField(link.TextField)
+		fk := stmt.Schema.LookUpField(link.IDField)
+		if text == nil || fk == nil {
+			return nil, fmt.Errorf("model %s has no field %s or %s", stmt.Schema.Name, link.TextField, link.IDField)
+		}
+
+		var values []struct {
+			Value    string
+			RowCount int64
+		}
+		err := db.Table(stmt.Schema.Table).
+			Select(text.DBName + " AS value, COUNT(*) AS row_count").
+			Where(fk.DBName + " IS NULL").
+			Where(text.DBName + " IS NOT NULL AND TRIM(" + text.DBName + ") <> ''").
+			Group(text.DBName).
+			Scan(&values).Error
+		if err != nil {
+			return nil, err
+		}
+
+		for _, v := range values {
+			m := bestMasterMatch(table.Normalize(v.Value), candidates)
+			m.Table, m.Column, m.Value, m.Rows = stmt.Schema.Table, text.DBName, v.Value, v.RowCount
+			if m.MasterID == nil || m.Score < threshold || m.Ambiguous {
+				report.Unmatched = append(report.Unmatched, m)
+				continue
+			}
+			if apply {
+				res := db.Table(stmt.Schema.Table).
+					Where(text.DBName+" = ? AND "+fk.DBName+" IS NULL", v.Value).
+					Update(fk.DBName, *m.MasterID)
+				if res.Error != nil {
+					return nil, res.Error
+				}
+				m.Linked = true
+				report.Linked += res.RowsAffected
+			}
+			report.Matches = append(report.Matches, m)
+		}
+	}
+
+	sort.SliceStable(report.Matches, func(i, j int) bool { return report.Matches[i].Rows > report.Matches[j].Rows })
+	sort.SliceStable(report.Unmatched, func(i, j int) bool { return report.Unmatched[i].Rows > report.Unmatched[j].Rows })
+	return report, nil
+}
+
+func loadMasterCandidates(db *gorm.DB, table MasterTable) ([]masterCandidate, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(table.Model); err != nil {
+		return nil, err
+	}
+	key := stmt.Schema.LookUpField(table.KeyField)
+	if key == nil {
+		return nil, fmt.Errorf("model %s has no key field %s", stmt.Schema.Name, table.KeyField)
+	}
+	var rows []struct {
+		ID        uuid.UUID
+		MasterKey string
+		Aliases   pq.StringArray
+	}
+	err := db.Table(stmt.Schema.Table).
+		Select("id, " + key.DBName + " AS master_key, aliases").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	out := make([]masterCandidate, len(rows))
+	for i, r := range rows {
+		c := masterCandidate{id: r.ID, key: r.MasterKey, forms: []string{table.Normalize(r.MasterKey)}}
+		for _, a := range r.Aliases {
+			c.forms = append(c.forms, table.Normalize(a))
+		}
+		out[i] = c
+	}
+	return out, nil
+}
+
+// bestMasterMatch scores value against every candidate. The result is
+// ambiguous when a different candidate comes within masterAmbiguityMargin.
+func bestMasterMatch(value string, candidates []masterCandidate) MasterMatch {
+	var m MasterMatch
+	runnerUp := 0.0
+	for i := range candidates {
+		c := &candidates[i]
+		score := 0.0
+		for _, f := range c.forms {
+			score = max(score, masterSimilarity(value, f))
+		}
+		switch {
+		case score > m.Score:
+			runnerUp = m.Score
+			m.Score, m.MasterID, m.MasterKey = score, &c.id, c.key
+		case score > runnerUp:
+			runnerUp = score
+		}
+	}
+	m.Ambiguous = m.MasterID != nil && m.Score < 1 && m.Score-runnerUp < masterAmbiguityMargin
+	return m
+}
+
+// MasterLabels maps master record IDs, of every kind, to their name or
+// number so reports can group linked rows under one label
+type MasterLabels map[uuid.UUID]string
+
+// LoadMasterLabels reads the keys of every master table
+func LoadMasterLabels(db *gorm.DB) (MasterLabels, error) {
+	labels := MasterLabels{}
+	for _, t := range MasterTables {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(t.Model); err != nil {
+			return nil, err
+		}
+		key := stmt.Schema.LookUpField(t.KeyField)
+		var rows []struct {
+			ID        uuid.UUID
+			MasterKey string
+		}
+		if err := db.Table(stmt.Schema.Table).Select("id, " + key.DBName + " AS master_key").Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+		for _, r := range rows {
+			labels[r.ID] = r.MasterKey
+		}
+	}
+	return labels, nil
+}
+
+// Of returns the master key for id, or the trimmed free text when the row
+// is not linked
+func (l MasterLabels) Of(id *uuid.UUID, raw string) string {
+	if id != nil {
+		if label, ok := l[*id]; ok {
+			return label
+		}
+	}
+	return strings.TrimSpace(raw)
+}
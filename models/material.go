@@ -11,23 +11,31 @@ import (
 // MaterialIndent represents a “Material Indent” form submission.
 type Material struct {
 	ID                     uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
-	NameOfSite             string         `gorm:"not null" json:"nameOfSite"`
+	NameOfSite             string         `gorm:"not null" json:"nameOfSite" validate:"required"`
+	SiteID                 *uuid.UUID     `gorm:"type:uuid;index" json:"siteId,omitempty"`
 	MaterialOrService      datatypes.JSON `gorm:"type:jsonb;not null" json:"materialOrService"` // e.g. ["Material","Service"]
-	Description            string         `gorm:"not null" json:"description"`
-	QtyRequiredNow         string         `gorm:"not null" json:"qtyRequiredNow"`
+	Description            string         `gorm:"not null" json:"description" validate:"required"`
+	QtyRequiredNow         string         `gorm:"not null" json:"qtyRequiredNow" validate:"required"`
 	EstimatedCost          *string        `json:"estimatedCost,omitempty"`
 	QuotationPhotos        datatypes.JSON `gorm:"type:jsonb;not null" json:"quotationPhotos"` // e.g. ["file1.png","file2.jpg"]
 	QtyPresentStock        *string        `json:"qtyPresentStock,omitempty"`
 	ExpectedCompletionDate *JSONTime      `json:"expectedCompletionDate,omitempty"`
 	Priority               *string        `json:"priority,omitempty"` // e.g. "one week"
-	DueDate                JSONTime       `gorm:"not null" json:"dueDate"`
+	DueDate                JSONTime       `gorm:"not null" json:"dueDate" validate:"required"`
 	SiteEngineerName       string         `gorm:"not null" json:"siteEngineerName"`
 	PhoneNumber            string         `gorm:"not null" json:"phoneNumber"`
-	Latitude               float64        `gorm:"not null" json:"latitude"`
-	Longitude              float64        `gorm:"not null" json:"longitude"`
-	SubmittedAt            JSONTime       `gorm:"not null" json:"submittedAt"`
+	Latitude               float64        `gorm:"not null" json:"latitude" validate:"latitude"`
+	Longitude              float64        `gorm:"not null" json:"longitude" validate:"longitude"`
+	GeofenceStatus         string         `gorm:"size:10;index" json:"geofenceStatus,omitempty"`
+	GeofenceDistanceMeters *float64       `json:"geofenceDistanceMeters,omitempty"`
+	SubmittedAt            JSONTime       `gorm:"not null" json:"submittedAt" validate:"required,notfuture"`
+	// Draft keeps a new request out of approval until it is submitted
+	Draft bool `gorm:"-" json:"draft,omitempty"`
 
 	CreatedAt time.Time      `gorm:"autoCreateTime" json:"createdAt"`
 	UpdatedAt time.Time      `gorm:"autoUpdateTime" json:"updatedAt"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 }
+
+// SiteField implements SiteScoped
+func (Material) SiteField() string { return "NameOfSite" }
@@ -10,26 +10,33 @@ import (
 
 // MnrReport represents a “MNR” form submission.
 type Mnr struct {
-	ID                   uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
-	NameOfSite           string         `gorm:"not null" json:"nameOfSite"`
-	ZoneName             string         `gorm:"not null" json:"zoneName"`
-	WorkDescription      string         `gorm:"not null" json:"workDescription"`
-	SkilledLabourCount   string         `gorm:"not null" json:"skilledLabourCount"`
-	UnskilledLabourCount string         `gorm:"not null" json:"unskilledLabourCount"`
-	WomenCount           string         `gorm:"not null" json:"womenCount"`
-	LabourType           string         `gorm:"null" json:"labourType"` // e.g. "Skilled", "Unskilled",
-	StartTime            JSONTime       `gorm:"null" json:"startTime"`  // e.g. "2023-10-01T08:00:00Z"
-	EndTime              JSONTime       `gorm:"null" json:"endTime"`    // e.g. "2023-10-01T17:00:00Z"
-	ContractorName       string         `gorm:"not null" json:"contractorName"`
-	AttendanceTakenBy    string         `gorm:"not null" json:"attendanceTakenBy"`
-	AttendancePhone      string         `gorm:"not null" json:"attendancePhone"`
-	WorkPhotos           datatypes.JSON `gorm:"type:jsonb;not null" json:"workPhotos"` // e.g. ["img1.jpg", "img2.png"]
-	Remarks              *string        `json:"remarks,omitempty"`
-	Latitude             float64        `gorm:"not null" json:"latitude"`
-	Longitude            float64        `gorm:"not null" json:"longitude"`
-	SubmittedAt          JSONTime       `gorm:"not null" json:"submittedAt"`
+	ID                     uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	NameOfSite             string         `gorm:"not null" json:"nameOfSite" validate:"required"`
+	SiteID                 *uuid.UUID     `gorm:"type:uuid;index" json:"siteId,omitempty"`
+	ZoneName               string         `gorm:"not null" json:"zoneName"`
+	WorkDescription        string         `gorm:"not null" json:"workDescription"`
+	SkilledLabourCount     string         `gorm:"not null" json:"skilledLabourCount" validate:"omitempty,numeric"`
+	UnskilledLabourCount   string         `gorm:"not null" json:"unskilledLabourCount" validate:"omitempty,numeric"`
+	WomenCount             string         `gorm:"not null" json:"womenCount" validate:"omitempty,numeric"`
+	LabourType             string         `gorm:"null" json:"labourType"` // e.g. "Skilled", "Unskilled",
+	StartTime              JSONTime       `gorm:"null" json:"startTime"`  // e.g. "2023-10-01T08:00:00Z"
+	EndTime                JSONTime       `gorm:"null" json:"endTime"`    // e.g. "2023-10-01T17:00:00Z"
+	ContractorName         string         `gorm:"not null" json:"contractorName" validate:"required"`
+	ContractorID           *uuid.UUID     `gorm:"type:uuid;index" json:"contractorId,omitempty"`
+	AttendanceTakenBy      string         `gorm:"not null" json:"attendanceTakenBy"`
+	AttendancePhone        string         `gorm:"not null" json:"attendancePhone"`
+	WorkPhotos             datatypes.JSON `gorm:"type:jsonb;not null" json:"workPhotos"` // e.g. ["img1.jpg", "img2.png"]
+	Remarks                *string        `json:"remarks,omitempty"`
+	Latitude               float64        `gorm:"not null" json:"latitude" validate:"latitude"`
+	Longitude              float64        `gorm:"not null" json:"longitude" validate:"longitude"`
+	GeofenceStatus         string         `gorm:"size:10;index" json:"geofenceStatus,omitempty"`
+	GeofenceDistanceMeters *float64       `json:"geofenceDistanceMeters,omitempty"`
+	SubmittedAt            JSONTime       `gorm:"not null" json:"submittedAt" validate:"required,notfuture"`
 
 	CreatedAt time.Time      `gorm:"autoCreateTime" json:"createdAt"`
 	UpdatedAt time.Time      `gorm:"autoUpdateTime" json:"updatedAt"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 }
+
+// SiteField implements SiteScoped
+func (Mnr) SiteField() string { return "NameOfSite" }
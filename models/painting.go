@@ -12,9 +12,11 @@ import (
 type Painting struct {
 	ID                 uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
 	NameOfYard         *string        `gorm:"column:name_of_yard" json:"nameOfYard,omitempty"`
+	YardID             *uuid.UUID     `gorm:"column:yard_id;type:uuid;index" json:"yardId,omitempty"`
 	ContractorName     *string        `gorm:"column:contractor_name" json:"contractorName,omitempty"`
-	WorkDoneActivity   string         `gorm:"column:work_done_activity;not null" json:"workDoneActivity"`
-	NumberOfCoats      int            `gorm:"column:number_of_coats;not null" json:"numberOfCoats"`
+	ContractorID       *uuid.UUID     `gorm:"column:contractor_id;type:uuid;index" json:"contractorId,omitempty"`
+	WorkDoneActivity   string         `gorm:"column:work_done_activity;not null" json:"workDoneActivity" validate:"required"`
+	NumberOfCoats      int            `gorm:"column:number_of_coats;not null" json:"numberOfCoats" validate:"gte=0,lte=20"`
 	DiaOfPipe          string         `gorm:"column:dia_of_pipe;not null" json:"diaOfPipe"`
 	PipeNo             string         `gorm:"column:pipe_no;not null" json:"pipeNo"`
 	LengthOfPipe       string         `gorm:"column:length_of_pipe;not null" json:"lengthOfPipe"`
@@ -23,11 +25,14 @@ type Painting struct {
 	Remarks            *string        `gorm:"column:remarks" json:"remarks,omitempty"`
 	SiteEngineerName   string         `gorm:"column:site_engineer_name" json:"siteEngineerName,omitempty"`
 	PhoneNumber        string         `gorm:"column:phone_number" json:"phoneNumber,omitempty"`
-	Latitude           float64        `gorm:"column:latitude;not null" json:"latitude"`
-	Longitude          float64        `gorm:"column:longitude;not null" json:"longitude"`
-	SubmittedAt        JSONTime       `gorm:"column:submitted_at;not null" json:"submittedAt"`
+	Latitude           float64        `gorm:"column:latitude;not null" json:"latitude" validate:"latitude"`
+	Longitude          float64        `gorm:"column:longitude;not null" json:"longitude" validate:"longitude"`
+	SubmittedAt        JSONTime       `gorm:"column:submitted_at;not null" json:"submittedAt" validate:"required,notfuture"`
 
 	CreatedAt time.Time      `gorm:"autoCreateTime" json:"createdAt"`
 	UpdatedAt time.Time      `gorm:"autoUpdateTime" json:"updatedAt"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 }
+
+// SiteField implements SiteScoped
+func (Painting) SiteField() string { return "NameOfYard" }
@@ -0,0 +1,123 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// RolePermission grants a permission to every user with Role. Permission
+// is a name from Permissions(), "<module>:*" for every action of a module,
+// or "*" for everything.
+type RolePermission struct {
+	Role       string    `gorm:"primaryKey;size:50" json:"role"`
+	Permission string    `gorm:"primaryKey;size:100" json:"permission"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// PermissionInfo describes one permission of the catalog
+type PermissionInfo struct {
+	Name   string `json:"name"`
+	Module string `json:"module"`
+	Action string `json:"action"`
+}
+
+// Permission actions
+const (
+	ActionRead    = "read"
+	ActionCreate  = "create"
+	ActionUpdate  = "update"
+	ActionDelete  = "delete"
+	ActionApprove = "approve"
+	ActionResolve = "resolve"
+	ActionManage  = "manage"
+	// ActionAll on "sites" lifts the site restriction of SiteScope
+	ActionAll = "all"
+)
+
+// RecordModules are the form submission modules, named as in their routes
+var RecordModules = []string{
+	"dprsite", "wrapping", "eway", "water", "stock", "dairysite", "payment", "material",
+	"mnr", "nmr_vehicle", "contractor", "painting", "diesel", "tasks", "vehiclelog",
+}
+
+// adminModules are the non-record areas and the actions they support
+var adminModules = []struct {
+	module  string
+	actions []string
+}{
+	{"users", []string{ActionRead, ActionManage}},
+	{"reports", []string{ActionRead}},
+	{"kpi", []string{ActionRead}},
+	{"quarantine", []string{ActionRead, ActionResolve}},
+	{"api_clients", []string{ActionManage}},
+	{"permissions", []string{ActionManage}},
+	{"sites", []string{ActionRead, ActionManage, ActionAll}},
+	{"master", []string{ActionRead, ActionManage}},
+	{"approvals", []string{ActionManage}},
+	{"geofence", []string{ActionManage}},
+	{"files", []string{ActionRead}},
+}
+
+var permissionCatalog = buildPermissionCatalog()
+
+func buildPermissionCatalog() []PermissionInfo {
+	var out []PermissionInfo
+	add := func(module string, actions ...string) {
+		for _, a := range actions {
+			out = append(out, PermissionInfo{Name: PermissionName(module, a), Module: module, Action: a})
+		}
+	}
+	for _, m := range RecordModules {
+		add(m, ActionRead, ActionCreate, ActionUpdate, ActionDelete)
+	}
+	for _, w := range ApprovalWorkflows {
+		add(w.Module, ActionApprove, w.DoneAction)
+	}
+	for _, m := range adminModules {
+		add(m.module, m.actions...)
+	}
+	return out
+}
+
+// PermissionName builds a permission name such as "diesel:read"
+func PermissionName(module, action string) string {
+	return module + ":" + action
+}
+
+// Permissions returns the catalog of known permissions
+func Permissions() []PermissionInfo {
+	return permissionCatalog
+}
+
+// IsGrantable reports whether name may be stored in a RolePermission
+func IsGrantable(name string) bool {
+	if name == "*" {
+		return true
+	}
+	if module, ok := strings.CutSuffix(name, ":*"); ok {
+		for _, p := range permissionCatalog {
+			if p.Module == module {
+				return true
+			}
+		}
+		return false
+	}
+	for _, p := range permissionCatalog {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// GrantCovers reports whether a granted permission, possibly a wildcard,
+// includes permission
+func GrantCovers(grant, permission string) bool {
+	if grant == "*" || grant == permission {
+		return true
+	}
+	if module, ok := strings.CutSuffix(grant, ":*"); ok {
+		return strings.HasPrefix(permission, module+":")
+	}
+	return false
+}
@@ -0,0 +1,25 @@
+package models
+
+import "testing"
+
+func TestGrantCovers(t *testing.T) {
+	tests := []struct {
+		grant, permission string
+		want              bool
+	}{
+		{"*", "diesel:read", true},
+		{"diesel:read", "diesel:read", true},
+		{"diesel:*", "diesel:read", true},
+		{"diesel:*", "diesel:export", true},
+		{"diesel:read", "diesel:create", false},
+		{"diesel:*", "dieselx:read", false},
+		{"diesel:*", "water:read", false},
+		{"diesel", "diesel:read", false},
+		{"", "diesel:read", false},
+	}
+	for _, tt := range tests {
+		if got := GrantCovers(tt.grant, tt.permission); got != tt.want {
+			t.Errorf("GrantCovers(%q, %q) = %v, want %v", tt.grant, tt.permission, got, tt.want)
+		}
+	}
+}
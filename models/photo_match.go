@@ -0,0 +1,175 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PhotoMatchDistance is the most bits perceptual hashes may differ in for
+// two photos to count as the same picture
+const PhotoMatchDistance = 6
+
+// photoMatchLimit bounds the earlier photos flagged for any one photo
+const photoMatchLimit = 10
+
+// PhotoCheckedModules are the modules whose photos are proof of work done,
+// such as meter readings and bills, and so are checked for reuse
+var PhotoCheckedModules = []string{"diesel", "contractor", "dprsite", "vehiclelog"}
+
+// PhotoMatch flags a photo linked to a record of a PhotoCheckedModules
+// module that is the same as, or looks the same as, a photo of an earlier
+// record. Site is the flagged record's, normalized.
+type PhotoMatch struct {
+	ID                uint      `gorm:"primaryKey" json:"id"`
+	Module            string    `gorm:"size:30;not null;index:idx_photo_matches_record" json:"module"`
+	RecordID          uuid.UUID `gorm:"type:uuid;not null;index:idx_photo_matches_record" json:"recordId"`
+	Site              string    `gorm:"size:255;index" json:"site,omitempty"`
+	AttachmentID      uuid.UUID `gorm:"type:uuid;not null;index" json:"attachmentId"`
+	MatchModule       string    `gorm:"size:30;not null;index:idx_photo_matches_match" json:"matchModule"`
+	MatchRecordID     uuid.UUID `gorm:"type:uuid;not null;index:idx_photo_matches_match" json:"matchRecordId"`
+	MatchAttachmentID uuid.UUID `gorm:"type:uuid;not null" json:"matchAttachmentId"`
+	Exact             bool      `gorm:"not null" json:"exact"`    // same file, by SHA-256
+	Distance          int       `gorm:"not null" json:"distance"` // bits the perceptual hashes differ in
+	CreatedAt         time.Time `gorm:"index" json:"createdAt"`
+}
+
+// photoChecked reports whether module is one of PhotoCheckedModules
+func photoChecked(module string) bool {
+	for _, m := range PhotoCheckedModules {
+		if m == module {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPhotos flags the attachments in ids that match photos linked to
+// other records, when record is of a PhotoCheckedModules module. Run it
+// once the attachments are linked, in the same transaction.
+func matchPhotos(db *gorm.DB, ids []uuid.UUID, record interface{}) error {
+	module, recordID := AuditModule(record), approvalRecordID(record)
+	if !photoChecked(module) {
+		return nil
+	}
+	site, _, _ := RecordSite(record)
+	var list []Attachment
+	err := db.Select("id", "sha256", "phash").
+		Where("id IN ? AND (sha256 <> '' OR phash IS NOT NULL)", ids).Find(&list).Error
+	if err != nil {
+		return err
+	}
+	for _, a := range list {
+		var candidates []struct {
+			ID       uuid.UUID
+			Module   string
+			RecordID uuid.UUID
+			Exact    bool
+			Distance int
+		}
+		err := db.Raw(`SELECT id, module, record_id, exact, distance FROM (
+			SELECT id, module, record_id, created_at,
+				COALESCE(sha256 <> '' AND sha256 = @sha, false) AS exact,
+				CASE WHEN phash IS NULL OR CAST(@phash AS bigint) IS NULL THEN 64
+					ELSE length(replace(CAST(phash # CAST(@phash AS bigint) AS bit(64))::text, '0', '')) END AS distance
+			FROM attachments
+			WHERE record_id IS NOT NULL AND NOT (module = @module AND record_id = @record)
+		) candidates
+		WHERE exact OR distance <= @max
+		ORDER BY exact DESC, distance, created_at
+		LIMIT @limit`,
+			sql.Named("sha", a.SHA256), sql.Named("phash", a.PHash),
+			sql.Named("module", module), sql.Named("record", recordID),
+			sql.Named("max", PhotoMatchDistance), sql.Named("limit", photoMatchLimit),
+		).Scan(&candidates).Error
+		if err != nil {
+			return err
+		}
+		for _, c := range candidates {
+			distance := c.Distance
+			if c.Exact {
+				distance = 0
+			}
+			match := PhotoMatch{
+				Module:            module,
+				RecordID:          recordID,
+				Site:              NormalizeSiteName(site),
+				AttachmentID:      a.ID,
+				MatchModule:       c.Module,
+				MatchRecordID:     c.RecordID,
+				MatchAttachmentID: c.ID,
+				Exact:             c.Exact,
+				Distance:          distance,
+			}
+			if err := db.Create(&match).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// deletePhotoMatches drops the matches a purged record is part of, on
+// either side
+func deletePhotoMatches(db *gorm.DB, module string, recordID uuid.UUID) error {
+	return db.Where("(module = ? AND record_id = ?) OR (match_module = ? AND match_record_id = ?)",
+		module, recordID, module, recordID).Delete(&PhotoMatch{}).Error
+}
+
+// PhotoDuplicate is a row of the suspected duplicates report: a photo of
+// a record and the earlier photo it matches, with links to both records
+// and photos
+type PhotoDuplicate struct {
+	PhotoMatch
+	UploadedByName      string `json:"uploadedByName,omitempty"`
+	MatchUploadedByName string `json:"matchUploadedByName,omitempty"`
+	RecordURL           string `json:"recordUrl"`
+	PhotoURL            string `json:"photoUrl"`
+	MatchRecordURL      string `json:"matchRecordUrl"`
+	MatchPhotoURL       string `json:"matchPhotoUrl"`
+}
+
+// PhotoDuplicateReport lists the photo matches flagged in [from, to) for
+// records in scope, newest first. Zero times leave that end open and
+// module, when set, limits it to one module.
+func PhotoDuplicateReport(db *gorm.DB, scope SiteScope, module string, from, to time.Time) ([]PhotoDuplicate, error) {
+	query := db.Table("photo_matches AS m").
+		Select("m.*, a.uploaded_by_name, b.uploaded_by_name AS match_uploaded_by_name").
+		Joins("LEFT JOIN attachments a ON a.id = m.attachment_id").
+		Joins("LEFT JOIN attachments b ON b.id = m.match_attachment_id")
+	if module != "" {
+		if !photoChecked(module) {
+			return nil, &ParamError{Param: "module", Message: fmt.Sprintf("%q is not checked for duplicate photos", module)}
+		}
+		query = query.Where("m.module = ?", module)
+	}
+	if !from.IsZero() {
+		query = query.Where("m.created_at >= ?", from)
+	}
+	if !to.IsZero() {
+		query = query.Where("m.created_at < ?", to)
+	}
+	if !scope.All {
+		query = query.Where("m.site IN ?", scope.Sites)
+	}
+	var rows []PhotoDuplicate
+	if err := query.Order("m.created_at DESC, m.id DESC").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for i := range rows {
+		row := &rows[i]
+		row.RecordURL = recordURL(row.Module, row.RecordID)
+		row.PhotoURL = AttachmentURL(row.AttachmentID)
+		row.MatchRecordURL = recordURL(row.MatchModule, row.MatchRecordID)
+		row.MatchPhotoURL = AttachmentURL(row.MatchAttachmentID)
+	}
+	return rows, nil
+}
+
+// recordURL is the admin URL a record is read at
+func recordURL(module string, id uuid.UUID) string {
+	return "/api/v1/admin/" + module + "/" + id.String()
+}
@@ -1,6 +1,6 @@
 package models
 
 type PhotoUpload struct {
-    Description string `json:"description"`
-    PhotoUrl string `json:"photo_url"`
+	Description string `json:"description"`
+	PhotoUrl    string `json:"photo_url"`
 }
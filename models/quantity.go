@@ -0,0 +1,98 @@
+package models
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Quantity is a numeric measurement (liters, meters, counts) stored in a
+// numeric column. For compatibility with older app builds it also accepts
+// the legacy string form ("12.5", "1,200") in JSON; it is always emitted
+// as a number. Models hold it as *Quantity: the migration leaves values it
+// could not parse NULL until their quarantine entry is resolved, and nil
+// keeps them NULL and null in JSON rather than passing them off as zero.
+type Quantity float64
+
+// Float returns the value of q, zero for nil
+func (q *Quantity) Float() float64 {
+	if q == nil {
+		return 0
+	}
+	return float64(*q)
+}
+
+// ParseQuantity parses the legacy string form. An empty string is zero.
+func ParseQuantity(s string) (Quantity, error) {
+	s = strings.ReplaceAll(strings.TrimSpace(s), ",", "")
+	if s == "" {
+		return 0, nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a number", s)
+	}
+	return Quantity(f), nil
+}
+
+// UnmarshalJSON accepts 12.5, "12.5" and null.
+func (q *Quantity) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	if bytes.Equal(b, []byte("null")) {
+		*q = 0
+		return nil
+	}
+	if len(b) > 0 && b[0] == '"' {
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+		v, err := ParseQuantity(s)
+		if err != nil {
+			return fmt.Errorf("Quantity.UnmarshalJSON: %w", err)
+		}
+		*q = v
+		return nil
+	}
+	var f float64
+	if err := json.Unmarshal(b, &f); err != nil {
+		return fmt.Errorf("Quantity.UnmarshalJSON: cannot parse %s", b)
+	}
+	*q = Quantity(f)
+	return nil
+}
+
+// Value implements driver.Valuer
+func (q Quantity) Value() (driver.Value, error) {
+	return float64(q), nil
+}
+
+// Scan implements sql.Scanner. NULL can only be scanned into a *Quantity.
+func (q *Quantity) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case float64:
+		*q = Quantity(v)
+	case int64:
+		*q = Quantity(v)
+	case []byte:
+		f, err := strconv.ParseFloat(string(v), 64)
+		if err != nil {
+			return fmt.Errorf("Quantity.Scan: parse %q: %w", v, err)
+		}
+		*q = Quantity(f)
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("Quantity.Scan: parse %q: %w", v, err)
+		}
+		*q = Quantity(f)
+	case nil:
+		return fmt.Errorf("Quantity.Scan: NULL needs a *Quantity")
+	default:
+		return fmt.Errorf("Quantity.Scan: unsupported type %T", src)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestQuantityJSON(t *testing.T) {
+	tests := []struct {
+		in   string
+		want *float64
+		err  bool
+	}{
+		{`12.5`, ptr(12.5), false},
+		{`"12.5"`, ptr(12.5), false},
+		{`"1,200"`, ptr(1200), false},
+		{`""`, ptr(0), false},
+		{`null`, nil, false},
+		{`"twelve"`, nil, true},
+		{`true`, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			var v struct {
+				Q *Quantity `json:"q"`
+			}
+			err := json.Unmarshal([]byte(`{"q":`+tt.in+`}`), &v)
+			if tt.err {
+				if err == nil {
+					t.Errorf("Unmarshal(%s) succeeded, want an error", tt.in)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			switch {
+			case tt.want == nil && v.Q != nil:
+				t.Errorf("Unmarshal(%s) = %v, want nil", tt.in, *v.Q)
+			case tt.want != nil && (v.Q == nil || float64(*v.Q) != *tt.want):
+				t.Errorf("Unmarshal(%s) = %v, want %v", tt.in, v.Q, *tt.want)
+			}
+		})
+	}
+}
+
+func TestQuantityNullStaysNull(t *testing.T) {
+	// A quarantined NULL must not come back as a zero
+	var d Diesel
+	if err := json.Unmarshal([]byte(`{"quantityInLiters":null}`), &d); err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out map[string]interface{}
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatal(err)
+	}
+	if q, ok := out["quantityInLiters"]; !ok || q != nil {
+		t.Errorf("quantityInLiters = %v, want null", q)
+	}
+	if d.QuantityInLiters.Float() != 0 {
+		t.Errorf("Float of nil = %v, want 0", d.QuantityInLiters.Float())
+	}
+	if err := ValidateModel(&d); err == nil {
+		t.Error("ValidateModel accepted a NULL quantity")
+	}
+}
+
+func ptr(f float64) *float64 { return &f }
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+)
+
+// NumericQuarantine holds a legacy text value the numeric column migration
+// could not parse. The typed column is left NULL until someone reviews the
+// row and supplies the correct number.
+type NumericQuarantine struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	SourceTable   string     `gorm:"size:64;not null;index:idx_quarantine_record" json:"sourceTable"`
+	RecordID      string     `gorm:"size:64;not null;index:idx_quarantine_record" json:"recordId"`
+	ColumnName    string     `gorm:"size:64;not null" json:"columnName"`
+	RawValue      string     `gorm:"not null" json:"rawValue"`
+	Resolved      bool       `gorm:"not null;default:false;index" json:"resolved"`
+	ResolvedValue *float64   `json:"resolvedValue,omitempty"`
+	ResolvedBy    *string    `json:"resolvedBy,omitempty"`
+	ResolvedAt    *time.Time `json:"resolvedAt,omitempty"`
+	CreatedAt     time.Time  `gorm:"autoCreateTime" json:"createdAt"`
+}
+
+// NumericColumn names a string column converted to a numeric one and the
+// record model of its table
+type NumericColumn struct {
+	Table  string
+	Column string
+	Model  interface{}
+}
+
+// NumericColumns lists every column converted by the typed numeric
+// migration. Quarantine fixes are only applied to these.
+var NumericColumns = []NumericColumn{
+	{Table: "diesels", Column: "quantity_in_liters", Model: Diesel{}},
+	{Table: "contractors", Column: "actual_meters", Model: Contractor{}},
+	{Table: "dpr_sites", Column: "actual_meters_laid_on_day", Model: DprSite{}},
+	{Table: "stocks", Column: "item_quantity", Model: Stock{}},
+	{Table: "waters", Column: "capacity_in_liters", Model: Water{}},
+}
@@ -0,0 +1,171 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// Output formats accepted by the format query parameter
+const (
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+)
+
+var reportFormats = map[string]bool{FormatJSON: true, FormatCSV: true, FormatXLSX: true, FormatGeoJSON: true}
+
+var (
+	stringArrayType = reflect.TypeOf(pq.StringArray{})
+	jsonType        = reflect.TypeOf(datatypes.JSON{})
+)
+
+// RowWriter receives an export one row at a time. Values are nil, string,
+// bool, int64, float64 or time.Time.
+type RowWriter interface {
+	WriteHeader(columns []string) error
+	WriteRow(values []interface{}) error
+	Close() error
+}
+
+// TableName returns the database table behind the service's model
+func (s *ReportService[T]) TableName() string {
+	stmt := &gorm.Statement{DB: s.db}
+	if err := stmt.Parse(s.model); err != nil {
+		return "report"
+	}
+	return stmt.Schema.Table
+}
+
+// ExportReport streams every row matching the filters, date range and sort
+// in params to out, ignoring page, limit and cursor. Rows are read straight
+// from the database cursor so memory use does not grow with the result.
+// GeoJSON exports always include the coordinates, whatever fields says.
+func (s *ReportService[T]) ExportReport(params *ReportParams, out RowWriter) error {
+	plan, err := s.plan(params)
+	if err != nil {
+		return err
+	}
+
+	query := s.base()
+	if len(params.Fields) > 0 {
+		if dbFields := s.getDBFields(params.Fields, plan.jsonToDB); len(dbFields) > 0 {
+			if params.Format == FormatGeoJSON {
+				if lat, lng, ok := coordinateColumns(plan.columns); ok {
+					for _, c := range []string{lat, lng} {
+						if !slices.Contains(dbFields, c) {
+							dbFields = append(dbFields, c)
+						}
+					}
+				}
+			}
+			query = query.Select(dbFields)
+		}
+	}
+	query = s.applyFilters(query, params, plan)
+	query = query.Order(orderBy(plan.sortCols))
+
+	rows, err := query.Rows()
+	if err != nil {
+		return fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	dbColumns, err := rows.Columns()
+	if err != nil {
+		return fmt.Errorf("failed to get columns: %w", err)
+	}
+
+	// Only columns exposed in JSON are exported, headed by their JSON names
+	var header []string
+	var exported []int
+	var types []reflect.Type
+	for i, col := range dbColumns {
+		jsonName := s.findJSONField(col, plan.jsonToDB)
+		rc, ok := plan.columns[jsonName]
+		if !ok {
+			continue
+		}
+		header = append(header, jsonName)
+		exported = append(exported, i)
+		types = append(types, rc.GoType)
+	}
+	if err := out.WriteHeader(header); err != nil {
+		return err
+	}
+
+	values := make([]interface{}, len(dbColumns))
+	valuePtrs := make([]interface{}, len(dbColumns))
+	for i := range values {
+		valuePtrs[i] = &values[i]
+	}
+	line := make([]interface{}, len(exported))
+	for rows.Next() {
+		if err := rows.Scan(valuePtrs...); err != nil {
+			return fmt.Errorf("failed to scan row: %w", err)
+		}
+		for j, i := range exported {
+			line[j] = exportValue(values[i], types[j])
+		}
+		if err := out.WriteRow(line); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read rows: %w", err)
+	}
+	return out.Close()
+}
+
+// exportValue flattens a scanned column into a spreadsheet friendly value.
+// Photo and file arrays become a single cell of comma separated URLs.
+func exportValue(v interface{}, goType reflect.Type) interface{} {
+	switch goType {
+	case stringArrayType:
+		var arr pq.StringArray
+		if err := arr.Scan(v); err == nil {
+			return strings.Join(arr, ", ")
+		}
+	case jsonType:
+		raw := toBytes(v)
+		var arr []string
+		if err := json.Unmarshal(raw, &arr); err == nil {
+			return strings.Join(arr, ", ")
+		}
+		return string(raw)
+	}
+
+	switch t := v.(type) {
+	case nil, string, bool, int64, float64:
+		return t
+	case time.Time:
+		return t
+	case []byte:
+		return string(t)
+	case int32:
+		return int64(t)
+	case float32:
+		return float64(t)
+	}
+	return fmt.Sprint(v)
+}
+
+func toBytes(v interface{}) []byte {
+	switch t := v.(type) {
+	case []byte:
+		return t
+	case string:
+		return []byte(t)
+	case nil:
+		return nil
+	}
+	b, _ := json.Marshal(v)
+	return b
+}
@@ -0,0 +1,260 @@
+package models
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// Filter operators accepted in report query strings, e.g. quantityInLiters[gte]=100
+const (
+	OpEq     = "eq"
+	OpNe     = "ne"
+	OpGt     = "gt"
+	OpGte    = "gte"
+	OpLt     = "lt"
+	OpLte    = "lte"
+	OpIn     = "in"
+	OpNin    = "nin"
+	OpLike   = "like"
+	OpIlike  = "ilike"
+	OpIsNull = "isnull"
+)
+
+// Column kinds used to decide which operators a field supports
+const (
+	kindString = "text"
+	kindUUID   = "uuid"
+	kindNumber = "number"
+	kindBool   = "boolean"
+	kindTime   = "date/time"
+	kindOther  = "structured"
+)
+
+var filterOperators = map[string]bool{
+	OpEq: true, OpNe: true, OpGt: true, OpGte: true, OpLt: true, OpLte: true,
+	OpIn: true, OpNin: true, OpLike: true, OpIlike: true, OpIsNull: true,
+}
+
+var operatorsByKind = map[string]map[string]bool{
+	kindString: {OpEq: true, OpNe: true, OpIn: true, OpNin: true, OpLike: true, OpIlike: true, OpIsNull: true},
+	kindUUID:   {OpEq: true, OpNe: true, OpIn: true, OpNin: true, OpIsNull: true},
+	kindNumber: {OpEq: true, OpNe: true, OpGt: true, OpGte: true, OpLt: true, OpLte: true, OpIn: true, OpNin: true, OpIsNull: true},
+	kindBool:   {OpEq: true, OpNe: true, OpIsNull: true},
+	kindTime:   {OpEq: true, OpNe: true, OpGt: true, OpGte: true, OpLt: true, OpLte: true, OpIsNull: true},
+	kindOther:  {OpIsNull: true},
+}
+
+var sqlOperators = map[string]string{
+	OpEq: "=", OpNe: "<>", OpGt: ">", OpGte: ">=", OpLt: "<", OpLte: "<=",
+	OpIn: "IN", OpNin: "NOT IN", OpLike: "LIKE", OpIlike: "ILIKE",
+}
+
+var uuidType = reflect.TypeOf(uuid.UUID{})
+
+// Filter is a single condition parsed from the query string
+type Filter struct {
+	Field string // JSON field name
+	Op    string
+	Value string
+}
+
+// ParamError is returned for report parameters the client got wrong.
+// Handlers should answer it with 400 instead of 500.
+type ParamError struct {
+	Param   string
+	Message string
+}
+
+func (e *ParamError) Error() string {
+	return fmt.Sprintf("invalid parameter %s: %s", e.Param, e.Message)
+}
+
+// reportColumn describes a model field that can be filtered or sorted on
+type reportColumn struct {
+	DBName     string
+	Kind       string
+	Nullable   bool
+	PrimaryKey bool
+	GoType     reflect.Type
+}
+
+// parseFilterKey splits "field[op]" into its parts. A bare "field" means eq.
+func parseFilterKey(key string) (string, string, error) {
+	open := strings.Index(key, "[")
+	if open == -1 {
+		if strings.Contains(key, "]") {
+			return "", "", &ParamError{Param: key, Message: "malformed filter"}
+		}
+		return key, OpEq, nil
+	}
+	if open == 0 || !strings.HasSuffix(key, "]") {
+		return "", "", &ParamError{Param: key, Message: "malformed filter, expected field[operator]"}
+	}
+	field := key[:open]
+	op := strings.ToLower(key[open+1 : len(key)-1])
+	if !filterOperators[op] {
+		return "", "", &ParamError{Param: key, Message: fmt.Sprintf("unknown operator %q", op)}
+	}
+	return field, op, nil
+}
+
+// sortFilters keeps generated SQL stable regardless of map iteration order
+func sortFilters(filters []Filter) {
+	sort.Slice(filters, func(i, j int) bool {
+		if filters[i].Field != filters[j].Field {
+			return filters[i].Field < filters[j].Field
+		}
+		return filters[i].Op < filters[j].Op
+	})
+}
+
+// buildReportColumns indexes the model's fields by JSON name together with
+// the kind of value each column holds.
+func buildReportColumns(db *gorm.DB, model interface{}) (map[string]reportColumn, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return nil, err
+	}
+	out := make(map[string]reportColumn)
+	for _, field := range stmt.Schema.Fields {
+		jsonName := field.Tag.Get("json")
+		if idx := strings.Index(jsonName, ","); idx != -1 {
+			jsonName = jsonName[:idx]
+		}
+		if jsonName == "" || jsonName == "-" || field.DBName == "" {
+			continue
+		}
+		out[jsonName] = reportColumn{
+			DBName:     field.DBName,
+			Kind:       columnKind(field),
+			Nullable:   field.FieldType.Kind() == reflect.Ptr,
+			PrimaryKey: field.PrimaryKey,
+			GoType:     field.IndirectFieldType,
+		}
+	}
+	return out, nil
+}
+
+func columnKind(field *schema.Field) string {
+	t := field.IndirectFieldType
+	switch {
+	case t == uuidType || strings.EqualFold(string(field.DataType), "uuid"):
+		return kindUUID
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Map:
+		return kindOther
+	}
+	switch field.GORMDataType {
+	case schema.String:
+		return kindString
+	case schema.Int, schema.Uint, schema.Float:
+		return kindNumber
+	case schema.Bool:
+		return kindBool
+	case schema.Time:
+		return kindTime
+	}
+	return kindOther
+}
+
+// filterCondition is a validated SQL fragment with its arguments
+type filterCondition struct {
+	SQL  string
+	Args []interface{}
+}
+
+// buildFilterConditions checks every filter against the model's columns and
+// turns it into a parameterised condition.
+func buildFilterConditions(filters []Filter, columns map[string]reportColumn) ([]filterCondition, error) {
+	conds := make([]filterCondition, 0, len(filters))
+	for _, f := range filters {
+		param := f.Field
+		if f.Op != OpEq {
+			param = fmt.Sprintf("%s[%s]", f.Field, f.Op)
+		}
+		col, ok := columns[f.Field]
+		if !ok {
+			return nil, &ParamError{Param: param, Message: fmt.Sprintf("unknown field %q", f.Field)}
+		}
+		if !operatorsByKind[col.Kind][f.Op] {
+			return nil, &ParamError{Param: param, Message: fmt.Sprintf("operator %q is not supported for %s field %q", f.Op, col.Kind, f.Field)}
+		}
+
+		switch f.Op {
+		case OpIsNull:
+			isNull, err := strconv.ParseBool(f.Value)
+			if err != nil {
+				return nil, &ParamError{Param: param, Message: "expected true or false"}
+			}
+			if isNull {
+				conds = append(conds, filterCondition{SQL: col.DBName + " IS NULL"})
+			} else {
+				conds = append(conds, filterCondition{SQL: col.DBName + " IS NOT NULL"})
+			}
+		case OpIn, OpNin:
+			var values []interface{}
+			for _, raw := range strings.Split(f.Value, ",") {
+				v, err := convertFilterValue(strings.TrimSpace(raw), col.Kind)
+				if err != nil {
+					return nil, &ParamError{Param: param, Message: err.Error()}
+				}
+				values = append(values, v)
+			}
+			conds = append(conds, filterCondition{SQL: col.DBName + " " + sqlOperators[f.Op] + " ?", Args: []interface{}{values}})
+		default:
+			v, err := convertFilterValue(f.Value, col.Kind)
+			if err != nil {
+				return nil, &ParamError{Param: param, Message: err.Error()}
+			}
+			conds = append(conds, filterCondition{SQL: col.DBName + " " + sqlOperators[f.Op] + " ?", Args: []interface{}{v}})
+		}
+	}
+	return conds, nil
+}
+
+// convertFilterValue parses a raw query value into the column's Go type
+func convertFilterValue(raw, kind string) (interface{}, error) {
+	switch kind {
+	case kindNumber:
+		n, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", raw)
+		}
+		return n, nil
+	case kindBool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a boolean", raw)
+		}
+		return b, nil
+	case kindUUID:
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a valid id", raw)
+		}
+		return id.String(), nil
+	case kindTime:
+		t, err := parseFilterTime(raw)
+		if err != nil {
+			return nil, err
+		}
+		return t, nil
+	}
+	return raw, nil
+}
+
+func parseFilterTime(raw string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, raw); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is not a date (use YYYY-MM-DD or RFC3339)", raw)
+}
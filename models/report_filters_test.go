@@ -0,0 +1,112 @@
+package models
+
+import (
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseFilterKey(t *testing.T) {
+	tests := []struct {
+		key       string
+		field, op string
+		err       bool
+	}{
+		{"nameOfSite", "nameOfSite", OpEq, false},
+		{"quantityInLiters[gte]", "quantityInLiters", OpGte, false},
+		{"remarks[ISNULL]", "remarks", OpIsNull, false},
+		{"item[nin]", "item", OpNin, false},
+		{"item[between]", "", "", true},
+		{"[eq]", "", "", true},
+		{"item[eq", "", "", true},
+		{"item]", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			field, op, err := parseFilterKey(tt.key)
+			if tt.err {
+				var perr *ParamError
+				if !errors.As(err, &perr) {
+					t.Errorf("parseFilterKey(%q) = %v, want a ParamError", tt.key, err)
+				}
+				return
+			}
+			if err != nil || field != tt.field || op != tt.op {
+				t.Errorf("parseFilterKey(%q) = %q, %q, %v; want %q, %q", tt.key, field, op, err, tt.field, tt.op)
+			}
+		})
+	}
+}
+
+func TestParseReportParamsFilters(t *testing.T) {
+	r := httptest.NewRequest("GET", "/?vehicleNumber=KA01&quantityInLiters[gte]=100&page=2&item[in]=HSD,%20Petrol&remarks=", nil)
+	params, err := ParseReportParams(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Reserved and empty parameters are not filters, the rest are sorted
+	want := []Filter{
+		{Field: "item", Op: OpIn, Value: "HSD, Petrol"},
+		{Field: "quantityInLiters", Op: OpGte, Value: "100"},
+		{Field: "vehicleNumber", Op: OpEq, Value: "KA01"},
+	}
+	if !reflect.DeepEqual(params.Filters, want) {
+		t.Errorf("Filters = %+v, want %+v", params.Filters, want)
+	}
+}
+
+func TestBuildFilterConditions(t *testing.T) {
+	columns, err := buildReportColumns(dryRunDB(t), Diesel{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	day := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		filter Filter
+		sql    string
+		args   []interface{}
+		err    bool
+	}{
+		{"text eq", Filter{"nameOfSite", OpEq, "Yard A"}, "name_of_site = ?", []interface{}{"Yard A"}, false},
+		{"text ilike", Filter{"vehicleNumber", OpIlike, "%ka01%"}, "vehicle_number ILIKE ?", []interface{}{"%ka01%"}, false},
+		{"number range", Filter{"quantityInLiters", OpGte, "100"}, "quantity_in_liters >= ?", []interface{}{float64(100)}, false},
+		{"number list", Filter{"latitude", OpNin, "1, 2.5"}, "latitude NOT IN ?", []interface{}{[]interface{}{float64(1), 2.5}}, false},
+		{"time", Filter{"createdAt", OpLt, "2026-10-01"}, "created_at < ?", []interface{}{day}, false},
+		{"uuid", Filter{"siteId", OpEq, "6F9619FF-8B86-D011-B42D-00C04FC964FF"}, "site_id = ?", []interface{}{"6f9619ff-8b86-d011-b42d-00c04fc964ff"}, false},
+		{"is null", Filter{"remarks", OpIsNull, "true"}, "remarks IS NULL", nil, false},
+		{"is not null", Filter{"remarks", OpIsNull, "false"}, "remarks IS NOT NULL", nil, false},
+		{"array is null", Filter{"billPhotos", OpIsNull, "true"}, "bill_photos IS NULL", nil, false},
+
+		{"unknown field", Filter{"password", OpEq, "x"}, "", nil, true},
+		{"hidden field", Filter{"deletedAt", OpIsNull, "true"}, "", nil, true},
+		{"like on a number", Filter{"quantityInLiters", OpLike, "1%"}, "", nil, true},
+		{"range on text", Filter{"nameOfSite", OpGt, "a"}, "", nil, true},
+		{"equality on an array", Filter{"billPhotos", OpEq, "x"}, "", nil, true},
+		{"not a number", Filter{"quantityInLiters", OpGte, "lots"}, "", nil, true},
+		{"not a number in a list", Filter{"latitude", OpIn, "1,two"}, "", nil, true},
+		{"not a date", Filter{"createdAt", OpLt, "yesterday"}, "", nil, true},
+		{"not an id", Filter{"siteId", OpEq, "yard-a"}, "", nil, true},
+		{"not a boolean", Filter{"remarks", OpIsNull, "maybe"}, "", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conds, err := buildFilterConditions([]Filter{tt.filter}, columns)
+			if tt.err {
+				var perr *ParamError
+				if !errors.As(err, &perr) {
+					t.Errorf("buildFilterConditions = %v, %v; want a ParamError", conds, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(conds) != 1 || conds[0].SQL != tt.sql || !reflect.DeepEqual(conds[0].Args, tt.args) {
+				t.Errorf("buildFilterConditions = %+v, want %q %v", conds, tt.sql, tt.args)
+			}
+		})
+	}
+}
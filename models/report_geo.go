@@ -0,0 +1,157 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// FormatGeoJSON exports a report as a GeoJSON FeatureCollection of points
+const FormatGeoJSON = "geojson"
+
+// earthRadiusKm is the mean Earth radius used for great-circle distances
+const earthRadiusKm = 6371.0
+
+// kmPerDegree is the length of one degree of latitude
+const kmPerDegree = 111.32
+
+// JSON names of the coordinate fields every submission carries
+const (
+	LatitudeField  = "latitude"
+	LongitudeField = "longitude"
+)
+
+// BBox limits a report to points inside a longitude/latitude box
+type BBox struct {
+	MinLng, MinLat, MaxLng, MaxLat float64
+}
+
+// GeoPoint is a latitude/longitude pair in degrees
+type GeoPoint struct {
+	Lat, Lng float64
+}
+
+// DistanceKm returns the great-circle distance between a and b by the
+// haversine formula
+func DistanceKm(a, b GeoPoint) float64 {
+	dLat := radians(b.Lat - a.Lat)
+	dLng := radians(b.Lng - a.Lng)
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(radians(a.Lat))*math.Cos(radians(b.Lat))*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(math.Min(1, h)))
+}
+
+func radians(deg float64) float64 { return deg * math.Pi / 180 }
+
+// parseBBox reads bbox=minLng,minLat,maxLng,maxLat, the order GeoJSON and
+// QGIS use
+func parseBBox(s string) (*BBox, error) {
+	v, err := parseFloats(s, 4)
+	if err != nil {
+		return nil, &ParamError{Param: "bbox", Message: "expected minLng,minLat,maxLng,maxLat"}
+	}
+	b := &BBox{MinLng: v[0], MinLat: v[1], MaxLng: v[2], MaxLat: v[3]}
+	if b.MinLat > b.MaxLat || b.MinLng > b.MaxLng {
+		return nil, &ParamError{Param: "bbox", Message: "minimum must not exceed maximum"}
+	}
+	if !validLatLng(b.MinLat, b.MinLng) || !validLatLng(b.MaxLat, b.MaxLng) {
+		return nil, &ParamError{Param: "bbox", Message: "coordinates out of range"}
+	}
+	return b, nil
+}
+
+// parseNear reads near=lat,lng
+func parseNear(s string) (*GeoPoint, error) {
+	v, err := parseFloats(s, 2)
+	if err != nil {
+		return nil, &ParamError{Param: "near", Message: "expected lat,lng"}
+	}
+	if !validLatLng(v[0], v[1]) {
+		return nil, &ParamError{Param: "near", Message: "coordinates out of range"}
+	}
+	return &GeoPoint{Lat: v[0], Lng: v[1]}, nil
+}
+
+func parseFloats(s string, n int) ([]float64, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != n {
+		return nil, fmt.Errorf("expected %d numbers", n)
+	}
+	out := make([]float64, n)
+	for i, p := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, fmt.Errorf("invalid number %q", p)
+		}
+		out[i] = f
+	}
+	return out, nil
+}
+
+func validLatLng(lat, lng float64) bool {
+	return lat >= -90 && lat <= 90 && lng >= -180 && lng <= 180
+}
+
+// geoConditions turns the bbox and near filters into conditions on the
+// model's latitude and longitude columns. The radius test is the haversine
+// formula in plain SQL, behind a bounding box prefilter that can use an
+// index, so PostGIS is not needed.
+func geoConditions(params *ReportParams, columns map[string]reportColumn) ([]filterCondition, error) {
+	if params.BBox == nil && params.Near == nil {
+		return nil, nil
+	}
+	lat, lng, ok := coordinateColumns(columns)
+	if !ok {
+		param := "bbox"
+		if params.Near != nil {
+			param = "near"
+		}
+		return nil, &ParamError{Param: param, Message: "this report has no location"}
+	}
+
+	var conds []filterCondition
+	if b := params.BBox; b != nil {
+		conds = append(conds, filterCondition{
+			SQL:  fmt.Sprintf("%s BETWEEN ? AND ? AND %s BETWEEN ? AND ?", lat, lng),
+			Args: []interface{}{b.MinLat, b.MaxLat, b.MinLng, b.MaxLng},
+		})
+	}
+	if p := params.Near; p != nil {
+		dLat := params.RadiusKm / kmPerDegree
+		conds = append(conds, filterCondition{
+			SQL:  fmt.Sprintf("%s BETWEEN ? AND ?", lat),
+			Args: []interface{}{p.Lat - dLat, p.Lat + dLat},
+		})
+		// Longitude degrees shrink towards the poles; near them, or across
+		// the antimeridian, only the latitude band is prefiltered
+		if cos := math.Cos(radians(p.Lat)); cos > 0.01 {
+			dLng := dLat / cos
+			if p.Lng-dLng >= -180 && p.Lng+dLng <= 180 {
+				conds = append(conds, filterCondition{
+					SQL:  fmt.Sprintf("%s BETWEEN ? AND ?", lng),
+					Args: []interface{}{p.Lng - dLng, p.Lng + dLng},
+				})
+			}
+		}
+		conds = append(conds, filterCondition{
+			SQL: fmt.Sprintf("2 * %v * ASIN(SQRT(LEAST(1, "+
+				"POWER(SIN(RADIANS(%s - ?) / 2), 2) + "+
+				"COS(RADIANS(?)) * COS(RADIANS(%s)) * POWER(SIN(RADIANS(%s - ?) / 2), 2)))) <= ?",
+				earthRadiusKm, lat, lat, lng),
+			Args: []interface{}{p.Lat, p.Lat, p.Lng, params.RadiusKm},
+		})
+	}
+	return conds, nil
+}
+
+// coordinateColumns returns the DB columns behind the latitude and
+// longitude fields, if the model has both
+func coordinateColumns(columns map[string]reportColumn) (string, string, bool) {
+	lat, okLat := columns[LatitudeField]
+	lng, okLng := columns[LongitudeField]
+	if !okLat || !okLng || lat.Kind != kindNumber || lng.Kind != kindNumber {
+		return "", "", false
+	}
+	return lat.DBName, lng.DBName, true
+}
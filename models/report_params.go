@@ -3,7 +3,9 @@ package models
 import (
 	"database/sql"
 	"fmt"
+	"math"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -17,8 +19,15 @@ type ReportParams struct {
 	FromDate   string `validate:"omitempty,datetime"`
 	ToDate     string `validate:"omitempty,datetime"`
 	Fields     []string
-	Filters    map[string]interface{} // Generic filters for any field
-	DateColumn string                 // Configurable date column (default: "created_at")
+	Filters    []Filter // Generic filters for any field, e.g. nameOfSite[in]=A,B
+	DateColumn string   // Configurable date column (default: "created_at")
+	Sort       []SortField
+	Cursor     string    // Opaque keyset cursor from a previous nextCursor
+	UseCursor  bool      // Keyset pagination instead of page/limit
+	Format     string    // json (default), csv, xlsx or geojson
+	BBox       *BBox     // bbox=minLng,minLat,maxLng,maxLat
+	Near       *GeoPoint // near=lat,lng, together with RadiusKm
+	RadiusKm   float64
 }
 
 // ReportResponse represents the API response structure
@@ -27,12 +36,16 @@ type ReportResponse struct {
 	Page  int                      `json:"page"`
 	Limit int                      `json:"limit"`
 	Data  []map[string]interface{} `json:"data"`
+	// NextCursor is set in cursor mode while more rows remain
+	NextCursor string `json:"nextCursor,omitempty"`
 }
 
 // ReportService provides generic reporting functionality for any GORM model
 type ReportService[T any] struct {
-	db    *gorm.DB
-	model T
+	db      *gorm.DB
+	model   T
+	scope   SiteScope
+	deleted bool
 }
 
 // NewReportService creates a new generic report service
@@ -40,16 +53,24 @@ func NewReportService[T any](db *gorm.DB, model T) *ReportService[T] {
 	return &ReportService[T]{
 		db:    db,
 		model: model,
+		scope: AllSites(),
 	}
 }
 
+// WithSiteScope limits every report of a SiteScoped model to the sites in
+// scope
+func (s *ReportService[T]) WithSiteScope(scope SiteScope) *ReportService[T] {
+	s.scope = scope
+	return s
+}
+
 // ParseReportParams extracts and validates query parameters from HTTP request
 func ParseReportParams(r *http.Request) (*ReportParams, error) {
 	params := &ReportParams{
 		Page:       1,
 		Limit:      10,
-		Filters:    make(map[string]interface{}),
 		DateColumn: "created_at", // default date column
+		Format:     FormatJSON,
 	}
 
 	query := r.URL.Query()
@@ -97,20 +118,76 @@ func ParseReportParams(r *http.Request) (*ReportParams, error) {
 		params.DateColumn = strings.TrimSpace(dateCol)
 	}
 
+	// Parse sort order, e.g. sort=-submittedAt,nameOfSite
+	if sortStr := query.Get("sort"); sortStr != "" {
+		sort, err := parseSort(sortStr)
+		if err != nil {
+			return nil, err
+		}
+		params.Sort = sort
+	}
+
+	// An empty cursor= asks for the first page in keyset mode
+	if query.Has("cursor") {
+		params.UseCursor = true
+		params.Cursor = strings.TrimSpace(query.Get("cursor"))
+	}
+
+	// Parse output format
+	if format := strings.ToLower(strings.TrimSpace(query.Get("format"))); format != "" {
+		if !reportFormats[format] {
+			return nil, fmt.Errorf("invalid format parameter: %s (must be json, csv, xlsx or geojson)", format)
+		}
+		params.Format = format
+	}
+
+	// Parse location filters
+	if bbox := strings.TrimSpace(query.Get("bbox")); bbox != "" {
+		b, err := parseBBox(bbox)
+		if err != nil {
+			return nil, err
+		}
+		params.BBox = b
+	}
+	near := strings.TrimSpace(query.Get("near"))
+	radius := strings.TrimSpace(query.Get("radiusKm"))
+	if near != "" || radius != "" {
+		if near == "" {
+			return nil, &ParamError{Param: "radiusKm", Message: "needs near=lat,lng"}
+		}
+		p, err := parseNear(near)
+		if err != nil {
+			return nil, err
+		}
+		km, err := strconv.ParseFloat(radius, 64)
+		if err != nil || !(km > 0) || math.IsInf(km, 0) {
+			return nil, &ParamError{Param: "radiusKm", Message: "must be a positive number of kilometres"}
+		}
+		params.Near, params.RadiusKm = p, km
+	}
+
 	// Parse generic filters (any other query parameters)
 	reservedParams := map[string]bool{
 		"page": true, "limit": true, "fields": true,
 		"fromDate": true, "toDate": true, "dateColumn": true,
+		"sort": true, "cursor": true, "format": true,
+		"bbox": true, "near": true, "radiusKm": true,
 	}
 
 	for key, values := range query {
 		if !reservedParams[key] && len(values) > 0 {
 			value := strings.TrimSpace(values[0])
-			if value != "" {
-				params.Filters[key] = value
+			if value == "" {
+				continue
+			}
+			field, op, err := parseFilterKey(key)
+			if err != nil {
+				return nil, err
 			}
+			params.Filters = append(params.Filters, Filter{Field: field, Op: op, Value: value})
 		}
 	}
+	sortFilters(params.Filters)
 
 	return params, nil
 }
@@ -157,55 +234,177 @@ func (p *ReportParams) HasDateFilter() bool {
 
 // HasFilters returns true if any filters are applied
 func (p *ReportParams) HasFilters() bool {
-	return p.HasDateFilter() || len(p.Filters) > 0
+	return p.HasDateFilter() || len(p.Filters) > 0 || p.BBox != nil || p.Near != nil
 }
 
-// GetReport fetches report data using the provided parameters
-func (s *ReportService[T]) GetReport(params *ReportParams) (*ReportResponse, error) {
+// reportPlan is the validated form of ReportParams for one model
+type reportPlan struct {
+	jsonToDB map[string]string
+	columns  map[string]reportColumn
+	conds    []filterCondition
+	dateCol  string
+	sortCols []sortColumn
+	keyset   *filterCondition
+}
+
+// OnlyDeleted reports on the soft-deleted rows instead, with their
+// deletedAt
+func (s *ReportService[T]) OnlyDeleted() *ReportService[T] {
+	s.deleted = true
+	return s
+}
+
+// base is the query every report and count starts from
+func (s *ReportService[T]) base() *gorm.DB {
+	if s.deleted {
+		return s.db.Unscoped().Model(&s.model).Where("deleted_at IS NOT NULL")
+	}
+	return s.db.Model(&s.model)
+}
+
+// plan resolves params against the model schema. Anything the client got
+// wrong comes back as a *ParamError so bad input never reaches SQL.
+func (s *ReportService[T]) plan(params *ReportParams) (*reportPlan, error) {
 	// Get JSON to DB column mapping using your existing function
 	jsonToDB, err := BuildJSONtoDBColumnMap(s.db, s.model)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get column mapping: %w", err)
 	}
+	if s.deleted {
+		jsonToDB["deletedAt"] = "deleted_at"
+	}
+	columns, err := buildReportColumns(s.db, s.model)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get column mapping: %w", err)
+	}
+
+	conds, err := buildFilterConditions(params.Filters, columns)
+	if err != nil {
+		return nil, err
+	}
+	geoConds, err := geoConditions(params, columns)
+	if err != nil {
+		return nil, err
+	}
+	conds = append(conds, geoConds...)
+	siteCond, err := siteCondition(s.db, s.model, s.scope)
+	if err != nil {
+		return nil, err
+	}
+	if siteCond != nil {
+		conds = append(conds, *siteCond)
+	}
+	// The date column only matters to a date filter; the default
+	// created_at is not visible on every model
+	var dateCol string
+	if params.HasDateFilter() {
+		if dateCol, err = resolveDateColumn(s.db, s.model, params.DateColumn, columns); err != nil {
+			return nil, err
+		}
+	}
+	sortCols, err := buildSortColumns(params.Sort, columns, params.UseCursor)
+	if err != nil {
+		return nil, err
+	}
+	var keyset *filterCondition
+	if params.UseCursor && params.Cursor != "" {
+		cond, err := keysetCondition(params.Cursor, params.Sort, sortCols)
+		if err != nil {
+			return nil, err
+		}
+		keyset = &cond
+	}
+
+	return &reportPlan{
+		jsonToDB: jsonToDB,
+		columns:  columns,
+		conds:    conds,
+		dateCol:  dateCol,
+		sortCols: sortCols,
+		keyset:   keyset,
+	}, nil
+}
+
+// GetReport fetches report data using the provided parameters
+func (s *ReportService[T]) GetReport(params *ReportParams) (*ReportResponse, error) {
+	plan, err := s.plan(params)
+	if err != nil {
+		return nil, err
+	}
 
 	// Build base query
-	query := s.db.Model(&s.model)
+	query := s.base()
 
-	// Apply field selection
+	// Apply field selection. Cursor mode needs the sort columns to build
+	// nextCursor; they are dropped again from the output below.
+	var extraFields []string
 	if len(params.Fields) > 0 {
-		dbFields := s.getDBFields(params.Fields, jsonToDB)
+		dbFields := s.getDBFields(params.Fields, plan.jsonToDB)
 		if len(dbFields) > 0 {
+			if params.UseCursor {
+				for _, c := range plan.sortCols {
+					if !slices.Contains(dbFields, c.DBName) {
+						dbFields = append(dbFields, c.DBName)
+						extraFields = append(extraFields, c.JSONName)
+					}
+				}
+			}
 			query = query.Select(dbFields)
 		}
 	}
 
 	// Apply filters
-	query = s.applyFilters(query, params, jsonToDB)
+	query = s.applyFilters(query, params, plan)
+	query = query.Order(orderBy(plan.sortCols))
 
 	// Execute main query
-	rows, err := query.Limit(params.Limit).Offset(params.GetOffset()).Rows()
+	if params.UseCursor {
+		if plan.keyset != nil {
+			query = query.Where(plan.keyset.SQL, plan.keyset.Args...)
+		}
+		// One extra row tells us whether another page exists
+		query = query.Limit(params.Limit + 1)
+	} else {
+		query = query.Limit(params.Limit).Offset(params.GetOffset())
+	}
+	rows, err := query.Rows()
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute query: %w", err)
 	}
 	defer rows.Close()
 
 	// Process results
-	results, err := s.processRows(rows, jsonToDB)
+	results, err := s.processRows(rows, plan.jsonToDB)
 	if err != nil {
 		return nil, fmt.Errorf("failed to process rows: %w", err)
 	}
 
+	var nextCursor string
+	if params.UseCursor && len(results) > params.Limit {
+		results = results[:params.Limit]
+		nextCursor, err = encodeCursor(params.Sort, plan.sortCols, results[len(results)-1])
+		if err != nil {
+			return nil, fmt.Errorf("failed to build cursor: %w", err)
+		}
+	}
+	for _, row := range results {
+		for _, f := range extraFields {
+			delete(row, f)
+		}
+	}
+
 	// Get total count with same filters
-	total, err := s.getTotalCount(params, jsonToDB)
+	total, err := s.getTotalCount(params, plan)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get total count: %w", err)
 	}
 
 	return &ReportResponse{
-		Total: total,
-		Page:  params.Page,
-		Limit: params.Limit,
-		Data:  results,
+		Total:      total,
+		Page:       params.Page,
+		Limit:      params.Limit,
+		Data:       results,
+		NextCursor: nextCursor,
 	}, nil
 }
 
@@ -220,28 +419,47 @@ func (s *ReportService[T]) getDBFields(fields []string, jsonToDB map[string]stri
 	return dbFields
 }
 
+// resolveDateColumn accepts either the JSON name or the DB column of a
+// date/time field and returns the DB column. DB columns are looked up in
+// the whole schema, so fields hidden from JSON such as a created_at tagged
+// json:"-" can still be filtered on.
+func resolveDateColumn(db *gorm.DB, model interface{}, name string, columns map[string]reportColumn) (string, error) {
+	if col, ok := columns[name]; ok {
+		if col.Kind != kindTime {
+			return "", &ParamError{Param: "dateColumn", Message: fmt.Sprintf("%q is not a date field", name)}
+		}
+		return col.DBName, nil
+	}
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return "", err
+	}
+	if field, ok := stmt.Schema.FieldsByDBName[name]; ok {
+		if columnKind(field) != kindTime {
+			return "", &ParamError{Param: "dateColumn", Message: fmt.Sprintf("%q is not a date field", name)}
+		}
+		return field.DBName, nil
+	}
+	return "", &ParamError{Param: "dateColumn", Message: fmt.Sprintf("unknown date field %q", name)}
+}
+
 // applyFilters applies all filters to the database query
-func (s *ReportService[T]) applyFilters(query *gorm.DB, params *ReportParams, jsonToDB map[string]string) *gorm.DB {
+func (s *ReportService[T]) applyFilters(query *gorm.DB, params *ReportParams, plan *reportPlan) *gorm.DB {
+	dateCol := plan.dateCol
 	// Apply date filters
 	if params.HasDateFilter() {
 		if params.FromDate != "" && params.ToDate != "" {
-			query = query.Where(params.DateColumn+" BETWEEN ? AND ?", params.FromDate, params.ToDate)
+			query = query.Where(dateCol+" BETWEEN ? AND ?", params.FromDate, params.ToDate)
 		} else if params.FromDate != "" {
-			query = query.Where(params.DateColumn+" >= ?", params.FromDate)
+			query = query.Where(dateCol+" >= ?", params.FromDate)
 		} else if params.ToDate != "" {
-			query = query.Where(params.DateColumn+" <= ?", params.ToDate)
+			query = query.Where(dateCol+" <= ?", params.ToDate)
 		}
 	}
 
 	// Apply generic filters
-	for jsonField, value := range params.Filters {
-		if dbCol, ok := jsonToDB[jsonField]; ok {
-			// Use the DB column name for the query
-			query = query.Where(dbCol+" = ?", value)
-		} else {
-			// If not in mapping, try using the field name directly
-			query = query.Where(jsonField+" = ?", value)
-		}
+	for _, cond := range plan.conds {
+		query = query.Where(cond.SQL, cond.Args...)
 	}
 
 	return query
@@ -293,9 +511,9 @@ func (s *ReportService[T]) findJSONField(dbCol string, jsonToDB map[string]strin
 }
 
 // getTotalCount gets the total count with the same filters applied
-func (s *ReportService[T]) getTotalCount(params *ReportParams, jsonToDB map[string]string) (int64, error) {
-	countQuery := s.db.Model(&s.model)
-	countQuery = s.applyFilters(countQuery, params, jsonToDB)
+func (s *ReportService[T]) getTotalCount(params *ReportParams, plan *reportPlan) (int64, error) {
+	countQuery := s.base()
+	countQuery = s.applyFilters(countQuery, params, plan)
 
 	var total int64
 	if err := countQuery.Count(&total).Error; err != nil {
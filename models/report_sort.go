@@ -0,0 +1,202 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// SortField is one entry of the sort parameter, e.g. "-submittedAt"
+type SortField struct {
+	Field string // JSON field name
+	Desc  bool
+}
+
+// sortColumn is a validated SortField resolved to its DB column
+type sortColumn struct {
+	JSONName string
+	DBName   string
+	Kind     string
+	Desc     bool
+}
+
+// reportCursor is the decoded form of the opaque cursor/nextCursor value.
+// It remembers the sort it was issued for so a cursor cannot be replayed
+// against a different ordering.
+type reportCursor struct {
+	Sort   string   `json:"s"`
+	Values []string `json:"v"`
+}
+
+// parseSort reads "sort=-submittedAt,nameOfSite" into SortFields
+func parseSort(raw string) ([]SortField, error) {
+	var out []SortField
+	seen := map[string]bool{}
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		field := SortField{Field: part}
+		if strings.HasPrefix(part, "-") {
+			field = SortField{Field: part[1:], Desc: true}
+		} else if strings.HasPrefix(part, "+") {
+			field.Field = part[1:]
+		}
+		if field.Field == "" {
+			return nil, &ParamError{Param: "sort", Message: fmt.Sprintf("malformed sort entry %q", part)}
+		}
+		if seen[field.Field] {
+			return nil, &ParamError{Param: "sort", Message: fmt.Sprintf("field %q listed more than once", field.Field)}
+		}
+		seen[field.Field] = true
+		out = append(out, field)
+	}
+	return out, nil
+}
+
+// sortString renders SortFields back to the query form
+func sortString(fields []SortField) string {
+	parts := make([]string, len(fields))
+	for i, f := range fields {
+		if f.Desc {
+			parts[i] = "-" + f.Field
+		} else {
+			parts[i] = f.Field
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// buildSortColumns validates the requested sort against the model and always
+// finishes with the primary key so the ordering is total and stable. With no
+// sort, newest records come first.
+func buildSortColumns(fields []SortField, columns map[string]reportColumn, keyset bool) ([]sortColumn, error) {
+	if len(fields) == 0 {
+		for jsonName, col := range columns {
+			if col.DBName == "created_at" && col.Kind == kindTime && !col.Nullable {
+				fields = []SortField{{Field: jsonName, Desc: true}}
+				break
+			}
+		}
+	}
+
+	var out []sortColumn
+	var pk *sortColumn
+	for jsonName, col := range columns {
+		if col.PrimaryKey {
+			pk = &sortColumn{JSONName: jsonName, DBName: col.DBName, Kind: col.Kind}
+			break
+		}
+	}
+
+	hasPK := false
+	for _, f := range fields {
+		col, ok := columns[f.Field]
+		if !ok {
+			return nil, &ParamError{Param: "sort", Message: fmt.Sprintf("unknown field %q", f.Field)}
+		}
+		if col.Kind == kindOther {
+			return nil, &ParamError{Param: "sort", Message: fmt.Sprintf("field %q cannot be sorted", f.Field)}
+		}
+		// NULLs break keyset comparisons, so only non-nullable columns qualify
+		if keyset && col.Nullable {
+			return nil, &ParamError{Param: "sort", Message: fmt.Sprintf("optional field %q cannot be used with cursor pagination", f.Field)}
+		}
+		if col.PrimaryKey {
+			hasPK = true
+		}
+		out = append(out, sortColumn{JSONName: f.Field, DBName: col.DBName, Kind: col.Kind, Desc: f.Desc})
+	}
+	if !hasPK && pk != nil {
+		out = append(out, *pk)
+	}
+	return out, nil
+}
+
+// orderBy converts sort columns to a GORM ORDER BY clause
+func orderBy(cols []sortColumn) clause.OrderBy {
+	order := clause.OrderBy{}
+	for _, c := range cols {
+		order.Columns = append(order.Columns, clause.OrderByColumn{Column: clause.Column{Name: c.DBName}, Desc: c.Desc})
+	}
+	return order
+}
+
+// encodeCursor builds the nextCursor value from the last row of a page
+func encodeCursor(sort []SortField, cols []sortColumn, row map[string]interface{}) (string, error) {
+	cur := reportCursor{Sort: sortString(sort)}
+	for _, c := range cols {
+		v, ok := row[c.JSONName]
+		if !ok {
+			return "", fmt.Errorf("cursor column %s missing from result", c.DBName)
+		}
+		cur.Values = append(cur.Values, cursorValueString(v))
+	}
+	b, err := json.Marshal(cur)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func cursorValueString(v interface{}) string {
+	switch t := v.(type) {
+	case time.Time:
+		return t.Format(time.RFC3339Nano)
+	case []byte:
+		return string(t)
+	case nil:
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+// keysetCondition decodes a cursor and returns the WHERE clause selecting
+// the rows after it, e.g. (a > ?) OR (a = ? AND id > ?).
+func keysetCondition(raw string, sort []SortField, cols []sortColumn) (filterCondition, error) {
+	invalid := &ParamError{Param: "cursor", Message: "invalid or expired cursor"}
+
+	b, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return filterCondition{}, invalid
+	}
+	var cur reportCursor
+	if err := json.Unmarshal(b, &cur); err != nil || len(cur.Values) != len(cols) {
+		return filterCondition{}, invalid
+	}
+	if cur.Sort != sortString(sort) {
+		return filterCondition{}, &ParamError{Param: "cursor", Message: "cursor was issued for a different sort order"}
+	}
+
+	values := make([]interface{}, len(cols))
+	for i, c := range cols {
+		v, err := convertFilterValue(cur.Values[i], c.Kind)
+		if err != nil {
+			return filterCondition{}, invalid
+		}
+		values[i] = v
+	}
+
+	var ors []string
+	var args []interface{}
+	for i, c := range cols {
+		var ands []string
+		for j := 0; j < i; j++ {
+			ands = append(ands, cols[j].DBName+" = ?")
+			args = append(args, values[j])
+		}
+		op := ">"
+		if c.Desc {
+			op = "<"
+		}
+		ands = append(ands, c.DBName+" "+op+" ?")
+		args = append(args, values[i])
+		ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+	}
+	return filterCondition{SQL: "(" + strings.Join(ors, " OR ") + ")", Args: args}, nil
+}
@@ -0,0 +1,129 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func sortColumnNames(cols []sortColumn) []string {
+	var names []string
+	for _, c := range cols {
+		name := c.DBName
+		if c.Desc {
+			name = "-" + name
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+func TestBuildSortColumns(t *testing.T) {
+	columns, err := buildReportColumns(dryRunDB(t), Diesel{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		sort string
+		want []string
+	}{
+		{"newest first by default", "", []string{"-created_at", "id"}},
+		{"ends with the primary key", "-submittedAt,nameOfSite", []string{"-submitted_at", "name_of_site", "id"}},
+		{"primary key not repeated", "-id", []string{"-id"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields, err := parseSort(tt.sort)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cols, err := buildSortColumns(fields, columns, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := sortColumnNames(cols); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sort %q = %v, want %v", tt.sort, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	columns, err := buildReportColumns(dryRunDB(t), Diesel{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort, err := parseSort("-submittedAt,nameOfSite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cols, err := buildSortColumns(sort, columns, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	submitted := time.Date(2026, 10, 1, 9, 30, 15, 123456789, time.UTC)
+	row := map[string]interface{}{
+		"submittedAt": submitted,
+		"nameOfSite":  "Yard A",
+		"id":          "6f9619ff-8b86-d011-b42d-00c04fc964ff",
+	}
+	cursor, err := encodeCursor(sort, cols, row)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cond, err := keysetCondition(cursor, sort, cols)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantSQL := "((submitted_at < ?) OR (submitted_at = ? AND name_of_site > ?) OR (submitted_at = ? AND name_of_site = ? AND id > ?))"
+	if cond.SQL != wantSQL {
+		t.Errorf("SQL = %s, want %s", cond.SQL, wantSQL)
+	}
+	wantArgs := []interface{}{submitted, submitted, "Yard A", submitted, "Yard A", row["id"]}
+	if len(cond.Args) != len(wantArgs) {
+		t.Fatalf("Args = %v, want %v", cond.Args, wantArgs)
+	}
+	for i, want := range wantArgs {
+		got := cond.Args[i]
+		if gt, ok := got.(time.Time); ok {
+			if !gt.Equal(want.(time.Time)) {
+				t.Errorf("Args[%d] = %v, want %v", i, got, want)
+			}
+		} else if got != want {
+			t.Errorf("Args[%d] = %v, want %v", i, got, want)
+		}
+	}
+
+	// A cursor only fits the sort it was issued for
+	other, _ := parseSort("nameOfSite")
+	otherCols, err := buildSortColumns(other, columns, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, raw := range map[string]string{
+		"other sort":  cursor,
+		"not base64":  "!!!",
+		"not json":    "bm90IGpzb24",
+		"wrong count": "eyJzIjoibmFtZU9mU2l0ZSIsInYiOlsiWWFyZCBBIl19",
+	} {
+		if _, err := keysetCondition(raw, other, otherCols); err == nil {
+			t.Errorf("%s: keysetCondition accepted %q", name, raw)
+		}
+	}
+}
+
+func TestKeysetSortRejectsNullableColumns(t *testing.T) {
+	columns, err := buildReportColumns(dryRunDB(t), Diesel{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields, _ := parseSort("remarks")
+	if _, err := buildSortColumns(fields, columns, false); err != nil {
+		t.Errorf("page mode sort on an optional field = %v, want nil", err)
+	}
+	if _, err := buildSortColumns(fields, columns, true); err == nil {
+		t.Error("cursor mode accepted a sort on an optional field")
+	}
+}
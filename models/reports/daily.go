@@ -0,0 +1,85 @@
+package reports
+
+// DailyProgress is the consolidated Daily Progress Report for one site and day
+type DailyProgress struct {
+	Site string `json:"site"`
+	Date string `json:"date"` // YYYY-MM-DD
+
+	Progress        []ProgressEntry `json:"progress"` // DPR entries
+	TotalMetersLaid float64         `json:"totalMetersLaid"`
+
+	Labour               []LabourEntry `json:"labour"` // MNR entries
+	TotalSkilledLabour   int           `json:"totalSkilledLabour"`
+	TotalUnskilledLabour int           `json:"totalUnskilledLabour"`
+	TotalWomen           int           `json:"totalWomen"`
+
+	Equipment           []EquipmentEntry `json:"equipment"` // NMR vehicles and vehicle logs
+	TotalEquipmentHours float64          `json:"totalEquipmentHours"`
+
+	Diesel            []DieselEntry `json:"diesel"`
+	TotalDieselLiters float64       `json:"totalDieselLiters"`
+	TotalDieselAmount float64       `json:"totalDieselAmount"`
+
+	Stock []StockEntry `json:"stock"` // IN/OUT movements at the site's yard
+
+	Water            []WaterEntry `json:"water"`
+	TotalWaterLiters float64      `json:"totalWaterLiters"`
+
+	Photos []PhotoRef `json:"photos"`
+}
+
+type ProgressEntry struct {
+	ChainageFrom string  `json:"chainageFrom"`
+	ChainageTo   string  `json:"chainageTo"`
+	MetersLaid   float64 `json:"metersLaid"`
+	TypeOfWorks  string  `json:"typeOfWorks"`
+	PipeDia      string  `json:"pipeDia"`
+	Contractor   string  `json:"contractor"`
+	SiteEngineer string  `json:"siteEngineer"`
+}
+
+type LabourEntry struct {
+	Contractor      string `json:"contractor"`
+	WorkDescription string `json:"workDescription"`
+	Skilled         int    `json:"skilled"`
+	Unskilled       int    `json:"unskilled"`
+	Women           int    `json:"women"`
+}
+
+type EquipmentEntry struct {
+	Source      string  `json:"source"` // "nmr_vehicle" or "vehicle_log"
+	VehicleType string  `json:"vehicleType"`
+	Vehicle     string  `json:"vehicle"`
+	Hours       float64 `json:"hours"`
+	Description string  `json:"description"`
+}
+
+type DieselEntry struct {
+	Vehicle    string  `json:"vehicle"`
+	ToWhom     string  `json:"toWhom"`
+	CardNumber string  `json:"cardNumber"`
+	Liters     float64 `json:"liters"`
+	Amount     float64 `json:"amount"`
+}
+
+type StockEntry struct {
+	InOut       string  `json:"inOut"`
+	Item        string  `json:"item"`
+	PipeDia     string  `json:"pipeDia"`
+	Quantity    float64 `json:"quantity"`
+	TotalLength string  `json:"totalLength"`
+	Vehicle     string  `json:"vehicle"`
+}
+
+type WaterEntry struct {
+	Tanker   string  `json:"tanker"`
+	Supplier string  `json:"supplier"`
+	Purpose  string  `json:"purpose"`
+	Liters   float64 `json:"liters"`
+}
+
+type PhotoRef struct {
+	Module string `json:"module"`
+	Label  string `json:"label"`
+	URL    string `json:"url"`
+}
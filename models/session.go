@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Session is one login of a user. Its refresh token rotates on every use:
+// only the SHA-256 of the current and the previous token are stored, and
+// presenting an older token again revokes the whole session.
+type Session struct {
+	ID            uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID        uuid.UUID  `gorm:"type:uuid;not null;index" json:"userId"`
+	RefreshHash   string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	PreviousHash  string     `gorm:"size:64;index" json:"-"`
+	RotatedAt     time.Time  `json:"rotatedAt"`
+	ExpiresAt     time.Time  `gorm:"not null" json:"expiresAt"`
+	LastUsedAt    time.Time  `json:"lastUsedAt"`
+	UserAgent     string     `gorm:"size:255" json:"userAgent"`
+	IP            string     `gorm:"size:64" json:"ip"`
+	RevokedAt     *time.Time `json:"revokedAt,omitempty"`
+	RevokedReason string     `gorm:"size:100" json:"revokedReason,omitempty"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	UpdatedAt     time.Time  `json:"updatedAt"`
+}
+
+// Reasons recorded when a session is revoked
+const (
+	RevokedLogout   = "logout"
+	RevokedAdmin    = "revoked by admin"
+	RevokedReuse    = "refresh token reused"
+	RevokedInactive = "user inactive"
+)
@@ -0,0 +1,208 @@
+package models
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+// Site is a work site or yard. Records name their site in free text
+// (NameOfSite, SiteName or YardName), so sites are matched by name, ignoring
+// case and surrounding spaces.
+type Site struct {
+	ID      uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	Name    string         `gorm:"size:200;not null;uniqueIndex" json:"name" validate:"required,max=200"`
+	Cluster string         `gorm:"size:100;index" json:"cluster" validate:"max=100"`
+	Aliases pq.StringArray `gorm:"type:text[]" json:"aliases" validate:"dive,max=200" swaggertype:"array,string"`
+	// Pipeline chainage the site covers, in metres
+	ChainageFrom *float64 `json:"chainageFrom,omitempty" validate:"omitempty,gte=0"`
+	ChainageTo   *float64 `json:"chainageTo,omitempty" validate:"omitempty,gte=0"`
+	// Geofence: submissions are expected within RadiusMeters of the centre
+	Latitude     *float64 `json:"latitude,omitempty" validate:"omitempty,latitude"`
+	Longitude    *float64 `json:"longitude,omitempty" validate:"omitempty,longitude"`
+	RadiusMeters *float64 `json:"radiusMeters,omitempty" validate:"omitempty,gt=0"`
+	// Boundary, when set, is the geofence instead of the circle
+	Boundary  GeoPolygon `gorm:"type:jsonb" json:"boundary,omitempty" validate:"omitempty,min=3,dive,lnglat" swaggertype:"array,number"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+}
+
+// UserSite assigns a user to one site
+type UserSite struct {
+	UserID    uuid.UUID `gorm:"type:uuid;primaryKey" json:"userId"`
+	SiteName  string    `gorm:"size:200;primaryKey" json:"siteName"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// UserCluster assigns a user to every site of a cluster, e.g. a coordinator
+type UserCluster struct {
+	UserID    uuid.UUID `gorm:"type:uuid;primaryKey" json:"userId"`
+	Cluster   string    `gorm:"size:100;primaryKey" json:"cluster"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// SiteScoped is implemented by models that belong to a site. SiteField
+// names the Go field holding the site name.
+type SiteScoped interface {
+	SiteField() string
+}
+
+// SiteScope is the set of sites a request may see and submit for
+type SiteScope struct {
+	All   bool
+	Sites []string // normalized with NormalizeSiteName
+}
+
+// AllSites is the scope of users and clients with no site restriction
+func AllSites() SiteScope {
+	return SiteScope{All: true}
+}
+
+// RestrictSites limits a scope to the named sites
+func RestrictSites(names []string) SiteScope {
+	scope := SiteScope{Sites: []string{}}
+	seen := map[string]bool{}
+	for _, n := range names {
+		n = NormalizeSiteName(n)
+		if n != "" && !seen[n] {
+			seen[n] = true
+			scope.Sites = append(scope.Sites, n)
+		}
+	}
+	return scope
+}
+
+// WithSiteAliases adds the aliases of the named sites to names, so records
+// filed under an alias stay visible to the users of the site
+func WithSiteAliases(db *gorm.DB, names []string) ([]string, error) {
+	normalized := RestrictSites(names).Sites
+	if len(normalized) == 0 {
+		return names, nil
+	}
+	var aliases []pq.StringArray
+	err := db.Model(&Site{}).Where("LOWER(TRIM(name)) IN ?", normalized).Pluck("aliases", &aliases).Error
+	if err != nil {
+		return nil, err
+	}
+	all := append([]string{}, names...)
+	for _, a := range aliases {
+		all = append(all, a...)
+	}
+	return all, nil
+}
+
+// NormalizeSiteName is the form site names are compared in
+func NormalizeSiteName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// Allows reports whether site is inside the scope
+func (s SiteScope) Allows(site string) bool {
+	if s.All {
+		return true
+	}
+	site = NormalizeSiteName(site)
+	for _, allowed := range s.Sites {
+		if allowed == site {
+			return true
+		}
+	}
+	return false
+}
+
+// Intersect returns the sites allowed by both scopes
+func (s SiteScope) Intersect(other SiteScope) SiteScope {
+	if s.All {
+		return other
+	}
+	if other.All {
+		return s
+	}
+	var both []string
+	for _, site := range s.Sites {
+		if other.Allows(site) {
+			both = append(both, site)
+		}
+	}
+	return RestrictSites(both)
+}
+
+// RecordSite returns the site of a SiteScoped record. ok is false for
+// models that do not belong to a site.
+func RecordSite(record interface{}) (site string, jsonName string, ok bool) {
+	scoped, isScoped := record.(SiteScoped)
+	if !isScoped {
+		return "", "", false
+	}
+	v := reflect.Indirect(reflect.ValueOf(record))
+	f, found := v.Type().FieldByName(scoped.SiteField())
+	if !found {
+		return "", "", false
+	}
+	jsonName = strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+	field := v.FieldByIndex(f.Index)
+	if field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			return "", jsonName, true
+		}
+		field = field.Elem()
+	}
+	return field.String(), jsonName, true
+}
+
+// CheckRecordSite returns a ValidationError when record belongs to a site
+// outside scope. Records of models without a site are only open to
+// unrestricted scopes.
+func CheckRecordSite(record interface{}, scope SiteScope) error {
+	if scope.All {
+		return nil
+	}
+	site, jsonName, ok := RecordSite(record)
+	if !ok {
+		return ValidationError{{
+			Code:    "site",
+			Message: "these records belong to no site; only users with access to all sites may submit them",
+		}}
+	}
+	if scope.Allows(site) {
+		return nil
+	}
+	return ValidationError{{
+		Field:   jsonName,
+		Code:    "site",
+		Message: fmt.Sprintf("you are not assigned to site %q", site),
+	}}
+}
+
+// siteCondition restricts a query on model to the sites in scope. It
+// returns nil when no restriction applies. A restricted scope sees nothing
+// of models without a site.
+func siteCondition(db *gorm.DB, model interface{}, scope SiteScope) (*filterCondition, error) {
+	if scope.All {
+		return nil, nil
+	}
+	scoped, ok := model.(SiteScoped)
+	if !ok {
+		return &filterCondition{SQL: "1 = 0"}, nil
+	}
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return nil, err
+	}
+	field := stmt.Schema.LookUpField(scoped.SiteField())
+	if field == nil {
+		return nil, fmt.Errorf("model %s has no site field %s", stmt.Schema.Name, scoped.SiteField())
+	}
+	if len(scope.Sites) == 0 {
+		return &filterCondition{SQL: "1 = 0"}, nil
+	}
+	return &filterCondition{
+		SQL:  "LOWER(TRIM(" + field.DBName + ")) IN ?",
+		Args: []interface{}{scope.Sites},
+	}, nil
+}
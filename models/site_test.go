@@ -0,0 +1,67 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckRecordSite(t *testing.T) {
+	restricted := RestrictSites([]string{" Yard A ", "site b"})
+	tests := []struct {
+		name   string
+		record interface{}
+		scope  SiteScope
+		ok     bool
+	}{
+		{"assigned site", &Contractor{SiteName: "YARD A"}, restricted, true},
+		{"other site", &Contractor{SiteName: "Yard C"}, restricted, false},
+		{"no site restriction", &Contractor{SiteName: "Yard C"}, AllSites(), true},
+		{"model without a site", &Eway{}, restricted, false},
+		{"model without a site, unrestricted", &Eway{}, AllSites(), true},
+		{"no sites at all", &Contractor{SiteName: "Yard A"}, RestrictSites(nil), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckRecordSite(tt.record, tt.scope)
+			if tt.ok && err != nil {
+				t.Errorf("CheckRecordSite = %v, want nil", err)
+			}
+			var verr ValidationError
+			if !tt.ok && !errors.As(err, &verr) {
+				t.Errorf("CheckRecordSite = %v, want a ValidationError", err)
+			}
+		})
+	}
+}
+
+func TestSiteCondition(t *testing.T) {
+	db := dryRunDB(t)
+	restricted := RestrictSites([]string{"Yard A"})
+	tests := []struct {
+		name  string
+		model interface{}
+		scope SiteScope
+		want  string
+	}{
+		{"unrestricted", Contractor{}, AllSites(), ""},
+		{"site model", Contractor{}, restricted, "LOWER(TRIM(site_name)) IN ?"},
+		{"no assigned sites", Contractor{}, RestrictSites(nil), "1 = 0"},
+		{"model without a site", Eway{}, restricted, "1 = 0"},
+		{"model without a site, unrestricted", Task{}, AllSites(), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cond, err := siteCondition(db, tt.model, tt.scope)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := ""
+			if cond != nil {
+				got = cond.SQL
+			}
+			if got != tt.want {
+				t.Errorf("siteCondition = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
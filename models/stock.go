@@ -11,31 +11,37 @@ import (
 // StockReport represents one “stock” form submission.
 type Stock struct {
 	ID                     uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
-	InOut                  string         `gorm:"column:in_out;not null"               json:"inOut"`
-	YardName               string         `gorm:"column:yard_name;not null"            json:"yardName"`
-	InvoiceDate            JSONTime       `gorm:"column:invoice_date;not null"         json:"invoiceDate"`
+	InOut                  string         `gorm:"column:in_out;not null"               json:"inOut" validate:"required,oneof=IN OUT"`
+	YardName               string         `gorm:"column:yard_name;not null"            json:"yardName" validate:"required"`
+	YardID                 *uuid.UUID     `gorm:"column:yard_id;type:uuid;index"       json:"yardId,omitempty"`
+	InvoiceDate            JSONTime       `gorm:"column:invoice_date;not null"         json:"invoiceDate" validate:"required,notfuture"`
 	CompanyName            string         `gorm:"column:company_name;not null"         json:"companyName"`
-	ItemDescription        string         `gorm:"column:item_description;not null"     json:"itemDescription"`
+	ItemDescription        string         `gorm:"column:item_description;not null"     json:"itemDescription" validate:"required"`
 	SpecialItemDescription string         `gorm:"column:special_item_description;not null" json:"specialItemDescription"`
 	PipeDia                string         `gorm:"column:pipe_dia;not null"             json:"pipeDia"`
 	TotalLength            string         `gorm:"column:total_length;not null"         json:"totalLength"`
-	ItemQuantity           string         `gorm:"column:item_quantity;not null"        json:"itemQuantity"`
+	ItemQuantity           *Quantity      `gorm:"column:item_quantity;type:numeric"    json:"itemQuantity" validate:"required,gte=0"`
 	SpecialsDetail         *string        `gorm:"column:specials_detail"               json:"specialsDetail,omitempty"`
 	DefectiveMaterial      *string        `gorm:"column:defective_material"            json:"defectiveMaterial,omitempty"`
 	DefectivePhotos        datatypes.JSON `gorm:"column:defective_photos;type:jsonb;not null" json:"defectivePhotos"`
 	ContractorName         string         `gorm:"column:contractor_name;not null"      json:"contractorName"`
+	ContractorID           *uuid.UUID     `gorm:"column:contractor_id;type:uuid;index" json:"contractorId,omitempty"`
 	LabelNumber            string         `gorm:"column:label_number;not null"         json:"labelNumber"`
 	VehicleNumber          string         `gorm:"column:vehicle_number;not null"       json:"vehicleNumber"`
+	VehicleID              *uuid.UUID     `gorm:"column:vehicle_id;type:uuid;index"    json:"vehicleId,omitempty"`
 	Remarks                *string        `gorm:"column:remarks"                       json:"remarks,omitempty"`
 	YardInchargeName       string         `gorm:"column:yard_incharge_name;not null"   json:"yardInchargeName"`
 	YardInchargePhone      string         `gorm:"column:yard_incharge_phone;not null"  json:"yardInchargePhone"`
 	ChallanFiles           datatypes.JSON `gorm:"column:challan_files;type:jsonb;not null" json:"challanFiles"`
 
-	Latitude    float64  `gorm:"column:latitude;not null"             json:"latitude"`
-	Longitude   float64  `gorm:"column:longitude;not null"            json:"longitude"`
-	SubmittedAt JSONTime `gorm:"column:submitted_at;not null"         json:"submittedAt"`
+	Latitude    float64  `gorm:"column:latitude;not null"             json:"latitude" validate:"latitude"`
+	Longitude   float64  `gorm:"column:longitude;not null"            json:"longitude" validate:"longitude"`
+	SubmittedAt JSONTime `gorm:"column:submitted_at;not null"         json:"submittedAt" validate:"required,notfuture"`
 
 	CreatedAt time.Time      `gorm:"autoCreateTime"                       json:"createdAt"`
 	UpdatedAt time.Time      `gorm:"autoUpdateTime"                       json:"updatedAt"`
 	DeletedAt gorm.DeletedAt `gorm:"index"                                json:"-"`
 }
+
+// SiteField implements SiteScoped
+func (Stock) SiteField() string { return "YardName" }
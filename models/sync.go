@@ -0,0 +1,277 @@
+package models
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SyncModule describes one table the offline app can pull through /sync
+type SyncModule struct {
+	Name  string      // Name used by the app, the same as the route segment
+	Model interface{} // Zero value of the GORM model
+	// OwnerField is the Go field holding who submitted the record. Users
+	// who cannot see every record only receive rows where it matches them.
+	OwnerField string
+	// OwnerByName is set when OwnerField holds the user's name rather than
+	// their phone number
+	OwnerByName bool
+}
+
+// SyncModules lists the modules served by /sync, in pull order
+var SyncModules = []SyncModule{
+	{Name: "dprsite", Model: DprSite{}, OwnerField: "PhoneNumberOfInformationEnteredPerson"},
+	{Name: "wrapping", Model: Wrapping{}, OwnerField: "SiteEngineerPhone"},
+	{Name: "eway", Model: Eway{}, OwnerField: "EnteredBy", OwnerByName: true},
+	{Name: "water", Model: Water{}, OwnerField: "SiteEngineerPhone"},
+	{Name: "stock", Model: Stock{}, OwnerField: "YardInchargePhone"},
+	{Name: "dairysite", Model: DairySite{}, OwnerField: "SiteEngineerPhone"},
+	{Name: "payment", Model: Payment{}, OwnerField: "SiteEngineerPhone"},
+	{Name: "material", Model: Material{}, OwnerField: "PhoneNumber"},
+	{Name: "mnr", Model: Mnr{}, OwnerField: "AttendancePhone"},
+	{Name: "nmr_vehicle", Model: Nmr_Vehicle{}, OwnerField: "AttendancePhone"},
+	{Name: "contractor", Model: Contractor{}, OwnerField: "SiteEngineerPhone"},
+	{Name: "painting", Model: Painting{}, OwnerField: "PhoneNumber"},
+	{Name: "diesel", Model: Diesel{}, OwnerField: "PersonPhone"},
+	{Name: "tasks", Model: Task{}, OwnerField: "SiteEngineerPhone"},
+	{Name: "vehiclelog", Model: VehicleLog{}, OwnerField: "SiteEngineerPhone"},
+}
+
+// syncLag keeps the newest few seconds out of a pull. A transaction that
+// stamped updated_at just before a pull may not have committed yet, and
+// skipping past it would lose the change for good.
+const syncLag = 5 * time.Second
+
+// Change operations reported by a pull
+const (
+	SyncUpsert = "upsert"
+	SyncDelete = "delete"
+)
+
+// SyncChange is one changed record. Deleted records are tombstones that
+// carry no data.
+type SyncChange struct {
+	Module    string      `json:"module"`
+	ID        string      `json:"id"`
+	Op        string      `json:"op"`
+	ChangedAt time.Time   `json:"changedAt"`
+	Data      interface{} `json:"data,omitempty"`
+}
+
+// SyncResponse is one page of a pull. Watermark is passed back as `since`
+// to fetch the next page; HasMore is false once the client has caught up.
+type SyncResponse struct {
+	Changes   []SyncChange `json:"changes"`
+	Watermark string       `json:"watermark"`
+	HasMore   bool         `json:"hasMore"`
+}
+
+// SyncViewer is who is pulling. Modules for which CanReadAll returns true
+// are pulled for every site in Scope; for the others only the viewer's own
+// submissions are.
+type SyncViewer struct {
+	Name       string
+	Phone      string
+	CanReadAll func(module string) bool
+	Scope      SiteScope
+}
+
+// syncPosition is the last change a client holds for one module. Changes
+// are ordered by (changedAt, id) so a position is an exact resume point.
+type syncPosition struct {
+	ChangedAt time.Time `json:"t"`
+	ID        string    `json:"id"`
+}
+
+// syncWatermark is the decoded form of the opaque watermark
+type syncWatermark map[string]syncPosition
+
+// changedAtSQL is when a row last changed. Soft deletes only set
+// deleted_at, so it has to be taken into account as well.
+const changedAtSQL = "GREATEST(updated_at, COALESCE(deleted_at, updated_at))"
+
+// SyncService answers delta sync pulls
+type SyncService struct {
+	db *gorm.DB
+}
+
+// NewSyncService creates a new sync service
+func NewSyncService(db *gorm.DB) *SyncService {
+	return &SyncService{db: db}
+}
+
+// Pull returns up to limit changes after the since watermark across the
+// named modules (all of them when empty). Modules are drained in
+// SyncModules order, so repeating a pull with the same watermark returns
+// the same page and an interrupted sync can simply be retried.
+func (s *SyncService) Pull(since string, modules []string, limit int, viewer SyncViewer) (*SyncResponse, error) {
+	wm, err := decodeWatermark(since)
+	if err != nil {
+		return nil, err
+	}
+	selected, err := selectSyncModules(modules)
+	if err != nil {
+		return nil, err
+	}
+
+	upTo := time.Now().Add(-syncLag)
+	resp := &SyncResponse{Changes: []SyncChange{}}
+	for _, m := range selected {
+		remaining := limit - len(resp.Changes)
+		if remaining <= 0 {
+			resp.HasMore = true
+			break
+		}
+		pos, resumed := wm[m.Name]
+		changes, err := s.pullModule(m, pos, resumed, upTo, remaining+1, viewer)
+		if err != nil {
+			return nil, fmt.Errorf("sync %s: %w", m.Name, err)
+		}
+		if len(changes) > remaining {
+			changes = changes[:remaining]
+			resp.HasMore = true
+		}
+		if len(changes) > 0 {
+			last := changes[len(changes)-1]
+			wm[m.Name] = syncPosition{ChangedAt: last.ChangedAt, ID: last.ID}
+		}
+		resp.Changes = append(resp.Changes, changes...)
+	}
+
+	resp.Watermark, err = wm.encode()
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func selectSyncModules(names []string) ([]SyncModule, error) {
+	if len(names) == 0 {
+		return SyncModules, nil
+	}
+	want := map[string]bool{}
+	for _, n := range names {
+		want[n] = true
+	}
+	var out []SyncModule
+	for _, m := range SyncModules {
+		if want[m.Name] {
+			out = append(out, m)
+			delete(want, m.Name)
+		}
+	}
+	if len(want) > 0 {
+		unknown := make([]string, 0, len(want))
+		for n := range want {
+			unknown = append(unknown, n)
+		}
+		sort.Strings(unknown)
+		return nil, &ParamError{Param: "modules", Message: "unknown module " + strings.Join(unknown, ", ")}
+	}
+	return out, nil
+}
+
+// pullModule reads the changes of one module after pos. Tombstones are only
+// needed by clients that already hold data, so a first sync skips them.
+func (s *SyncService) pullModule(m SyncModule, pos syncPosition, resumed bool, upTo time.Time, limit int, viewer SyncViewer) ([]SyncChange, error) {
+	stmt := &gorm.Statement{DB: s.db}
+	if err := stmt.Parse(m.Model); err != nil {
+		return nil, err
+	}
+	sch := stmt.Schema
+	idField := sch.PrioritizedPrimaryField
+	updatedField := sch.LookUpField("UpdatedAt")
+	deletedField := sch.LookUpField("DeletedAt")
+	if idField == nil || updatedField == nil || deletedField == nil {
+		return nil, fmt.Errorf("model %s cannot be synced", sch.Name)
+	}
+
+	query := s.db.Unscoped().Model(m.Model).
+		Where(changedAtSQL+" <= ?", upTo).
+		Order(changedAtSQL).
+		Order("CAST(id AS text)").
+		Limit(limit)
+	if resumed {
+		query = query.Where("("+changedAtSQL+" > ? OR ("+changedAtSQL+" = ? AND CAST(id AS text) > ?))",
+			pos.ChangedAt, pos.ChangedAt, pos.ID)
+	} else {
+		query = query.Where("deleted_at IS NULL")
+	}
+	if viewer.CanReadAll == nil || !viewer.CanReadAll(m.Name) {
+		owner := sch.LookUpField(m.OwnerField)
+		if owner == nil {
+			return nil, fmt.Errorf("model %s has no owner field %s", sch.Name, m.OwnerField)
+		}
+		value := viewer.Phone
+		if m.OwnerByName {
+			value = viewer.Name
+		}
+		query = query.Where(owner.DBName+" = ?", value)
+	} else {
+		cond, err := siteCondition(s.db, m.Model, viewer.Scope)
+		if err != nil {
+			return nil, err
+		}
+		if cond != nil {
+			query = query.Where(cond.SQL, cond.Args...)
+		}
+	}
+
+	rows := reflect.New(reflect.SliceOf(sch.ModelType))
+	if err := query.Find(rows.Interface()).Error; err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	list := rows.Elem()
+	changes := make([]SyncChange, 0, list.Len())
+	for i := 0; i < list.Len(); i++ {
+		row := list.Index(i)
+		id, _ := idField.ValueOf(ctx, row)
+		change := SyncChange{Module: m.Name, ID: fmt.Sprint(id), Op: SyncUpsert, Data: row.Interface()}
+		if v, _ := updatedField.ValueOf(ctx, row); v != nil {
+			change.ChangedAt, _ = v.(time.Time)
+		}
+		if v, zero := deletedField.ValueOf(ctx, row); !zero {
+			if d, ok := v.(gorm.DeletedAt); ok && d.Valid {
+				change.Op = SyncDelete
+				change.Data = nil
+				if d.Time.After(change.ChangedAt) {
+					change.ChangedAt = d.Time
+				}
+			}
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+func decodeWatermark(raw string) (syncWatermark, error) {
+	wm := syncWatermark{}
+	if raw == "" {
+		return wm, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(raw)
+	if err == nil {
+		err = json.Unmarshal(b, &wm)
+	}
+	if err != nil {
+		return nil, &ParamError{Param: "since", Message: "invalid watermark"}
+	}
+	return wm, nil
+}
+
+func (wm syncWatermark) encode() (string, error) {
+	b, err := json.Marshal(wm)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
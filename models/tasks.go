@@ -10,21 +10,21 @@ import (
 // Task corresponds to your Dart TasksModel.
 type Task struct {
 	ID                     uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
-	Label                  string    `gorm:"not null" json:"label"`
+	Label                  string    `gorm:"not null" json:"label" validate:"required"`
 	Location               string    `gorm:"not null" json:"location"`
 	Measurement            string    `gorm:"not null" json:"measurement"`
-	TaskType               string    `gorm:"not null" json:"taskType"`
+	TaskType               string    `gorm:"not null" json:"taskType" validate:"required"`
 	ExpectedCompletionDays string    `gorm:"not null" json:"expectedCompletionDays"`
-	StartDate              time.Time `gorm:"not null" json:"startDate"`
-	EndDate                time.Time `gorm:"not null" json:"endDate"`
+	StartDate              time.Time `gorm:"not null" json:"startDate" validate:"required"`
+	EndDate                time.Time `gorm:"not null" json:"endDate" validate:"required,gtefield=StartDate"`
 	Description            *string   `json:"description,omitempty"`
 	PipeMaterial           *string   `json:"pipeMaterial,omitempty"`
 	PipeDia                *string   `json:"pipeDia,omitempty"`
 	Remarks                *string   `json:"remarks,omitempty"`
 	WorkAssignedBy         *string   `json:"workAssignedBy,omitempty"`
-	Latitude               float64   `gorm:"not null" json:"latitude"`
-	Longitude              float64   `gorm:"not null" json:"longitude"`
-	SubmittedAt            time.Time `gorm:"not null" json:"submittedAt"`
+	Latitude               float64   `gorm:"not null" json:"latitude" validate:"latitude"`
+	Longitude              float64   `gorm:"not null" json:"longitude" validate:"longitude"`
+	SubmittedAt            time.Time `gorm:"not null" json:"submittedAt" validate:"required,notfuture"`
 	SiteEngineerName       string    `gorm:"not null" json:"siteEngineerName"`
 	SiteEngineerPhone      string    `gorm:"not null" json:"siteEngineerPhone"`
 	// Example of array type field, if you have photos or attachments:
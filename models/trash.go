@@ -0,0 +1,80 @@
+package models
+
+import (
+	"reflect"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SuperAdminRole is the only role allowed to purge the trash by hand
+const SuperAdminRole = "super_admin"
+
+// trashPurgeBatch bounds how many records one retention pass loads at once
+const trashPurgeBatch = 200
+
+// PurgeRecord permanently removes a soft-deleted record, its approval
+// request and records the purge in the audit trail. Its attachments are
+// unlinked, to be reaped with the other unlinked uploads, and its photo
+// matches dropped. It reports false when
+// the record was already gone, e.g. purged by another instance. Call it in
+// a transaction.
+func PurgeRecord(db *gorm.DB, actor AuditActor, record interface{}) (bool, error) {
+	res := db.Unscoped().Where("deleted_at IS NOT NULL").Delete(record)
+	if res.Error != nil || res.RowsAffected == 0 {
+		return false, res.Error
+	}
+	module := AuditModule(record)
+	if _, ok := FindApprovalWorkflow(module); ok {
+		err := db.Where("module = ? AND record_id = ?", module, approvalRecordID(record)).
+			Delete(&ApprovalRequest{}).Error
+		if err != nil {
+			return false, err
+		}
+	}
+	if err := unlinkAttachments(db, module, approvalRecordID(record)); err != nil {
+		return false, err
+	}
+	if err := deletePhotoMatches(db, module, approvalRecordID(record)); err != nil {
+		return false, err
+	}
+	return true, WriteAudit(db, actor, AuditPurge, record, nil)
+}
+
+// PurgeExpiredTrash permanently removes the records of every module that
+// were deleted more than retention ago and returns how many went. Each
+// record is purged in its own transaction, so a failure only stops the
+// pass, not what it already removed.
+func PurgeExpiredTrash(db *gorm.DB, retention time.Duration) (int, error) {
+	actor := AuditActor{Name: "trash retention"}
+	cutoff := time.Now().Add(-retention)
+	purged := 0
+	for _, m := range SyncModules {
+		for {
+			records := reflect.New(reflect.SliceOf(reflect.TypeOf(m.Model)))
+			err := db.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+				Order("deleted_at").Limit(trashPurgeBatch).Find(records.Interface()).Error
+			if err != nil {
+				return purged, err
+			}
+			n := records.Elem().Len()
+			for i := 0; i < n; i++ {
+				record := records.Elem().Index(i).Addr().Interface()
+				err := db.Transaction(func(tx *gorm.DB) error {
+					ok, err := PurgeRecord(tx, actor, record)
+					if ok {
+						purged++
+					}
+					return err
+				})
+				if err != nil {
+					return purged, err
+				}
+			}
+			if n < trashPurgeBatch {
+				break
+			}
+		}
+	}
+	return purged, nil
+}
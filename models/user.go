@@ -8,6 +8,10 @@ import (
 	"gorm.io/gorm"
 )
 
+// DefaultRole is the role of users who register themselves. Other roles
+// are assigned by an admin.
+const DefaultRole = "user"
+
 type User struct {
 	ID           uuid.UUID `gorm:"type:uuid;primaryKey"`
 	Name         string    `gorm:"size:100;not null"`
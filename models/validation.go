@@ -0,0 +1,146 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// submittedAtSkew is how far in the future a timestamp may be before it is
+// rejected. The mobile app sends local (IST) time without an offset, which
+// parses as UTC and so lands up to 5h30 ahead of the server clock.
+const submittedAtSkew = 6 * time.Hour
+
+// FieldError is one failed rule, reported with the JSON field name
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ValidationError is returned by ValidateModel when any rule fails
+type ValidationError []FieldError
+
+func (e ValidationError) Error() string {
+	parts := make([]string, len(e))
+	for i, f := range e {
+		parts[i] = f.Field + ": " + f.Message
+	}
+	return "validation failed: " + strings.Join(parts, "; ")
+}
+
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+
+	// Report JSON names rather than Go field names
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return f.Name
+		}
+		return name
+	})
+
+	// Let rules see through our wrapper types
+	v.RegisterCustomTypeFunc(func(field reflect.Value) interface{} {
+		if t, ok := field.Interface().(JSONTime); ok {
+			return time.Time(t)
+		}
+		return nil
+	}, JSONTime{})
+	v.RegisterCustomTypeFunc(func(field reflect.Value) interface{} {
+		if q, ok := field.Interface().(Quantity); ok {
+			return float64(q)
+		}
+		return nil
+	}, Quantity(0))
+
+	v.RegisterValidation("notfuture", func(fl validator.FieldLevel) bool {
+		t, ok := fl.Field().Interface().(time.Time)
+		if !ok || t.IsZero() {
+			return true
+		}
+		return !t.After(time.Now().Add(submittedAtSkew))
+	})
+	v.RegisterValidation("lnglat", func(fl validator.FieldLevel) bool {
+		p, ok := fl.Field().Interface().([2]float64)
+		return ok && validLatLng(p[1], p[0])
+	})
+	return v
+}
+
+// ValidateModel runs the `validate` struct tag rules of a model and returns
+// a ValidationError listing every failure, or nil.
+func ValidateModel(model interface{}) error {
+	err := validate.Struct(model)
+	if err == nil {
+		return nil
+	}
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return err
+	}
+	out := make(ValidationError, 0, len(verrs))
+	for _, fe := range verrs {
+		out = append(out, FieldError{
+			Field:   fe.Field(),
+			Code:    fe.Tag(),
+			Message: validationMessage(fe),
+		})
+	}
+	return out
+}
+
+func validationMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "latitude":
+		return "must be a latitude between -90 and 90"
+	case "longitude":
+		return "must be a longitude between -180 and 180"
+	case "lnglat":
+		return "must be a [longitude, latitude] pair"
+	case "notfuture":
+		return "cannot be in the future"
+	case "oneof":
+		return "must be one of: " + strings.ReplaceAll(fe.Param(), " ", ", ")
+	case "numeric":
+		return "must be a number"
+	case "email":
+		return "must be a valid email address"
+	case "len":
+		return fmt.Sprintf("must be exactly %s characters", fe.Param())
+	case "gt":
+		return "must be greater than " + fe.Param()
+	case "gte", "min":
+		return "must be at least " + fe.Param()
+	case "lt":
+		return "must be less than " + fe.Param()
+	case "lte", "max":
+		return "must be at most " + fe.Param()
+	case "startswith":
+		return "must start with " + fe.Param()
+	case "cidr":
+		return "must be an IP address or CIDR range"
+	case "gtefield":
+		return "must not be before " + lowerFirst(fe.Param())
+	}
+	return "failed " + fe.Tag() + " rule"
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
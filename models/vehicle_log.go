@@ -9,34 +9,40 @@ import (
 )
 
 type VehicleLog struct {
-	ID                   uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
-	Email                string         `gorm:"type:varchar(255);not null" json:"email" validate:"required,email"`
-	SiteLocation         string         `gorm:"type:varchar(255);not null" json:"site_location"`
-	WorkingZone          string         `gorm:"type:varchar(255)" json:"working_zone,omitempty"`
-	Date                 time.Time      `gorm:"type:timestamp;not null" json:"date" validate:"required"`
-	VehicleType          string         `gorm:"type:varchar(255);not null" json:"vehicle_type"`
-	RegistrationNumber   string         `gorm:"type:varchar(255)" json:"registration_number,omitempty"`
-	OwnerName            string         `gorm:"type:varchar(255)" json:"owner_name,omitempty"`
-	DriverName           string         `gorm:"type:varchar(255)" json:"driver_name,omitempty"`
-	StartingReadingFiles pq.StringArray `gorm:"type:text[]" json:"starting_reading_files,omitempty"` // Postgres array
-	ClosingReadingFiles  pq.StringArray `gorm:"type:text[]" json:"closing_reading_files,omitempty"`
-	ReadingTotalKMHrs    string         `gorm:"type:varchar(100)" json:"reading_total_km_hrs,omitempty"`
-	TotalWorkingHours    string         `gorm:"type:varchar(100)" json:"total_working_hours,omitempty"`
-	DieselIssuedLitres   string         `gorm:"type:varchar(100)" json:"diesel_issued_litres,omitempty"`
-	WorkDescription      string         `gorm:"type:text" json:"work_description,omitempty"`
-	WorkImages           pq.StringArray `gorm:"type:text[]" json:"work_images,omitempty"`
-	Remarks              string         `gorm:"type:text" json:"remarks,omitempty"`
-	SiteEngineerName     string         `gorm:"not null" json:"siteEngineerName"`
-	SiteEngineerPhone    string         `gorm:"not null" json:"siteEngineerPhone"`
-	Latitude             float64        `gorm:"not null" json:"latitude"`
-	Longitude            float64        `gorm:"not null" json:"longitude"`
-	SubmittedAt          JSONTime       `gorm:"not null" json:"submittedAt"`
-	CreatedAt            time.Time      `gorm:"autoCreateTime" json:"createdAt"`
-	UpdatedAt            time.Time      `gorm:"autoUpdateTime" json:"updatedAt"`
-	DeletedAt            gorm.DeletedAt `gorm:"index" json:"-"`
+	ID                     uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	Email                  string         `gorm:"type:varchar(255);not null" json:"email" validate:"required,email"`
+	SiteLocation           string         `gorm:"type:varchar(255);not null" json:"site_location" validate:"required"`
+	SiteID                 *uuid.UUID     `gorm:"type:uuid;index" json:"site_id,omitempty"`
+	WorkingZone            string         `gorm:"type:varchar(255)" json:"working_zone,omitempty"`
+	Date                   time.Time      `gorm:"type:timestamp;not null" json:"date" validate:"required"`
+	VehicleType            string         `gorm:"type:varchar(255);not null" json:"vehicle_type" validate:"required"`
+	RegistrationNumber     string         `gorm:"type:varchar(255)" json:"registration_number,omitempty"`
+	OwnerName              string         `gorm:"type:varchar(255)" json:"owner_name,omitempty"`
+	DriverName             string         `gorm:"type:varchar(255)" json:"driver_name,omitempty"`
+	StartingReadingFiles   pq.StringArray `gorm:"type:text[]" json:"starting_reading_files,omitempty"` // Postgres array
+	ClosingReadingFiles    pq.StringArray `gorm:"type:text[]" json:"closing_reading_files,omitempty"`
+	ReadingTotalKMHrs      string         `gorm:"type:varchar(100)" json:"reading_total_km_hrs,omitempty"`
+	TotalWorkingHours      string         `gorm:"type:varchar(100)" json:"total_working_hours,omitempty"`
+	DieselIssuedLitres     string         `gorm:"type:varchar(100)" json:"diesel_issued_litres,omitempty"`
+	WorkDescription        string         `gorm:"type:text" json:"work_description,omitempty"`
+	WorkImages             pq.StringArray `gorm:"type:text[]" json:"work_images,omitempty"`
+	Remarks                string         `gorm:"type:text" json:"remarks,omitempty"`
+	SiteEngineerName       string         `gorm:"not null" json:"siteEngineerName"`
+	SiteEngineerPhone      string         `gorm:"not null" json:"siteEngineerPhone"`
+	Latitude               float64        `gorm:"not null" json:"latitude" validate:"latitude"`
+	Longitude              float64        `gorm:"not null" json:"longitude" validate:"longitude"`
+	GeofenceStatus         string         `gorm:"size:10;index" json:"geofenceStatus,omitempty"`
+	GeofenceDistanceMeters *float64       `json:"geofenceDistanceMeters,omitempty"`
+	SubmittedAt            JSONTime       `gorm:"not null" json:"submittedAt" validate:"required,notfuture"`
+	CreatedAt              time.Time      `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt              time.Time      `gorm:"autoUpdateTime" json:"updatedAt"`
+	DeletedAt              gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 // Optionally, add TableName() for custom table name
 func (VehicleLog) TableName() string {
 	return "vehicle_logs"
 }
+
+// SiteField implements SiteScoped
+func (VehicleLog) SiteField() string { return "SiteLocation" }
@@ -11,9 +11,11 @@ import (
 // WrappingReport represents one “wrapping” form submission.
 type Wrapping struct {
 	ID                uuid.UUID      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
-	YardName          string         `gorm:"column:yard_name;not null"              json:"yardName"`
-	ContractorName    string         `gorm:"column:contractor_name;not null"        json:"contractorName"`
-	Activity          string         `gorm:"column:activity;not null"               json:"activity"`
+	YardName          string         `gorm:"column:yard_name;not null"              json:"yardName" validate:"required"`
+	YardID            *uuid.UUID     `gorm:"column:yard_id;type:uuid;index"         json:"yardId,omitempty"`
+	ContractorName    string         `gorm:"column:contractor_name;not null"        json:"contractorName" validate:"required"`
+	ContractorID      *uuid.UUID     `gorm:"column:contractor_id;type:uuid;index"   json:"contractorId,omitempty"`
+	Activity          string         `gorm:"column:activity;not null"               json:"activity" validate:"required"`
 	PipeNo            string         `gorm:"column:pipe_no;not null"                json:"pipeNo"`
 	LengthOfPipe      string         `gorm:"column:length_of_pipe;not null"         json:"lengthOfPipe"`
 	SquareMeters      string         `gorm:"column:square_meters;not null"          json:"squareMeters"`
@@ -21,11 +23,14 @@ type Wrapping struct {
 	Remarks           *string        `gorm:"column:remarks"                         json:"remarks,omitempty"`
 	SiteEngineerName  string         `gorm:"column:site_engineer_name;not null"     json:"siteEngineerName"`
 	SiteEngineerPhone string         `gorm:"column:site_engineer_phone;not null"    json:"siteEngineerPhone"`
-	Latitude          float64        `gorm:"column:latitude;not null"               json:"latitude"`
-	Longitude         float64        `gorm:"column:longitude;not null"              json:"longitude"`
-	SubmittedAt       JSONTime       `gorm:"column:submitted_at;not null"           json:"submittedAt"`
+	Latitude          float64        `gorm:"column:latitude;not null"               json:"latitude" validate:"latitude"`
+	Longitude         float64        `gorm:"column:longitude;not null"              json:"longitude" validate:"longitude"`
+	SubmittedAt       JSONTime       `gorm:"column:submitted_at;not null"           json:"submittedAt" validate:"required,notfuture"`
 
 	CreatedAt time.Time      `gorm:"autoCreateTime"                         json:"createdAt"`
 	UpdatedAt time.Time      `gorm:"autoUpdateTime"                         json:"updatedAt"`
 	DeletedAt gorm.DeletedAt `gorm:"index"                                  json:"-"`
 }
+
+// SiteField implements SiteScoped
+func (Wrapping) SiteField() string { return "YardName" }
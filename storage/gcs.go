@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	gcs "cloud.google.com/go/storage"
+)
+
+// GCS keeps objects in a Google Cloud Storage bucket, authenticating with
+// the application default credentials
+type GCS struct {
+	client *gcs.Client
+	bucket string
+}
+
+// NewGCS opens a client for bucket
+func NewGCS(ctx context.Context, bucket string) (*GCS, error) {
+	if bucket == "" {
+		return nil, errors.New("storage: gcs backend needs a bucket")
+	}
+	client, err := gcs.NewClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("storage: failed to create GCS client: %w", err)
+	}
+	return &GCS{client: client, bucket: bucket}, nil
+}
+
+func (g *GCS) object(key string) (*gcs.ObjectHandle, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return g.client.Bucket(g.bucket).Object(key), nil
+}
+
+// Put implements Backend
+func (g *GCS) Put(ctx context.Context, key string, r io.Reader, contentType string) error {
+	obj, err := g.object(key)
+	if err != nil {
+		return err
+	}
+	w := obj.NewWriter(ctx)
+	w.ContentType = contentType
+	if _, err := io.Copy(w, r); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// Open implements Backend
+func (g *GCS) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	obj, err := g.object(key)
+	if err != nil {
+		return nil, err
+	}
+	rc, err := obj.NewReader(ctx)
+	if errors.Is(err, gcs.ErrObjectNotExist) {
+		return nil, ErrNotFound
+	}
+	return rc, err
+}
+
+// Delete implements Backend
+func (g *GCS) Delete(ctx context.Context, key string) error {
+	obj, err := g.object(key)
+	if err != nil {
+		return err
+	}
+	if err := obj.Delete(ctx); err != nil && !errors.Is(err, gcs.ErrObjectNotExist) {
+		return err
+	}
+	return nil
+}
+
+// SignedURL implements Backend with a V4 signed URL. The credentials
+// must be able to sign, with a private key or through the IAM signBlob
+// permission.
+func (g *GCS) SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	if err := checkTTL(ttl); err != nil {
+		return "", err
+	}
+	return g.client.Bucket(g.bucket).SignedURL(key, &gcs.SignedURLOptions{
+		Method:  http.MethodGet,
+		Expires: time.Now().Add(ttl),
+		Scheme:  gcs.SigningSchemeV4,
+	})
+}
@@ -0,0 +1,139 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Local keeps objects as files under a directory. It is also an
+// http.Handler serving them by key, for mounting under its URL prefix;
+// only requests carrying a valid signature from SignedURL are served.
+type Local struct {
+	dir     string
+	baseURL string
+	secret  []byte
+	files   http.Handler
+}
+
+// NewLocal stores objects under dir, creating it if needed. baseURL is the
+// prefix of download URLs, e.g. "/uploads/" or an absolute URL, and secret
+// the HMAC key their signatures are made with.
+func NewLocal(dir, baseURL, secret string) (*Local, error) {
+	if dir == "" {
+		return nil, errors.New("storage: local backend needs a directory")
+	}
+	if secret == "" {
+		return nil, errors.New("storage: local backend needs a signing key")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(baseURL, "/") {
+		baseURL += "/"
+	}
+	return &Local{dir: dir, baseURL: baseURL, secret: []byte(secret), files: http.FileServer(http.Dir(dir))}, nil
+}
+
+func (l *Local) path(key string) (string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(l.dir, filepath.FromSlash(key)), nil
+}
+
+// Put implements Backend. The file is written under a temporary name and
+// renamed into place, so readers never see half an object.
+func (l *Local) Put(ctx context.Context, key string, r io.Reader, contentType string) error {
+	p, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), p)
+}
+
+// Open implements Backend
+func (l *Local) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	p, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+// Delete implements Backend
+func (l *Local) Delete(ctx context.Context, key string) error {
+	p, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// SignedURL implements Backend. The URL carries its expiry and an
+// HMAC-SHA256 of the key and expiry.
+func (l *Local) SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	if err := checkTTL(ttl); err != nil {
+		return "", err
+	}
+	expires := strconv.FormatInt(time.Now().Add(ttl).Unix(), 10)
+	return l.baseURL + escapePath(key) + "?expires=" + expires + "&signature=" + l.sign(key, expires), nil
+}
+
+func (l *Local) sign(key, expires string) string {
+	mac := hmac.New(sha256.New, l.secret)
+	mac.Write([]byte(key + "\n" + expires))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// ServeHTTP serves the object named by the request path, provided the
+// query carries an unexpired signature for it. Anything else is 403.
+func (l *Local) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key, err := cleanKey(r.URL.Path)
+	expires := r.URL.Query().Get("expires")
+	at, convErr := strconv.ParseInt(expires, 10, 64)
+	if err != nil || convErr != nil || time.Now().Unix() > at ||
+		!hmac.Equal([]byte(l.sign(key, expires)), []byte(r.URL.Query().Get("signature"))) {
+		http.Error(w, "invalid or expired signature", http.StatusForbidden)
+		return
+	}
+	w.Header().Set("Cache-Control", "private, max-age="+strconv.FormatInt(max(0, at-time.Now().Unix()), 10))
+	l.files.ServeHTTP(w, r)
+}
@@ -0,0 +1,163 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestLocal(t *testing.T) *Local {
+	t.Helper()
+	l, err := NewLocal(t.TempDir(), "/uploads/", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+// get requests target from l mounted under /uploads/ as routes does
+func get(l *Local, target string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	http.StripPrefix("/uploads/", l).ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+	return w
+}
+
+func TestLocalPutOpenDelete(t *testing.T) {
+	ctx := context.Background()
+	l := newTestLocal(t)
+	const key = "2026/10/report.txt"
+
+	if err := l.Put(ctx, key, strings.NewReader("first"), "text/plain"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Put(ctx, key, strings.NewReader("second"), "text/plain"); err != nil {
+		t.Fatal(err)
+	}
+	rc, err := l.Open(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "second" {
+		t.Errorf("Open = %q, want the replaced content %q", data, "second")
+	}
+
+	if err := l.Delete(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Open(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Open after Delete = %v, want ErrNotFound", err)
+	}
+	if err := l.Delete(ctx, key); err != nil {
+		t.Errorf("Delete of a missing object = %v, want nil", err)
+	}
+}
+
+func TestLocalRejectsInvalidKeys(t *testing.T) {
+	ctx := context.Background()
+	l := newTestLocal(t)
+	for _, key := range []string{"", ".", "/", ".."} {
+		if err := l.Put(ctx, key, strings.NewReader("x"), "text/plain"); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Put(%q) = %v, want ErrInvalidKey", key, err)
+		}
+	}
+
+	// Keys climbing out of the store stay inside it
+	if err := l.Put(ctx, "../../escape.txt", strings.NewReader("x"), "text/plain"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Open(ctx, "escape.txt"); err != nil {
+		t.Errorf("Open(escape.txt) = %v, want the object stored under the cleaned key", err)
+	}
+}
+
+func TestLocalSignedURL(t *testing.T) {
+	ctx := context.Background()
+	l := newTestLocal(t)
+	const key = "2026/10/meter reading.jpg"
+	if err := l.Put(ctx, key, strings.NewReader("photo"), "image/jpeg"); err != nil {
+		t.Fatal(err)
+	}
+
+	signed, err := l.SignedURL(ctx, key, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(signed, "/uploads/2026/10/meter%20reading.jpg?") {
+		t.Fatalf("SignedURL = %q, want the escaped key under the base URL", signed)
+	}
+	w := get(l, signed)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET signed URL = %d %s, want 200", w.Code, w.Body)
+	}
+	if w.Body.String() != "photo" {
+		t.Errorf("body = %q, want %q", w.Body, "photo")
+	}
+	if cc := w.Header().Get("Cache-Control"); !strings.HasPrefix(cc, "private") {
+		t.Errorf("Cache-Control = %q, want private", cc)
+	}
+
+	for _, ttl := range []time.Duration{0, -time.Minute, MaxSignedURLTTL + time.Second} {
+		if _, err := l.SignedURL(ctx, key, ttl); err == nil {
+			t.Errorf("SignedURL with ttl %s succeeded, want an error", ttl)
+		}
+	}
+}
+
+func TestLocalServeHTTPRejectsBadSignatures(t *testing.T) {
+	ctx := context.Background()
+	l := newTestLocal(t)
+	for _, key := range []string{"a.txt", "b.txt"} {
+		if err := l.Put(ctx, key, strings.NewReader(key), "text/plain"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	signed, err := l.SignedURL(ctx, "a.txt", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := u.Query()
+	tampered := []byte(query.Get("signature"))
+	tampered[0] ^= 1
+	past := strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10)
+	other, err := NewLocal(t.TempDir(), "/uploads/", "other secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		target string
+	}{
+		{"unsigned", "/uploads/a.txt"},
+		{"expired", "/uploads/a.txt?expires=" + past + "&signature=" + l.sign("a.txt", past)},
+		{"tampered signature", "/uploads/a.txt?expires=" + query.Get("expires") + "&signature=" + string(tampered)},
+		{"extended expiry", "/uploads/a.txt?expires=" + strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10) + "&signature=" + query.Get("signature")},
+		{"other key", "/uploads/b.txt?" + u.RawQuery},
+		{"other secret", "/uploads/a.txt?expires=" + query.Get("expires") + "&signature=" + other.sign("a.txt", query.Get("expires"))},
+		{"malformed expiry", "/uploads/a.txt?expires=soon&signature=" + query.Get("signature")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := get(l, tt.target)
+			if w.Code != http.StatusForbidden {
+				t.Errorf("GET %s = %d, want 403", tt.target, w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+// Package storage keeps uploaded files behind one interface, so where they
+// live is a matter of configuration: the local disk, Google Cloud Storage
+// or any S3-compatible object store.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+// Backend names accepted by Config.Backend
+const (
+	BackendLocal = "local"
+	BackendGCS   = "gcs"
+	BackendS3    = "s3"
+)
+
+// ErrNotFound is returned by Open for a key that holds no object
+var ErrNotFound = errors.New("storage: object not found")
+
+// ErrInvalidKey is returned for keys that are empty or climb out of the
+// store with ".."
+var ErrInvalidKey = errors.New("storage: invalid object key")
+
+// MaxSignedURLTTL is the longest a signed URL may stay valid, the limit
+// S3 and GCS both impose
+const MaxSignedURLTTL = 7 * 24 * time.Hour
+
+// Backend stores private objects under slash separated keys. Clients only
+// ever download them through signed URLs.
+type Backend interface {
+	// Put stores the content of r under key, replacing any object there
+	Put(ctx context.Context, key string, r io.Reader, contentType string) error
+	// Open reads the object under key. It returns ErrNotFound when there
+	// is none.
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the object under key. A missing object is not an
+	// error.
+	Delete(ctx context.Context, key string) error
+	// SignedURL returns a URL that downloads the object under key until
+	// ttl has passed
+	SignedURL(ctx context.Context, key string, ttl time.Duration) (string, error)
+}
+
+// Config selects and sets up a backend. Only the fields of the chosen
+// backend are used.
+type Config struct {
+	Backend string
+
+	// LocalDir is the directory of the local backend and LocalURL the URL
+	// prefix it is served under. LocalSigningKey signs its download URLs.
+	LocalDir        string
+	LocalURL        string
+	LocalSigningKey string
+
+	GCSBucket string
+
+	// S3Endpoint is the base URL of the service, e.g.
+	// https://s3.ap-south-1.amazonaws.com or a MinIO server. Objects are
+	// addressed path-style.
+	S3Endpoint  string
+	S3Region    string
+	S3Bucket    string
+	S3AccessKey string
+	S3SecretKey string
+}
+
+// New sets up the backend cfg names
+func New(ctx context.Context, cfg Config) (Backend, error) {
+	switch cfg.Backend {
+	case BackendLocal:
+		return NewLocal(cfg.LocalDir, cfg.LocalURL, cfg.LocalSigningKey)
+	case BackendGCS:
+		return NewGCS(ctx, cfg.GCSBucket)
+	case BackendS3:
+		return NewS3(cfg.S3Endpoint, cfg.S3Region, cfg.S3Bucket, cfg.S3AccessKey, cfg.S3SecretKey)
+	}
+	return nil, fmt.Errorf("storage: unknown backend %q (must be local, gcs or s3)", cfg.Backend)
+}
+
+// cleanKey normalises key to a relative slash separated path, rejecting
+// keys that would leave the store
+func cleanKey(key string) (string, error) {
+	key = strings.TrimPrefix(path.Clean("/"+strings.ReplaceAll(key, "\\", "/")), "/")
+	if key == "" || key == "." {
+		return "", ErrInvalidKey
+	}
+	return key, nil
+}
+
+// escapePath escapes each segment of a slash separated key for use in a URL
+func escapePath(key string) string {
+	parts := strings.Split(key, "/")
+	for i, p := range parts {
+		parts[i] = url.PathEscape(p)
+	}
+	return strings.Join(parts, "/")
+}
+
+func checkTTL(ttl time.Duration) error {
+	if ttl <= 0 || ttl > MaxSignedURLTTL {
+		return fmt.Errorf("storage: signed URL lifetime %s out of range", ttl)
+	}
+	return nil
+}